@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/helson-lin/doke/pkg/convert"
+	"gopkg.in/yaml.v3"
+)
+
+// community.docker.docker_container 模块的参数
+type ansibleContainer struct {
+	Name              string                   `yaml:"name"`
+	Image             ansibleString            `yaml:"image"`
+	State             string                   `yaml:"state"`
+	Platform          string                   `yaml:"platform,omitempty"`
+	RestartPolicy     string                   `yaml:"restart_policy,omitempty"`
+	RestartRetries    int                      `yaml:"restart_retries,omitempty"`
+	AutoRemove        bool                     `yaml:"auto_remove,omitempty"`
+	Entrypoint        []ansibleString          `yaml:"entrypoint,omitempty"`
+	Command           []ansibleString          `yaml:"command,omitempty"`
+	User              ansibleString            `yaml:"user,omitempty"`
+	WorkingDir        ansibleString            `yaml:"working_dir,omitempty"`
+	Hostname          ansibleString            `yaml:"hostname,omitempty"`
+	Domainname        ansibleString            `yaml:"domainname,omitempty"`
+	Env               map[string]any           `yaml:"env,omitempty"`
+	Labels            map[string]ansibleString `yaml:"labels,omitempty"`
+	PublishedPorts    []string                 `yaml:"published_ports,omitempty"`
+	PublishAllPorts   bool                     `yaml:"publish_all_ports,omitempty"`
+	ExposedPorts      []string                 `yaml:"exposed_ports,omitempty"`
+	Volumes           []ansibleString          `yaml:"volumes,omitempty"`
+	VolumesFrom       []string                 `yaml:"volumes_from,omitempty"`
+	Tmpfs             []ansibleString          `yaml:"tmpfs,omitempty"`
+	NetworkMode       string                   `yaml:"network_mode,omitempty"`
+	Networks          []ansibleNetwork         `yaml:"networks,omitempty"`
+	Links             []string                 `yaml:"links,omitempty"`
+	MacAddress        string                   `yaml:"mac_address,omitempty"`
+	DNSServers        []string                 `yaml:"dns_servers,omitempty"`
+	DNSSearchDomains  []string                 `yaml:"dns_search_domains,omitempty"`
+	DNSOpts           []string                 `yaml:"dns_opts,omitempty"`
+	EtcHosts          map[string]string        `yaml:"etc_hosts,omitempty"`
+	Capabilities      []string                 `yaml:"capabilities,omitempty"`
+	CapDrop           []string                 `yaml:"cap_drop,omitempty"`
+	Privileged        bool                     `yaml:"privileged,omitempty"`
+	ReadOnly          bool                     `yaml:"read_only,omitempty"`
+	Init              bool                     `yaml:"init,omitempty"`
+	SecurityOpts      []ansibleString          `yaml:"security_opts,omitempty"`
+	Groups            []string                 `yaml:"groups,omitempty"`
+	Devices           []string                 `yaml:"devices,omitempty"`
+	DeviceRequests    []ansibleDeviceRequest   `yaml:"device_requests,omitempty"`
+	Ulimits           []string                 `yaml:"ulimits,omitempty"`
+	Sysctls           map[string]ansibleString `yaml:"sysctls,omitempty"`
+	Cpus              float64                  `yaml:"cpus,omitempty"`
+	CPUShares         int64                    `yaml:"cpu_shares,omitempty"`
+	CpusetCpus        string                   `yaml:"cpuset_cpus,omitempty"`
+	CPUPeriod         int64                    `yaml:"cpu_period,omitempty"`
+	CPUQuota          int64                    `yaml:"cpu_quota,omitempty"`
+	Memory            string                   `yaml:"memory,omitempty"`
+	MemoryReservation string                   `yaml:"memory_reservation,omitempty"`
+	MemorySwap        string                   `yaml:"memory_swap,omitempty"`
+	KernelMemory      string                   `yaml:"kernel_memory,omitempty"`
+	ShmSize           string                   `yaml:"shm_size,omitempty"`
+	PidsLimit         int64                    `yaml:"pids_limit,omitempty"`
+	OomScoreAdj       int                      `yaml:"oom_score_adj,omitempty"`
+	OomKiller         bool                     `yaml:"oom_killer,omitempty"`
+	BlkioWeight       uint16                   `yaml:"blkio_weight,omitempty"`
+	DeviceReadBps     []ansibleDeviceRate      `yaml:"device_read_bps,omitempty"`
+	DeviceWriteBps    []ansibleDeviceRate      `yaml:"device_write_bps,omitempty"`
+	DeviceReadIOps    []ansibleDeviceRate      `yaml:"device_read_iops,omitempty"`
+	DeviceWriteIOps   []ansibleDeviceRate      `yaml:"device_write_iops,omitempty"`
+	StorageOpts       map[string]string        `yaml:"storage_opts,omitempty"`
+	IpcMode           string                   `yaml:"ipc_mode,omitempty"`
+	PidMode           string                   `yaml:"pid_mode,omitempty"`
+	Uts               string                   `yaml:"uts,omitempty"`
+	UsernsMode        string                   `yaml:"userns_mode,omitempty"`
+	Runtime           string                   `yaml:"runtime,omitempty"`
+	CgroupParent      string                   `yaml:"cgroup_parent,omitempty"`
+	CgroupnsMode      string                   `yaml:"cgroupns_mode,omitempty"`
+	LogDriver         string                   `yaml:"log_driver,omitempty"`
+	LogOptions        map[string]ansibleString `yaml:"log_options,omitempty"`
+	StopSignal        string                   `yaml:"stop_signal,omitempty"`
+	StopTimeout       int                      `yaml:"stop_timeout,omitempty"`
+	Healthcheck       *ansibleHealthcheck      `yaml:"healthcheck,omitempty"`
+	Interactive       bool                     `yaml:"interactive,omitempty"`
+	TTY               bool                     `yaml:"tty,omitempty"`
+}
+
+type ansibleNetwork struct {
+	Name        string   `yaml:"name"`
+	Aliases     []string `yaml:"aliases,omitempty"`
+	Ipv4Address string   `yaml:"ipv4_address,omitempty"`
+	Ipv6Address string   `yaml:"ipv6_address,omitempty"`
+}
+
+type ansibleHealthcheck struct {
+	Test          []ansibleString `yaml:"test"`
+	Interval      string          `yaml:"interval,omitempty"`
+	Timeout       string          `yaml:"timeout,omitempty"`
+	Retries       int             `yaml:"retries,omitempty"`
+	StartPeriod   string          `yaml:"start_period,omitempty"`
+	StartInterval string          `yaml:"start_interval,omitempty"`
+}
+
+// device_requests 的单个请求，capabilities 与 docker 相同为多组能力的“或”
+type ansibleDeviceRequest struct {
+	Driver       string            `yaml:"driver,omitempty"`
+	Count        int               `yaml:"count,omitempty"`
+	DeviceIDs    []string          `yaml:"device_ids,omitempty"`
+	Capabilities [][]string        `yaml:"capabilities,omitempty"`
+	Options      map[string]string `yaml:"options,omitempty"`
+}
+
+// 块设备的 IO 限速，bps 以字节为单位
+type ansibleDeviceRate struct {
+	Path string `yaml:"path"`
+	Rate uint64 `yaml:"rate"`
+}
+
+// ansible 会把字符串当作 Jinja2 模板渲染，包含模板语法的字面值标记为 !unsafe
+type ansibleString string
+
+func (s ansibleString) MarshalYAML() (interface{}, error) {
+	if !strings.Contains(string(s), "{{") && !strings.Contains(string(s), "{%") && !strings.Contains(string(s), "{#") {
+		return string(s), nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!unsafe", Value: string(s)}, nil
+}
+
+func ansibleStrings(values []string) []ansibleString {
+	var result []ansibleString
+	for _, value := range values {
+		result = append(result, ansibleString(value))
+	}
+	return result
+}
+
+func ansibleStringMap(values map[string]string) map[string]ansibleString {
+	if values == nil {
+		return nil
+	}
+	result := make(map[string]ansibleString, len(values))
+	for key, value := range values {
+		result[key] = ansibleString(value)
+	}
+	return result
+}
+
+type ansibleResource struct {
+	Name       string            `yaml:"name"`
+	Driver     string            `yaml:"driver,omitempty"`
+	DriverOpts map[string]string `yaml:"driver_options,omitempty"`
+	State      string            `yaml:"state"`
+}
+
+// ansible 任务，每个任务只包含一个模块
+type ansibleTask struct {
+	Name      string            `yaml:"name"`
+	Network   *ansibleResource  `yaml:"community.docker.docker_network,omitempty"`
+	Volume    *ansibleResource  `yaml:"community.docker.docker_volume,omitempty"`
+	Container *ansibleContainer `yaml:"community.docker.docker_container,omitempty"`
+}
+
+// 生成 ansible 任务列表，网络与卷的任务排在容器之前
+func getAnsibleYaml(specs []*convert.ContainerSpec, resources *convert.DockerResources) (string, []string, error) {
+	var tasks []ansibleTask
+	var warnings []string
+	networks := make(map[string]bool)
+	volumes := make(map[string]bool)
+	for _, spec := range specs {
+		for _, name := range getUserNetworks(spec) {
+			if networks[name] {
+				continue
+			}
+			networks[name] = true
+			network := &ansibleResource{Name: name, State: "present"}
+			if resource, ok := getNetworkResource(name, resources); ok {
+				network.Driver = resource.Driver
+				network.DriverOpts = resource.Options
+			}
+			tasks = append(tasks, ansibleTask{Name: fmt.Sprintf("Create network %s", name), Network: network})
+		}
+		for _, mount := range spec.Mounts {
+			if mount.Type != "volume" || mount.Source == "" || volumes[mount.Source] {
+				continue
+			}
+			volumes[mount.Source] = true
+			vol := &ansibleResource{Name: mount.Source, Driver: firstNonEmpty(mount.Driver, "local"), State: "present"}
+			if resource, ok := getVolumeResource(mount.Source, resources); ok {
+				vol.DriverOpts = resource.Options
+			}
+			tasks = append(tasks, ansibleTask{Name: fmt.Sprintf("Create volume %s", mount.Source), Volume: vol})
+		}
+	}
+	for _, spec := range specs {
+		container, containerWarnings := getAnsibleContainer(spec)
+		tasks = append(tasks, ansibleTask{Name: fmt.Sprintf("Run container %s", spec.Name), Container: container})
+		warnings = append(warnings, containerWarnings...)
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(tasks); err != nil {
+		return "", nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	return buffer.String(), warnings, nil
+}
+
+// 根据 ContainerSpec 构建 docker_container 模块参数
+func getAnsibleContainer(spec *convert.ContainerSpec) (*ansibleContainer, []string) {
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", spec.Name, fmt.Sprintf(format, args...)))
+	}
+	service, _, _ := convert.ComposeService(spec, "", nil)
+	resources := spec.Resources
+
+	task := &ansibleContainer{
+		Name:             spec.Name,
+		Image:            ansibleString(spec.Image),
+		State:            "started",
+		Platform:         spec.Platform,
+		AutoRemove:       spec.AutoRemove,
+		Entrypoint:       ansibleStrings(spec.Entrypoint),
+		Command:          ansibleStrings(spec.Command),
+		User:             ansibleString(spec.User),
+		WorkingDir:       ansibleString(spec.WorkingDir),
+		Hostname:         ansibleString(spec.Hostname),
+		Domainname:       ansibleString(spec.Domainname),
+		Labels:           ansibleStringMap(spec.Labels),
+		PublishAllPorts:  spec.PublishAll,
+		VolumesFrom:      spec.VolumesFrom,
+		Tmpfs:            ansibleStrings(service.Tmpfs),
+		NetworkMode:      service.NetworkMode,
+		Links:            spec.Network.Links,
+		DNSServers:       spec.Network.DNS,
+		DNSOpts:          spec.Network.DNSOptions,
+		MacAddress:       spec.Network.MacAddress,
+		Privileged:       spec.Privileged,
+		ReadOnly:         spec.ReadOnly,
+		Init:             spec.Init,
+		Capabilities:     spec.CapAdd,
+		CapDrop:          spec.CapDrop,
+		SecurityOpts:     ansibleStrings(spec.SecurityOpt),
+		Groups:           spec.GroupAdd,
+		Sysctls:          ansibleStringMap(spec.Sysctls),
+		CPUShares:        resources.CPUShares,
+		Cpus:             resources.CPUs,
+		CpusetCpus:       resources.CpusetCpus,
+		CPUPeriod:        resources.CPUPeriod,
+		CPUQuota:         resources.CPUQuota,
+		PidsLimit:        resources.PidsLimit,
+		OomScoreAdj:      resources.OomScoreAdj,
+		OomKiller:        resources.OomKillDisable,
+		StorageOpts:      spec.StorageOpt,
+		IpcMode:          spec.Ipc,
+		PidMode:          spec.Pid,
+		Uts:              spec.Uts,
+		UsernsMode:       spec.Userns,
+		Runtime:          spec.Runtime,
+		CgroupParent:     spec.CgroupParent,
+		CgroupnsMode:     spec.Cgroupns,
+		DNSSearchDomains: spec.Network.DNSSearch,
+		StopSignal:       spec.StopSignal,
+		Interactive:      spec.Interactive,
+		TTY:              spec.Tty,
+	}
+
+	if spec.Restart != "" {
+		policy, retries, _ := strings.Cut(spec.Restart, ":")
+		task.RestartPolicy = policy
+		task.RestartRetries, _ = strconv.Atoi(retries)
+	}
+
+	// ansible 的 env 值必须是字符串
+	for _, env := range spec.Env {
+		var value any
+		switch {
+		case env.Ref != "":
+			// 引用的变量（例如 --redact 替换掉的密钥）从运行 ansible 的环境中读取
+			value = fmt.Sprintf(`{{ lookup("ansible.builtin.env", "%s") }}`, env.Ref)
+		case env.Value != nil:
+			value = ansibleString(*env.Value)
+		default:
+			warn("environment variable %s is inherited from the Docker client environment and is skipped", env.Name)
+			continue
+		}
+		if task.Env == nil {
+			task.Env = make(map[string]any)
+		}
+		task.Env[env.Name] = value
+	}
+
+	// 与 -p 的格式相同，未指定宿主机端口时由 docker 随机分配
+	for _, port := range spec.Ports {
+		task.PublishedPorts = append(task.PublishedPorts, port.String())
+	}
+	for _, port := range spec.Expose {
+		task.ExposedPorts = append(task.ExposedPorts, port.String())
+	}
+	for _, mount := range spec.Mounts {
+		if mount.Type != "tmpfs" {
+			task.Volumes = append(task.Volumes, ansibleString(mount.String()))
+		}
+	}
+
+	for _, attachment := range spec.Network.Networks {
+		task.Networks = append(task.Networks, ansibleNetwork{
+			Name:        attachment.Name,
+			Aliases:     attachment.Aliases,
+			Ipv4Address: attachment.IPv4Address,
+			Ipv6Address: attachment.IPv6Address,
+		})
+	}
+
+	for _, host := range spec.Network.ExtraHosts {
+		if hostname, ip, ok := strings.Cut(host, ":"); ok {
+			if task.EtcHosts == nil {
+				task.EtcHosts = make(map[string]string)
+			}
+			task.EtcHosts[hostname] = ip
+		}
+	}
+	for _, device := range spec.Devices {
+		task.Devices = append(task.Devices, fmt.Sprintf("%s:%s:%s", device.HostPath, device.ContainerPath, firstNonEmpty(device.Permissions, "rwm")))
+	}
+	for _, request := range spec.DeviceRequests {
+		item := ansibleDeviceRequest{Driver: request.Driver, Count: request.Count, DeviceIDs: request.DeviceIDs, Options: request.Options}
+		if len(request.Capabilities) > 0 {
+			item.Capabilities = [][]string{request.Capabilities}
+		}
+		task.DeviceRequests = append(task.DeviceRequests, item)
+	}
+	for _, ulimit := range spec.Ulimits {
+		task.Ulimits = append(task.Ulimits, fmt.Sprintf("%s:%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+
+	if resources.Memory > 0 {
+		task.Memory = fmt.Sprintf("%d", resources.Memory)
+	}
+	if resources.MemoryReservation > 0 {
+		task.MemoryReservation = fmt.Sprintf("%d", resources.MemoryReservation)
+	}
+	if resources.MemorySwap != 0 {
+		task.MemorySwap = fmt.Sprintf("%d", resources.MemorySwap)
+	}
+	if resources.KernelMemory > 0 {
+		task.KernelMemory = fmt.Sprintf("%d", resources.KernelMemory)
+	}
+	if resources.ShmSize > 0 {
+		task.ShmSize = fmt.Sprintf("%d", resources.ShmSize)
+	}
+	if blkio := resources.Blkio; blkio != nil {
+		task.BlkioWeight = blkio.Weight
+		for _, limits := range []struct {
+			devices []convert.BlkioLimitSpec
+			target  *[]ansibleDeviceRate
+		}{
+			{blkio.DeviceReadBps, &task.DeviceReadBps},
+			{blkio.DeviceWriteBps, &task.DeviceWriteBps},
+			{blkio.DeviceReadIOps, &task.DeviceReadIOps},
+			{blkio.DeviceWriteIOps, &task.DeviceWriteIOps},
+		} {
+			for _, device := range limits.devices {
+				*limits.target = append(*limits.target, ansibleDeviceRate{Path: device.Path, Rate: device.Rate})
+			}
+		}
+		for _, device := range blkio.WeightDevice {
+			warn("blkio weight %d of %s is not supported by docker_container and is skipped", device.Weight, device.Path)
+		}
+	}
+	if len(spec.Annotations) > 0 {
+		warn("annotations are not supported by docker_container and are skipped")
+	}
+	if spec.Logging != nil {
+		task.LogDriver = spec.Logging.Driver
+		task.LogOptions = ansibleStringMap(spec.Logging.Options)
+	}
+	if spec.StopTimeout != nil {
+		task.StopTimeout = *spec.StopTimeout
+	}
+
+	if health := spec.Healthcheck; health != nil {
+		task.Healthcheck = &ansibleHealthcheck{
+			Test:          ansibleStrings(health.Test),
+			Interval:      health.Interval,
+			Timeout:       health.Timeout,
+			Retries:       health.Retries,
+			StartPeriod:   health.StartPeriod,
+			StartInterval: health.StartInterval,
+		}
+	}
+
+	return task, warnings
+}
+
+// 容器连接的自定义网络（不包含 bridge、host、none 等内置网络）
+func getUserNetworks(spec *convert.ContainerSpec) []string {
+	var networks []string
+	for _, attachment := range spec.Network.Networks {
+		if !isSystemNetwork(attachment.Name) {
+			networks = append(networks, attachment.Name)
+		}
+	}
+	return networks
+}
+
+func getNetworkResource(name string, resources *convert.DockerResources) (types.NetworkResource, bool) {
+	if resources == nil {
+		return types.NetworkResource{}, false
+	}
+	network, ok := resources.Networks[name]
+	return network, ok
+}
+
+func getVolumeResource(name string, resources *convert.DockerResources) (volume.Volume, bool) {
+	if resources == nil {
+		return volume.Volume{}, false
+	}
+	vol, ok := resources.Volumes[name]
+	return vol, ok
+}
@@ -3,279 +3,530 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
-	"github.com/docker/go-units"
 	"github.com/helson-lin/doke/i18n"
+	"github.com/helson-lin/doke/pkg/convert"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var containerId string
 var isCompose bool = false
-
-type Service struct {
-	Image         string            `yaml:"image"`
-	ContainerName string            `yaml:"container_name,omitempty"`
-	Ports         []string          `yaml:"ports,omitempty"`
-	Environment   map[string]string `yaml:"environment,omitempty"`
-	Volumes       []string          `yaml:"volumes,omitempty"`
-	Command       string            `yaml:"command,omitempty"`
-	Networks      []string          `yaml:"networks,omitempty"`
-	HealthCheck   *HealthCheck      `yaml:"healthcheck,omitempty"`
-}
-
-type HealthCheck struct {
-	Test        []string `yaml:"test"`
-	Interval    string   `yaml:"interval,omitempty"`
-	Timeout     string   `yaml:"timeout,omitempty"`
-	Retries     int      `yaml:"retries,omitempty"`
-	StartPeriod string   `yaml:"start_period,omitempty"`
-}
-
-type DockerCompose struct {
-	Version  string              `yaml:"version"`
-	Services map[string]Service  `yaml:"services"`
-	Networks map[string]struct{} `yaml:"networks,omitempty"`
-}
+var keepDefaults bool = false
+var labelSelectors []string
+var projectName string
+var shellName string = convert.ShellPosix
+var multiline bool = false
+var fromFile string
+var outputFormat string = formatRun
+var redact bool = false
+var redactFile string
+var anonymize bool = false
+var sortEnv bool = false
+var mergeFile string
+var exportAll bool = false
+var runningOnly bool = false
+var nameRegex string
+var inspectJobs int = 4
+
+// 支持的输出格式
+const (
+	formatRun       = "run"
+	formatCompose   = "compose"
+	formatK8s       = "k8s"
+	formatSystemd   = "systemd"
+	formatQuadlet   = "quadlet"
+	formatAnsible   = "ansible"
+	formatTerraform = "terraform"
+	formatNomad     = "nomad"
+	formatSpecJSON  = "spec-json"
+	formatSpecYAML  = "spec-yaml"
+)
 
 func init() {
 	dockerCommand.PersistentFlags().BoolVarP(&isCompose, "json", "j", false, "export docker compose file")
+	dockerCommand.PersistentFlags().BoolVar(&isCompose, "compose", false, i18n.T("command.flag.compose"))
+	dockerCommand.PersistentFlags().BoolVar(&keepDefaults, "keep-defaults", false, i18n.T("command.flag.keep_defaults"))
+	dockerCommand.PersistentFlags().StringArrayVarP(&labelSelectors, "label", "l", nil, i18n.T("command.flag.label"))
+	dockerCommand.PersistentFlags().StringVar(&projectName, "project", "", i18n.T("command.flag.project"))
+	dockerCommand.PersistentFlags().StringVar(&shellName, "shell", convert.ShellPosix, i18n.T("command.flag.shell"))
+	dockerCommand.PersistentFlags().BoolVarP(&multiline, "multiline", "m", false, i18n.T("command.flag.multiline"))
+	dockerCommand.PersistentFlags().StringVarP(&fromFile, "from-file", "f", "", i18n.T("command.flag.from_file"))
+	dockerCommand.PersistentFlags().StringVar(&outputFormat, "format", formatRun, i18n.T("command.flag.format"))
+	dockerCommand.PersistentFlags().BoolVar(&redact, "redact", false, i18n.T("command.flag.redact"))
+	dockerCommand.PersistentFlags().StringVar(&redactFile, "redact-file", "", i18n.T("command.flag.redact_file"))
+	dockerCommand.PersistentFlags().BoolVar(&anonymize, "anonymize", false, i18n.T("command.flag.anonymize"))
+	dockerCommand.PersistentFlags().BoolVar(&sortEnv, "sort-env", false, i18n.T("command.flag.sort_env"))
+	dockerCommand.PersistentFlags().StringVar(&mergeFile, "merge", "", i18n.T("command.flag.merge"))
+	dockerCommand.PersistentFlags().StringVarP(&outputPath, "output", "o", "", i18n.T("command.flag.output"))
+	dockerCommand.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, i18n.T("command.flag.yes"))
+	dockerCommand.PersistentFlags().BoolVar(&forceOverwrite, "force", false, i18n.T("command.flag.force"))
+	dockerCommand.PersistentFlags().BoolVar(&exportAll, "all", false, i18n.T("command.flag.all"))
+	dockerCommand.PersistentFlags().BoolVar(&runningOnly, "running", false, i18n.T("command.flag.running"))
+	dockerCommand.PersistentFlags().StringVar(&nameRegex, "name-regex", "", i18n.T("command.flag.name_regex"))
+	dockerCommand.PersistentFlags().IntVar(&inspectJobs, "jobs", 4, i18n.T("command.flag.jobs"))
 	rootCmd.AddCommand(dockerCommand)
 }
 
 var dockerCommand = &cobra.Command{
-	Use:     "command [container id]",
+	Use:     "command [container id...]",
 	Aliases: []string{"c"}, // 添加别名 c
 	Short:   i18n.T("command.short"),
 	Long:    i18n.T("command.long"),
-	Args:    cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		// 没有指定容器时，必须通过 --all、过滤条件、标签、项目名称或 inspect 文件选择容器
+		if len(args) == 0 && !hasContainerFilters() && fromFile == "" {
+			return fmt.Errorf(i18n.T("command.no_container"))
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		// 从 args 中获取 containerId
-		containerId := args[0]
-		// 获取容器配置
-		config, err := getDockerContainerConfig(containerId)
+		if err := convert.ValidateShell(shellName); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		// -j 与 --compose 等价于 --format compose，合并到已有文件时也只能输出 compose
+		if isCompose || mergeFile != "" {
+			outputFormat = formatCompose
+		}
+		writer, err := getWriter(outputFormat)
 		if err != nil {
+			log.Fatalf("Error: %v", fmt.Errorf(i18n.T("command.unsupported_format", outputFormat)))
+		}
+		if err := compileNameFilter(); err != nil {
 			log.Fatalf("Error: %v", err)
 		}
-		if isCompose {
-			yamlData, err := getDockerComposeYaml(config)
+		// --all 为每个容器写入单独的文件，-o 只能是目录
+		if exportAll {
+			if mergeFile != "" || outputPath == "-" {
+				log.Fatalf("Error: %v", fmt.Errorf(i18n.T("command.all_needs_directory")))
+			}
+			if outputPath == "" {
+				outputPath = defaultExportDir
+			}
+		}
+		// 获取容器配置，指定 inspect 文件时不需要连接 Docker，也不会去掉镜像默认值
+		var configs, unstripped []*types.ContainerJSON
+		if fromFile != "" {
+			configs, err = readContainerConfigs(fromFile, args)
+			unstripped = configs
+		} else {
+			var warnings []string
+			configs, unstripped, warnings, err = getDockerContainerConfigs(args)
+			printWarnings(warnings)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if outputFormat == formatCompose {
+			// 报告 compose 创建的容器与原始 compose 文件之间的差异
+			for _, config := range unstripped {
+				drifts, files, err := getComposeDrift(config)
+				if err != nil {
+					rootCmd.PrintErrln(i18n.T("command.drift_failed", err))
+				} else if len(drifts) > 0 {
+					rootCmd.PrintErrln(i18n.T("command.drift_title", convert.ComposeServiceName(config), strings.Join(files, ", ")))
+					for _, drift := range drifts {
+						rootCmd.PrintErrln("   • " + drift)
+					}
+				}
+			}
+		}
+
+		specs := convert.NewSpecs(configs)
+		options := convertOptions{Project: projectName, Format: convert.Format{Shell: shellName, Multiline: multiline}}
+		if writer.Resources {
+			options.Resources = loadDockerResources(specs)
+		}
+		convert.Normalize(specs, convert.NormalizeOptions{SortEnv: sortEnv})
+		// 密钥替换为 ${VAR} 引用，真实的值写入输出文件旁边的 .env 文件，与其他输出文件一起检查是否覆盖
+		var envFiles []outputFile
+		if redact || anonymize {
+			secrets := convert.Redact(specs, convert.RedactOptions{Anonymize: anonymize})
+			if len(secrets) > 0 {
+				envFile, err := renderDotEnv(getDotEnvPath(), secrets)
+				if err != nil {
+					log.Fatalf("Error: %v", err)
+				}
+				if options.EnvFile, err = filepath.Abs(envFile.Path); err != nil {
+					log.Fatalf("Error: %v", err)
+				}
+				envFiles = append(envFiles, envFile)
+				rootCmd.PrintErrln(i18n.T("command.redacted", len(secrets), envFile.Path))
+			}
+		}
+		// -o 指定目录时每个容器（compose 为每个项目）写入单独的文件，--all 时每个容器一个文件并生成索引
+		if exportAll || mergeFile == "" && isOutputDirectory(outputPath) {
+			files, warnings, err := renderOutputFiles(writer, specs, options, outputFormat, outputPath, exportAll)
 			if err != nil {
 				log.Fatalf("Error: %v", err)
 			}
-			if yamlData != "" {
-				// fmt.Println(yamlData)
-				err := writeDockerComposeYaml(config.Name, yamlData)
+			printWarnings(warnings)
+			if exportAll {
+				index, err := renderIndexFile(files, configs, outputPath)
 				if err != nil {
 					log.Fatalf("Error: %v", err)
 				}
+				files = append(files, index)
 			}
-		} else {
-			// 打印容器配置信息
-			runCommand := generateRunCommand(config)
-			fmt.Println(runCommand)
+			if err := writeOutputFiles(append(files, envFiles...), false); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+		output, warnings, err := writer.Write(specs, options)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printWarnings(warnings)
+		switch {
+		case mergeFile != "":
+			// 合并前先写入 .env，已存在的 .env 不能覆盖时不修改 compose 文件
+			if err = writeOutputFiles(envFiles, false); err == nil {
+				err = mergeDockerComposeYaml(mergeFile, output)
+			}
+		case outputFormat == formatCompose:
+			if output != "" {
+				err = writeComposeOutput(getComposeFileName(specs, projectName), output, envFiles...)
+			}
+		default:
+			err = writeOutput(output, envFiles...)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 	},
 }
 
-// 生成 Docker Compose YAML 文件并写入
-func writeDockerComposeYaml(containerName string, yamlData string) error {
-	currentDir, err := os.Getwd()
+// 查询网络与卷的详细信息，读取 inspect 文件或查询失败时返回 nil，按外部资源处理
+func loadDockerResources(specs []*convert.ContainerSpec) *convert.DockerResources {
+	if fromFile != "" {
+		return nil
+	}
+	resources, err := getDockerResources(specs)
 	if err != nil {
-		return fmt.Errorf(i18n.T("error.get_current_dir", err))
+		rootCmd.PrintErrln(i18n.T("command.resources_skipped", err))
+		return nil
 	}
+	return resources
+}
 
-	fileName := fmt.Sprintf("%s.yml", containerName)
-	filePath := filepath.Join(currentDir, fileName)
+// 将无法转换的配置输出到标准错误
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		rootCmd.PrintErrln(i18n.T("command.warning", warning))
+	}
+}
 
-	fmt.Printf(i18n.T("command.compose_confirm", filePath))
+// 导出容器时使用的 Docker API，测试中替换为假的客户端
+type dockerClient interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	Close() error
+}
+
+// 创建 Docker 客户端，测试中替换
+var newDockerClient = func() (dockerClient, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
 
-	var confirm string            // Declare confirm outside the if block
-	_, err = fmt.Scanln(&confirm) // Use _ to ignore the return value of a
+// 从 Docker 获取所有选中容器的配置，同时返回去掉镜像默认值之前的配置
+// 单个容器查询失败时跳过并返回警告，所有容器都失败时才返回错误
+func getDockerContainerConfigs(args []string) ([]*types.ContainerJSON, []*types.ContainerJSON, []string, error) {
+	cli, err := newDockerClient()
 	if err != nil {
-		return fmt.Errorf(i18n.T("error.read_user_input", err))
+		return nil, nil, nil, fmt.Errorf("failed to create Docker client: %v", err)
 	}
+	defer cli.Close()
 
-	if strings.ToLower(confirm) != "y" {
-		fmt.Println(i18n.T("command.compose_cancelled"))
-		return nil
+	// 获取所有需要导出的容器 ID
+	containerIds, err := resolveContainerIds(cli, args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	images := newImageConfigCache(cli)
+
+	// 并发查询容器，工作协程数量由 --jobs 限制，所有协程共用一个客户端，结果保持容器的顺序
+	configs := make([]*types.ContainerJSON, len(containerIds))
+	unstripped := make([]*types.ContainerJSON, len(containerIds))
+	errs := make([]error, len(containerIds))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, min(inspectJobs, len(containerIds))); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				configs[i], unstripped[i], errs[i] = getStrippedContainerConfig(cli, images, containerIds[i])
+			}
+		}()
+	}
+	for i := range containerIds {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
-	err = os.WriteFile(filePath, []byte(yamlData), 0644)
+	var inspected, inspectedUnstripped []*types.ContainerJSON
+	var warnings []string
+	for i, err := range errs {
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v, skipped", containerIds[i], err))
+			continue
+		}
+		inspected = append(inspected, configs[i])
+		inspectedUnstripped = append(inspectedUnstripped, unstripped[i])
+	}
+	if len(inspected) == 0 && len(containerIds) > 0 {
+		return nil, nil, nil, errors.Join(errs...)
+	}
+	return inspected, inspectedUnstripped, warnings, nil
+}
+
+// 查询容器配置，未指定 --keep-defaults 时去掉镜像自带的默认配置，同时返回查询到的原始配置
+func getStrippedContainerConfig(cli dockerClient, images *imageConfigCache, containerId string) (*types.ContainerJSON, *types.ContainerJSON, error) {
+	config, err := inspectDockerContainer(cli, containerId)
 	if err != nil {
-		return fmt.Errorf(i18n.T("error.write_file", err))
+		return nil, nil, err
+	}
+	if keepDefaults {
+		return config, config, nil
 	}
+	imageConfig, err := images.get(config.Image)
+	if err != nil {
+		rootCmd.PrintErrln(i18n.T("command.image_defaults_skipped", err))
+		return config, config, nil
+	}
+	return stripImageDefaults(config, imageConfig), config, nil
+}
 
-	fmt.Printf(i18n.T("command.compose_written", fileName))
-	fmt.Println()
-	return nil
+// 是否通过 --all、--running、--name-regex、标签或项目名称选择容器
+func hasContainerFilters() bool {
+	return exportAll || runningOnly || nameRegex != "" || len(labelSelectors) > 0 || projectName != ""
 }
 
-// 生成 docker compose yaml 文件
-func getDockerComposeYaml(config *types.ContainerJSON) (string, error) {
-	// 解析容器配置
-	container := config.Config
-	hostConfig := config.HostConfig
+// 编译后的 --name-regex，未指定时为 nil
+var nameFilter *regexp.Regexp
 
-	// 构建 Service
-	service := Service{
-		Image:         container.Image,
-		ContainerName: strings.TrimPrefix(config.Name, "/"), // 去掉容器名前缀的 "/"
-		Command:       strings.Join(container.Cmd, " "),
+// 编译 --name-regex，在选择容器之前调用一次
+func compileNameFilter() error {
+	if nameRegex == "" {
+		nameFilter = nil
+		return nil
 	}
-
-	// 解析端口映射
-	for port, bindings := range hostConfig.PortBindings {
-		for _, binding := range bindings {
-			service.Ports = append(service.Ports, fmt.Sprintf("%s:%s", binding.HostPort, port))
-		}
+	re, err := regexp.Compile(nameRegex)
+	if err != nil {
+		return fmt.Errorf("invalid --name-regex: %v", err)
 	}
+	nameFilter = re
+	return nil
+}
 
-	// 解析环境变量
-	service.Environment = make(map[string]string)
-	for _, env := range container.Env {
-		parts := strings.SplitN(env, "=", 2)
-		if len(parts) == 2 {
-			service.Environment[parts[0]] = parts[1]
-		}
+// 容器是否满足 --running 与 --name-regex
+func matchContainerFilters(name string, running bool) bool {
+	if runningOnly && !running {
+		return false
 	}
+	return nameFilter == nil || nameFilter.MatchString(strings.TrimPrefix(name, "/"))
+}
 
-	// 解析挂载卷
-	for _, mount := range hostConfig.Mounts {
-		service.Volumes = append(service.Volumes, fmt.Sprintf("%s:%s", mount.Source, mount.Target))
+// 过滤条件的描述，用于没有匹配的容器时提示
+func describeContainerFilters() string {
+	filters := getLabelSelectors()
+	if runningOnly {
+		filters = append(filters, "--running")
 	}
-
-	// 解析网络
-	if hostConfig.NetworkMode != "" {
-		service.Networks = []string{string(hostConfig.NetworkMode)}
+	if nameRegex != "" {
+		filters = append(filters, "--name-regex "+nameRegex)
 	}
-
-	// 解析健康检查
-	if container.Healthcheck != nil {
-		service.HealthCheck = &HealthCheck{
-			Test:        container.Healthcheck.Test,
-			Interval:    fmt.Sprintf("%dns", container.Healthcheck.Interval),
-			Timeout:     fmt.Sprintf("%dns", container.Healthcheck.Timeout),
-			Retries:     container.Healthcheck.Retries,
-			StartPeriod: fmt.Sprintf("%dns", container.Healthcheck.StartPeriod),
-		}
+	if len(filters) == 0 {
+		return "--all"
 	}
+	return strings.Join(filters, ", ")
+}
 
-	// 构建 Docker Compose 配置
-	compose := DockerCompose{
-		Version: "3.8",
-		Services: map[string]Service{
-			"app": service,
-		},
+// 标签选择器，--project 等价于 compose 项目标签
+func getLabelSelectors() []string {
+	selectors := append([]string{}, labelSelectors...)
+	if projectName != "" {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", convert.ComposeProjectLabel, projectName))
 	}
+	return selectors
+}
+
+// 合并命令行参数与标签、项目选择器得到的容器 ID，保持顺序并去重
+func resolveContainerIds(cli dockerClient, args []string) ([]string, error) {
+	containerIds := append([]string{}, args...)
 
-	// 添加网络配置
-	if len(service.Networks) > 0 {
-		compose.Networks = make(map[string]struct{})
-		for _, network := range service.Networks {
-			compose.Networks[network] = struct{}{}
+	if hasContainerFilters() {
+		containerFilters := filters.NewArgs()
+		for _, selector := range getLabelSelectors() {
+			containerFilters.Add("label", selector)
+		}
+		listed, err := cli.ContainerList(context.Background(), container.ListOptions{
+			All:     !runningOnly,
+			Filters: containerFilters,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list containers: %v", err)
+		}
+		var containers []types.Container
+		for _, c := range listed {
+			if len(c.Names) > 0 && matchContainerFilters(c.Names[0], c.State == "running") {
+				containers = append(containers, c)
+			}
+		}
+		if len(containers) == 0 {
+			return nil, fmt.Errorf(i18n.T("command.no_container_matched", describeContainerFilters()))
+		}
+		// docker 按创建时间返回容器，按名称排序使重新创建容器后输出顺序不变
+		slices.SortFunc(containers, func(a, b types.Container) int {
+			return strings.Compare(strings.Join(a.Names, ","), strings.Join(b.Names, ","))
+		})
+		for _, c := range containers {
+			containerIds = append(containerIds, c.ID)
 		}
 	}
 
-	// 转换为 YAML
-	yamlData, err := yaml.Marshal(&compose)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal YAML: %v", err)
+	seen := make(map[string]bool)
+	var result []string
+	for _, id := range containerIds {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
 	}
+	return result, nil
+}
 
-	return string(yamlData), nil
+// compose 文件名：优先使用项目名称，单个容器时使用容器名称
+func getComposeFileName(specs []*convert.ContainerSpec, project string) string {
+	if project == "" {
+		project = convert.ComposeProjectName(specs)
+	}
+	if project != "" {
+		return project
+	}
+	if len(specs) == 1 {
+		return specs[0].Name
+	}
+	return "docker-compose"
 }
 
-// 获取容器的配置信息
-func getDockerContainerConfig(containerID string) (*types.ContainerJSON, error) {
-	// 创建 Docker 客户端
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// 将导出的 service 合并到已有的 compose 文件，确认 diff 后写入
+func mergeDockerComposeYaml(fileName string, yamlData string) error {
+	existing, err := os.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", fileName, err)
+	}
+	merged, warnings, err := convert.MergeCompose(existing, []byte(yamlData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Docker client: %v", err)
+		return err
 	}
+	printWarnings(warnings)
 
-	// 获取容器的详细信息
-	containerInfo, err := cli.ContainerInspect(context.Background(), containerID)
+	diff := unifiedDiff(fileName, fileName, string(existing), string(merged))
+	if diff == "" {
+		fmt.Println(i18n.T("command.merge_unchanged", fileName))
+		return nil
+	}
+	fmt.Print(diff)
+	confirmed, err := confirm(i18n.T("command.merge_confirm", fileName))
 	if err != nil {
-		return nil, fmt.Errorf("failed to inspect container: %v", err)
+		return err
+	}
+	if !confirmed {
+		fmt.Println(i18n.T("command.compose_cancelled"))
+		return nil
 	}
 
-	return &containerInfo, nil
+	// 保留已有文件的权限
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(fileName, merged, mode); err != nil {
+		return fmt.Errorf(i18n.T("error.write_file", err))
+	}
+	fmt.Println(i18n.T("command.merge_written", fileName))
+	return nil
 }
 
-// 转换为命令行
-func generateRunCommand(config *types.ContainerJSON) string {
-	// LogObject(config)
-	var cmd strings.Builder
-
-	cmd.WriteString("docker run")
-	// 添加容器的名称
-	if config.Name != "" {
-		cmd.WriteString(fmt.Sprintf(" --name %s -d", strings.ReplaceAll(config.Name, "/", "")))
-	}
-	// 绑定端口映射
-	for key, value := range config.HostConfig.PortBindings {
-		if value[0].HostPort != "" {
-			parts := strings.Split(string(key), "/")
-			cmd.WriteString(fmt.Sprintf(" -p %s:%s", value[0].HostPort, parts[0]))
-		}
+// .env 文件的路径：指定 --redact-file 时使用指定的路径，否则放在输出文件所在的目录，compose 可以直接读取
+func getDotEnvPath() string {
+	if redactFile != "" {
+		return redactFile
 	}
-	// 绑定映射目录
-	for _, mount := range config.Mounts {
-		cmd.WriteString(fmt.Sprintf(" -v %s:%s", mount.Source, mount.Destination))
+	dir := "."
+	switch {
+	case mergeFile != "":
+		dir = filepath.Dir(mergeFile)
+	case exportAll || isOutputDirectory(outputPath):
+		dir = outputPath
+	case outputPath != "" && outputPath != "-":
+		dir = filepath.Dir(outputPath)
 	}
+	return filepath.Join(dir, ".env")
+}
 
-	// add env vars
-	for _, env := range config.Config.Env {
-		// 默认的环境变量直接抛弃
-		if !strings.HasPrefix(env, "PATH") {
-			// 判断 env 是否有值
-			envInfo := strings.Split(env, "=")
-			// 如果 env 变量只有 key 没有 value，那么直接抛弃
-			if envInfo[0] == "" && envInfo[1] != "" {
-				cmd.WriteString(fmt.Sprintf(" -e %s", env))
-			}
-		}
+// 生成 .env 文件，文件已存在时只更新同名变量，权限始终为 0600
+func renderDotEnv(fileName string, secrets []convert.Secret) (outputFile, error) {
+	var lines []string
+	data, err := os.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return outputFile{}, fmt.Errorf("failed to read %s: %v", fileName, err)
 	}
-
-	// add device
-	for _, device := range config.HostConfig.Devices {
-		cmd.WriteString(fmt.Sprintf("--device %s:%s", device.PathOnHost, device.PathInContainer))
+	values := make(map[string]string)
+	for _, secret := range secrets {
+		values[secret.Name] = secret.Value
 	}
-
-	// Add CPU limit
-	if config.HostConfig.NanoCPUs > 0 {
-		cpuLimit := fmt.Sprintf("%f", float64(config.HostConfig.NanoCPUs)/1_000_000_000) // Convert NanoCPUs to CPU units
-		cmd.WriteString(fmt.Sprintf(" --cpus=%s", cpuLimit))
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		key, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		if _, ok := values[strings.TrimSpace(key)]; ok || line == "" {
+			continue
+		}
+		lines = append(lines, line)
 	}
-
-	// Add Memory limit
-	if config.HostConfig.Memory > 0 {
-		memoryLimit := units.BytesSize(float64(config.HostConfig.Memory))
-		cmd.WriteString(fmt.Sprintf(" --memory=%s", memoryLimit))
+	content := convert.DotEnv(secrets)
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n" + content
 	}
+	return outputFile{Path: fileName, Data: content, Mode: 0600}, nil
+}
 
-	// Add other options from config (e.g., restart policy, network)
-	if config.HostConfig.RestartPolicy.Name != "" {
-		cmd.WriteString(fmt.Sprintf(" --restart %s", config.HostConfig.RestartPolicy.Name))
+// 获取容器的配置信息
+func getDockerContainerConfig(containerID string) (*types.ContainerJSON, error) {
+	// 创建 Docker 客户端
+	cli, err := newDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %v", err)
 	}
+	defer cli.Close()
 
-	if config.HostConfig.NetworkMode != "" {
-		cmd.WriteString(fmt.Sprintf(" --network %s", config.HostConfig.NetworkMode))
-	}
+	return inspectDockerContainer(cli, containerID)
+}
 
-	// 设置容器的镜像
-	if config.Config.Image != "" {
-		cmd.WriteString(fmt.Sprintf(" %s", config.Config.Image))
+// 使用已有的客户端获取容器的详细信息
+func inspectDockerContainer(cli dockerClient, containerID string) (*types.ContainerJSON, error) {
+	// 获取容器的详细信息
+	containerInfo, err := cli.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %v", err)
 	}
 
-	return cmd.String()
+	return &containerInfo, nil
 }
 
 func LogObject[T any](info T) {
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 假的 Docker 客户端，记录同时进行的查询数量
+type fakeDocker struct {
+	containers []types.Container
+	failing    map[string]bool
+
+	mu       sync.Mutex
+	list     container.ListOptions
+	active   int
+	maxCalls int
+}
+
+func (f *fakeDocker) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	f.list = options
+	var result []types.Container
+	for _, c := range f.containers {
+		if options.All || c.State == "running" {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeDocker) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	f.active++
+	f.maxCalls = max(f.maxCalls, f.active)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+	}()
+
+	if f.failing[containerID] {
+		return types.ContainerJSON{}, fmt.Errorf("No such container: %s", containerID)
+	}
+	for _, c := range f.containers {
+		if c.ID == containerID {
+			return types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{ID: c.ID, Name: c.Names[0], Image: c.Image, State: &types.ContainerState{Status: c.State}},
+				Config:            &container.Config{Image: c.Image},
+			}, nil
+		}
+	}
+	return types.ContainerJSON{}, fmt.Errorf("No such container: %s", containerID)
+}
+
+func (f *fakeDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{Config: &container.Config{}}, nil, nil
+}
+
+func (f *fakeDocker) Close() error { return nil }
+
+// 替换 Docker 客户端与选择容器的参数，测试结束后恢复
+func useFakeDocker(t *testing.T, fake *fakeDocker) {
+	t.Helper()
+	original := newDockerClient
+	newDockerClient = func() (dockerClient, error) { return fake, nil }
+	t.Cleanup(func() {
+		newDockerClient = original
+		exportAll, runningOnly, nameRegex, nameFilter, inspectJobs = false, false, "", nil, 4
+	})
+}
+
+func fakeContainers() []types.Container {
+	return []types.Container{
+		{ID: "c3", Names: []string{"/web"}, Image: "nginx", State: "running"},
+		{ID: "c1", Names: []string{"/api"}, Image: "example/api", State: "exited"},
+		{ID: "c2", Names: []string{"/api-worker"}, Image: "example/api", State: "running"},
+	}
+}
+
+// --all、--running 与 --name-regex 选择的容器，按名称排序
+func TestResolveContainerIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		all     bool
+		running bool
+		regex   string
+		want    []string
+		err     string
+	}{
+		{name: "args", args: []string{"c2", "c2", "c3"}, want: []string{"c2", "c3"}},
+		{name: "all", all: true, want: []string{"c1", "c2", "c3"}},
+		{name: "running", running: true, want: []string{"c2", "c3"}},
+		{name: "name regex", regex: "^api", want: []string{"c1", "c2"}},
+		{name: "running and name regex", running: true, regex: "^api", want: []string{"c2"}},
+		{name: "args and filters", args: []string{"c3"}, regex: "^api", want: []string{"c3", "c1", "c2"}},
+		{name: "no match", regex: "^db$", err: "command.no_container_matched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDocker{containers: fakeContainers()}
+			useFakeDocker(t, fake)
+			exportAll, runningOnly, nameRegex = tt.all, tt.running, tt.regex
+			if err := compileNameFilter(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := resolveContainerIds(fake, tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %q, want %q", got, tt.want)
+			}
+			if hasContainerFilters() && fake.list.All == tt.running {
+				t.Errorf("list all = %v with --running %v", fake.list.All, tt.running)
+			}
+		})
+	}
+}
+
+// 并发查询保持容器的顺序，单个容器失败时跳过并返回警告
+func TestGetDockerContainerConfigs(t *testing.T) {
+	var containers []types.Container
+	var ids []string
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("c%02d", i)
+		containers = append(containers, types.Container{ID: id, Names: []string{"/" + id}, Image: "busybox", State: "running"})
+		ids = append(ids, id)
+	}
+	fake := &fakeDocker{containers: containers, failing: map[string]bool{"c07": true}}
+	useFakeDocker(t, fake)
+	inspectJobs = 3
+
+	configs, unstripped, warnings, err := getDockerContainerConfigs(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, config := range configs {
+		got = append(got, config.ID)
+	}
+	want := slices.DeleteFunc(slices.Clone(ids), func(id string) bool { return id == "c07" })
+	if !slices.Equal(got, want) {
+		t.Errorf("configs = %q, want %q", got, want)
+	}
+	if len(unstripped) != len(configs) {
+		t.Errorf("got %d unstripped configs for %d configs", len(unstripped), len(configs))
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "c07: ") {
+		t.Errorf("warnings = %q, want one warning for c07", warnings)
+	}
+	if fake.maxCalls > inspectJobs {
+		t.Errorf("%d concurrent inspects, want at most %d", fake.maxCalls, inspectJobs)
+	}
+
+	// 所有容器都查询失败时返回错误
+	fake.failing = map[string]bool{"c00": true}
+	if _, _, _, err := getDockerContainerConfigs([]string{"c00"}); err == nil || !strings.Contains(err.Error(), "No such container: c00") {
+		t.Errorf("err = %v, want inspect error", err)
+	}
+}
+
+// 索引文件列出每个容器、对应的文件与状态
+func TestRenderIndexFile(t *testing.T) {
+	web := &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{ID: "c3", State: &types.ContainerState{Status: "running"}}}
+	files := []outputFile{
+		{Path: "out/web.yml", Specs: []*convert.ContainerSpec{{ID: "c3", Name: "web", Image: "nginx", Compose: &convert.ComposeInfo{Project: "shop"}}}},
+		{Path: "out/api.yml", Specs: []*convert.ContainerSpec{{ID: "c1", Name: "api", Image: "example/api"}}},
+	}
+
+	index, err := renderIndexFile(files, []*types.ContainerJSON{web}, "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Path != "out/"+indexFileName {
+		t.Errorf("path = %q", index.Path)
+	}
+	var entries []indexEntry
+	if err := json.Unmarshal([]byte(index.Data), &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []indexEntry{
+		{Name: "web", ID: "c3", Image: "nginx", Status: "running", Project: "shop", File: "web.yml"},
+		{Name: "api", ID: "c1", Image: "example/api", File: "api.yml"},
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+}
+
+// .env 默认放在输出文件所在的目录，指定 --redact-file 时使用指定的路径
+func TestGetDotEnvPath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		output string
+		merge  string
+		file   string
+		want   string
+	}{
+		{name: "stdout", want: ".env"},
+		{name: "output file", output: "deploy/web.yml", want: "deploy/.env"},
+		{name: "output directory", output: dir, want: filepath.Join(dir, ".env")},
+		{name: "merge", merge: "stack/docker-compose.yml", want: "stack/.env"},
+		{name: "redact file", output: dir, file: "secrets.env", want: "secrets.env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath, mergeFile, redactFile = tt.output, tt.merge, tt.file
+			t.Cleanup(func() { outputPath, mergeFile, redactFile = "", "", "" })
+			if got := getDotEnvPath(); got != tt.want {
+				t.Errorf("path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 已存在的 .env 只更新同名变量，与其他输出一样需要确认才能覆盖，写入后权限为 0600
+func TestRenderDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("# shop\nDB_PASSWORD=old\nTZ=UTC\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdinTerminal = func() bool { return false }
+	t.Cleanup(func() {
+		stdinTerminal = stdinIsTerminal
+		forceOverwrite = false
+	})
+
+	file, err := renderDotEnv(path, []convert.Secret{{Name: "DB_PASSWORD", Value: "new"}, {Name: "API_TOKEN", Value: "t0k3n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# shop\nTZ=UTC\nDB_PASSWORD=new\nAPI_TOKEN=t0k3n\n"; file.Data != want {
+		t.Errorf("data = %q, want %q", file.Data, want)
+	}
+	if err := writeOutput("docker run nginx\n", file); err == nil {
+		t.Fatal("existing .env was overwritten without --force")
+	}
+	forceOverwrite = true
+	if err := writeOutput("docker run nginx\n", file); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/helson-lin/doke/pkg/convert"
+	"gopkg.in/yaml.v3"
+)
+
+// compose 写入容器的标签，记录创建容器时使用的 compose 文件
+const (
+	composeConfigFilesLabel = "com.docker.compose.project.config_files"
+	composeWorkingDirLabel  = "com.docker.compose.project.working_dir"
+)
+
+// 读取创建容器时使用的 compose 文件中对应的 service 定义，文件不存在时返回 nil
+func loadComposeSourceService(config *types.ContainerJSON) (map[string]interface{}, []string, error) {
+	labels := config.Config.Labels
+	serviceName := labels[convert.ComposeServiceLabel]
+	if serviceName == "" || labels[composeConfigFilesLabel] == "" {
+		return nil, nil, nil
+	}
+
+	var files []string
+	service := make(map[string]interface{})
+	for _, file := range strings.Split(labels[composeConfigFilesLabel], ",") {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(labels[composeWorkingDirLabel], file)
+		}
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var document struct {
+			Services map[string]map[string]interface{} `yaml:"services"`
+		}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		// 多个文件时后面的文件覆盖前面的配置
+		for key, value := range document.Services[serviceName] {
+			service[key] = value
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil
+	}
+
+	return service, files, nil
+}
+
+// 对比 compose 文件与运行中的容器，返回发生变化的字段
+// config 必须是去掉镜像默认值之前的配置，compose 文件中与镜像相同的值不算变化
+func getComposeDrift(config *types.ContainerJSON) ([]string, []string, error) {
+	source, files, err := loadComposeSourceService(config)
+	if err != nil || source == nil {
+		return nil, nil, err
+	}
+	service, _, _ := convert.ComposeService(convert.NewSpec(config), "", nil)
+
+	var drifts []string
+	addDrift := func(field string, expected, actual interface{}) {
+		drifts = append(drifts, fmt.Sprintf("%s: %v -> %v", field, expected, actual))
+	}
+
+	if image, ok := source["image"].(string); ok && !strings.Contains(image, "${") && image != service.Image {
+		addDrift("image", image, service.Image)
+	}
+
+	if command, ok := source["command"]; ok {
+		expected := normalizeComposeCommand(strings.Join(composeStringList(command), " "))
+		if expected != normalizeComposeCommand(strings.Join(service.Command, " ")) {
+			addDrift("command", expected, strings.Join(service.Command, " "))
+		}
+	}
+
+	// 环境变量只比较文件中声明的 key，包含变量插值的值无法比较
+	env := make(map[string]string)
+	for _, item := range config.Config.Env {
+		key, value, _ := strings.Cut(item, "=")
+		env[key] = value
+	}
+	expectedEnv := composeMapping(source["environment"])
+	for _, key := range sortedKeys(expectedEnv) {
+		expected := expectedEnv[key]
+		if strings.Contains(expected, "${") {
+			continue
+		}
+		expected = strings.ReplaceAll(expected, "$$", "$")
+		if actual, ok := env[key]; !ok {
+			addDrift("environment."+key, expected, "<unset>")
+		} else if actual != expected {
+			addDrift("environment."+key, expected, actual)
+		}
+	}
+
+	if ports, ok := source["ports"]; ok {
+		expected := normalizeComposePorts(composePortList(ports))
+		actual := normalizeComposePorts(service.Ports)
+		if !reflect.DeepEqual(expected, actual) {
+			addDrift("ports", expected, actual)
+		}
+	}
+
+	return drifts, files, nil
+}
+
+// compose 中的字符串或列表统一转换为列表
+func composeStringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, fmt.Sprint(item))
+		}
+		return result
+	}
+	return nil
+}
+
+// compose 中的端口列表，长格式转换为与生成结果相同的短格式
+func composePortList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return composeStringList(value)
+	}
+	var result []string
+	for _, item := range items {
+		port, ok := item.(map[string]interface{})
+		if !ok {
+			result = append(result, fmt.Sprint(item))
+			continue
+		}
+		short := fmt.Sprint(port["target"])
+		if protocol, _ := port["protocol"].(string); protocol != "" {
+			short += "/" + protocol
+		}
+		published := ""
+		if port["published"] != nil {
+			published = fmt.Sprint(port["published"])
+		}
+		hostIP, _ := port["host_ip"].(string)
+		if strings.Contains(hostIP, ":") {
+			hostIP = "[" + hostIP + "]"
+		}
+		switch {
+		case hostIP != "":
+			short = hostIP + ":" + published + ":" + short
+		case published != "":
+			short = published + ":" + short
+		}
+		result = append(result, short)
+	}
+	return result
+}
+
+// 去掉引号和多余空白后的命令，字符串形式与列表形式可以互相比较
+func normalizeComposeCommand(command string) string {
+	command = strings.NewReplacer("'", "", "\"", "").Replace(command)
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// compose 中的 map 或 KEY=VALUE 列表统一转换为 map
+func composeMapping(value interface{}) map[string]string {
+	result := make(map[string]string)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil {
+				result[key] = ""
+			} else {
+				result[key] = fmt.Sprint(item)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			key, val, _ := strings.Cut(fmt.Sprint(item), "=")
+			result[key] = val
+		}
+	}
+	return result
+}
+
+// 端口映射去掉默认的 tcp 协议后排序，便于比较
+func normalizeComposePorts(ports []string) []string {
+	result := make([]string, 0, len(ports))
+	for _, port := range ports {
+		result = append(result, strings.TrimSuffix(port, "/tcp"))
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// 查询容器使用的网络与卷，查询失败的资源会被当作已存在的外部资源
+func getDockerResources(specs []*convert.ContainerSpec) (*convert.DockerResources, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %v", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	resources := &convert.DockerResources{
+		Networks: make(map[string]types.NetworkResource),
+		Volumes:  make(map[string]volume.Volume),
+	}
+	for _, spec := range specs {
+		for _, attachment := range spec.Network.Networks {
+			if _, ok := resources.Networks[attachment.Name]; ok {
+				continue
+			}
+			if network, err := cli.NetworkInspect(ctx, attachment.Name, types.NetworkInspectOptions{}); err == nil {
+				resources.Networks[attachment.Name] = network
+			}
+		}
+		for _, mount := range spec.Mounts {
+			if mount.Type != "volume" || mount.Source == "" {
+				continue
+			}
+			if _, ok := resources.Volumes[mount.Source]; ok {
+				continue
+			}
+			if vol, err := cli.VolumeInspect(ctx, mount.Source); err == nil {
+				resources.Volumes[mount.Source] = vol
+			}
+		}
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// compose 文件中与镜像默认值相同的 command 与环境变量不算变化，去掉默认值不能影响对比
+func TestComposeDriftIgnoresImageDefaults(t *testing.T) {
+	dir := t.TempDir()
+	compose := `services:
+  web:
+    image: nginx:1.25
+    command: ["nginx", "-g", "daemon off;"]
+    environment:
+      NGINX_VERSION: "1.25"
+      MODE: prod
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+	image := &container.Config{
+		Cmd: []string{"nginx", "-g", "daemon off;"},
+		Env: []string{"PATH=/usr/bin", "NGINX_VERSION=1.25"},
+	}
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: "abc", Name: "/web", HostConfig: &container.HostConfig{}},
+		Config: &container.Config{
+			Image: "nginx:1.25",
+			Cmd:   []string{"nginx", "-g", "daemon off;"},
+			Env:   []string{"PATH=/usr/bin", "NGINX_VERSION=1.25", "MODE=dev"},
+			Labels: map[string]string{
+				convert.ComposeServiceLabel: "web",
+				composeConfigFilesLabel:     "compose.yaml",
+				composeWorkingDirLabel:      dir,
+			},
+		},
+	}
+
+	stripped := stripImageDefaults(config, image)
+	if stripped.Config.Cmd != nil || !slices.Equal(stripped.Config.Env, []string{"MODE=dev"}) {
+		t.Errorf("stripped config = %q %q, want image defaults removed", stripped.Config.Cmd, stripped.Config.Env)
+	}
+	drifts, _, err := getComposeDrift(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"environment.MODE: prod -> dev"}; !slices.Equal(drifts, want) {
+		t.Errorf("drifts = %q, want %q", drifts, want)
+	}
+}
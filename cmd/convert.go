@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/helson-lin/doke/i18n"
+	"github.com/helson-lin/doke/pkg/convert"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var convertFile string
+var convertProject string
+var composeFile string
+var composeEnvFile string
+var composeProfiles []string
+var convertFrom string
+var convertTo string
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [input...]",
+	Short: i18n.T("convert.short"),
+	Long:  i18n.T("convert.long"),
+	Example: `  doke convert --from container --to k8s web db
+  doke convert --from inspect --to compose -f inspect.json
+  doke convert --from run --to systemd "docker run -d --name web nginx"
+  doke convert --from compose --to nomad -f docker-compose.yml web`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if convertFrom == "" {
+			cmd.Help()
+			return
+		}
+		if err := convert.ValidateShell(shellName); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		reader, err := getReader(convertFrom)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		writer, err := getWriter(convertTo)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		input := convertInput{Args: args, File: convertFile, Project: convertProject, EnvFile: composeEnvFile, Profiles: composeProfiles}
+		specs, warnings, err := reader.Read(input)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printWarnings(warnings)
+
+		options := convertOptions{Project: convertProject, Format: convert.Format{Shell: shellName, Multiline: multiline}}
+		// 只有来自 Docker 的容器可以查询网络与卷的详细信息
+		if writer.Resources && reader.Docker {
+			options.Resources = loadDockerResources(specs)
+		}
+		convert.Normalize(specs, convert.NormalizeOptions{SortEnv: sortEnv})
+		if isOutputDirectory(outputPath) {
+			files, warnings, err := renderOutputFiles(writer, specs, options, convertTo, outputPath, false)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			printWarnings(warnings)
+			if err := writeOutputFiles(files, false); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+		output, warnings, err := writer.Write(specs, options)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printWarnings(warnings)
+		if err := writeOutput(output); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+var runToComposeCmd = &cobra.Command{
+	Use:   "run-to-compose [docker run command]",
+	Short: i18n.T("convert.run_to_compose.short"),
+	Long:  i18n.T("convert.run_to_compose.long"),
+	Example: `  doke convert run-to-compose "docker run -d --name web -p 8080:80 nginx"
+  doke convert run-to-compose -- docker run -d --name web -p 8080:80 nginx
+  doke convert run-to-compose -f README.md`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && convertFile == "" {
+			return fmt.Errorf(i18n.T("convert.no_input"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		commands, connects, err := readDockerRunCommands(convertFile, args)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		yamlData, warnings, err := getRunComposeYaml(commands, connects, convertProject)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printWarnings(warnings)
+
+		fileName := convertProject
+		if fileName == "" {
+			fileName = "docker-compose"
+		}
+		if err := writeComposeOutput(fileName, yamlData); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+var composeToRunCmd = &cobra.Command{
+	Use:   "compose-to-run [service...]",
+	Short: i18n.T("convert.compose_to_run.short"),
+	Long:  i18n.T("convert.compose_to_run.long"),
+	Example: `  doke convert compose-to-run
+  doke convert compose-to-run -f docker-compose.yml --profile debug web
+  doke convert compose-to-run --shell powershell -m > up.ps1`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := convert.ValidateShell(shellName); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		file, err := findComposeFile(composeFile)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		project, err := loadComposeProject(file, composeEnvFile, convertProject)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		order, err := orderComposeServices(project, args, activeComposeProfiles(project, composeProfiles))
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		commands, warnings, err := getComposeRunCommands(project, order, shellName, multiline)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printWarnings(append(project.Warnings, warnings...))
+		if err := writeOutput(commands); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	convertCmd.Flags().StringVar(&convertFrom, "from", "", i18n.T("convert.flag.from"))
+	convertCmd.Flags().StringVar(&convertTo, "to", formatRun, i18n.T("convert.flag.to"))
+	convertCmd.Flags().StringVarP(&convertFile, "file", "f", "", i18n.T("convert.flag.input_file"))
+	convertCmd.Flags().StringVar(&convertProject, "project", "", i18n.T("convert.flag.compose_project"))
+	convertCmd.Flags().StringVar(&composeEnvFile, "env-file", "", i18n.T("convert.flag.env_file"))
+	convertCmd.Flags().StringArrayVar(&composeProfiles, "profile", nil, i18n.T("convert.flag.profile"))
+	convertCmd.Flags().StringVar(&shellName, "shell", convert.ShellPosix, i18n.T("command.flag.shell"))
+	convertCmd.Flags().BoolVarP(&multiline, "multiline", "m", false, i18n.T("command.flag.multiline"))
+	convertCmd.Flags().BoolVar(&sortEnv, "sort-env", false, i18n.T("command.flag.sort_env"))
+	runToComposeCmd.Flags().StringVarP(&convertFile, "file", "f", "", i18n.T("convert.flag.file"))
+	runToComposeCmd.Flags().StringVar(&convertProject, "project", "", i18n.T("convert.flag.project"))
+	composeToRunCmd.Flags().StringVarP(&composeFile, "file", "f", "", i18n.T("convert.flag.compose_file"))
+	composeToRunCmd.Flags().StringVar(&convertProject, "project", "", i18n.T("convert.flag.compose_project"))
+	composeToRunCmd.Flags().StringVar(&composeEnvFile, "env-file", "", i18n.T("convert.flag.env_file"))
+	composeToRunCmd.Flags().StringArrayVar(&composeProfiles, "profile", nil, i18n.T("convert.flag.profile"))
+	composeToRunCmd.Flags().StringVar(&shellName, "shell", convert.ShellPosix, i18n.T("command.flag.shell"))
+	composeToRunCmd.Flags().BoolVarP(&multiline, "multiline", "m", false, i18n.T("command.flag.multiline"))
+	convertCmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", i18n.T("command.flag.output"))
+	convertCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, i18n.T("command.flag.yes"))
+	convertCmd.PersistentFlags().BoolVar(&forceOverwrite, "force", false, i18n.T("command.flag.force"))
+	convertCmd.AddCommand(runToComposeCmd)
+	convertCmd.AddCommand(composeToRunCmd)
+	rootCmd.AddCommand(convertCmd)
+}
+
+// 读取 docker run 命令：指定文件时从文件中查找，单个参数按 shell 规则分词，多个参数视为已分好的词
+// 同时返回文件中连接其他网络的 docker network connect 命令
+func readDockerRunCommands(file string, args []string) ([][]string, [][]string, error) {
+	var commands, connects [][]string
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		if commands, connects, err = splitDockerRunCommands(string(data)); err != nil {
+			return nil, nil, err
+		}
+	case len(args) == 1:
+		var err error
+		if commands, connects, err = splitDockerRunCommands(args[0]); err != nil {
+			return nil, nil, err
+		}
+	default:
+		// 参数已经由当前 shell 展开，剩余的 $ 都是字面值
+		words := make([]string, len(args))
+		for i, arg := range args {
+			words[i] = strings.ReplaceAll(arg, "$", "$$")
+		}
+		if runArgs, ok := trimDockerRun(words); ok {
+			words = runArgs
+		}
+		commands = [][]string{words}
+	}
+	if len(commands) == 0 {
+		return nil, nil, fmt.Errorf(i18n.T("convert.no_command"))
+	}
+	return commands, connects, nil
+}
+
+// 将多个 docker run 命令转换为一个 compose 文件，返回 YAML 与无法转换的参数的警告
+func getRunComposeYaml(commands [][]string, connects [][]string, project string) (string, []string, error) {
+	compose := convert.DockerCompose{
+		Name:     project,
+		Services: make(map[string]convert.Service),
+	}
+
+	var parsedCommands []*parsedRunCommand
+	var specs []*convert.ContainerSpec
+	for i, args := range commands {
+		parsed, err := parseDockerRun(args)
+		if err != nil {
+			if len(commands) > 1 {
+				return "", nil, fmt.Errorf(i18n.T("convert.invalid_command", i+1, err))
+			}
+			return "", nil, err
+		}
+		parsedCommands = append(parsedCommands, parsed)
+		specs = append(specs, parsed.Spec)
+	}
+	warnings, err := applyNetworkConnects(specs, connects)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, parsed := range parsedCommands {
+		// service 名称冲突时追加序号
+		name := parsed.Name
+		for n := 2; ; n++ {
+			if _, exists := compose.Services[name]; !exists {
+				break
+			}
+			name = fmt.Sprintf("%s-%d", parsed.Name, n)
+		}
+		// --env-file 保留为 env_file 引用，不读取文件内容
+		service, networks, volumes := convert.ComposeService(parsed.Spec, "", nil)
+		service.EnvFile = parsed.EnvFiles
+		compose.Services[name] = service
+		for _, warning := range append(parsed.Warnings, convert.ComposeWarnings(parsed.Spec)...) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, warning))
+		}
+
+		// 命令中使用的网络需要事先创建（external），命名卷由 compose 创建
+		for key, network := range networks {
+			if compose.Networks == nil {
+				compose.Networks = make(map[string]convert.ComposeResource)
+			}
+			compose.Networks[key] = network
+		}
+		for key, vol := range volumes {
+			if compose.Volumes == nil {
+				compose.Volumes = make(map[string]convert.ComposeResource)
+			}
+			compose.Volumes[key] = vol
+		}
+	}
+
+	// --link、--volumes-from 与 container:x 引用的容器也在本次转换范围内时改为引用 service
+	containers := make(map[string]string)
+	for name, service := range compose.Services {
+		if service.ContainerName != "" {
+			containers[service.ContainerName] = name
+		}
+	}
+	convert.ResolveComposeReferences(&compose, func(container string) string { return containers[container] })
+
+	yamlData, err := yaml.Marshal(&compose)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	if err := convert.ValidateCompose(yamlData); err != nil {
+		return "", nil, fmt.Errorf("generated compose file is invalid: %v", err)
+	}
+	return string(yamlData), warnings, nil
+}
@@ -0,0 +1,1227 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/helson-lin/doke/pkg/convert"
+	"gopkg.in/yaml.v3"
+)
+
+// compose 默认查找的文件名
+var composeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// 解析并完成变量插值后的 compose 项目
+type composeProject struct {
+	Name     string
+	Dir      string
+	Services map[string]map[string]interface{}
+	Networks map[string]map[string]interface{}
+	Volumes  map[string]map[string]interface{}
+	Env      map[string]string
+	// 插值时未设置的变量等，与 compose 一样只警告不报错
+	Warnings []string
+}
+
+// 查找 compose 文件：未指定时在当前目录按 compose 的默认文件名查找
+func findComposeFile(file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	for _, name := range composeFileNames {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no compose file found, tried %s", strings.Join(composeFileNames, ", "))
+}
+
+// 读取 compose 文件，变量来自 .env 文件与当前环境，当前环境优先
+func loadComposeProject(file string, envFile string, project string) (*composeProject, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	if envFile == "" {
+		envFile = filepath.Join(dir, ".env")
+	}
+	if envData, err := os.ReadFile(envFile); err == nil {
+		for key, value := range parseDotEnv(string(envData)) {
+			env[key] = value
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %v", envFile, err)
+	}
+	for _, item := range os.Environ() {
+		if key, value, ok := strings.Cut(item, "="); ok {
+			env[key] = value
+		}
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	var warnings []string
+	interpolated, err := interpolateComposeValue(document, env, &warnings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpolate %s: %v", file, err)
+	}
+	document, _ = interpolated.(map[string]interface{})
+	// map 的遍历顺序不固定，警告排序后输出
+	slices.Sort(warnings)
+
+	result := &composeProject{
+		Dir:      dir,
+		Env:      env,
+		Warnings: warnings,
+		Services: composeSection(document["services"]),
+		Networks: composeSection(document["networks"]),
+		Volumes:  composeSection(document["volumes"]),
+	}
+	// 项目名称优先级：命令行、COMPOSE_PROJECT_NAME、文件中的 name、所在目录名
+	result.Name = firstNonEmpty(project, env["COMPOSE_PROJECT_NAME"], fmt.Sprint(valueOr(document["name"], "")), filepath.Base(dir))
+	result.Name = strings.Trim(composeServiceInvalid.ReplaceAllString(strings.ToLower(result.Name), ""), "-_")
+	if len(result.Services) == 0 {
+		return nil, fmt.Errorf("%s has no services", file)
+	}
+	return result, nil
+}
+
+func valueOr(value interface{}, fallback interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	return value
+}
+
+// 顶层 services、networks、volumes 转换为 map，值为空的条目使用空 map
+func composeSection(value interface{}) map[string]map[string]interface{} {
+	result := make(map[string]map[string]interface{})
+	section, _ := value.(map[string]interface{})
+	for key, item := range section {
+		entry, _ := item.(map[string]interface{})
+		if entry == nil {
+			entry = make(map[string]interface{})
+		}
+		result[key] = entry
+	}
+	return result
+}
+
+// 解析 .env 文件，支持注释、export 前缀与引号
+func parseDotEnv(data string) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			// 未加引号的值中 # 之后为注释
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// compose 变量插值：$VAR、${VAR}、${VAR:-default}、${VAR-default}、${VAR:?error}、${VAR:+replacement}，$$ 表示字面的 $
+// 默认值中可以嵌套变量，例如 ${TAG:-${FALLBACK:-latest}}，因此按括号匹配扫描而不是使用正则
+// 没有默认值且未设置的变量替换为空字符串，并在 warnings 中记录一次
+func interpolateCompose(value string, env map[string]string, warnings *[]string) (string, error) {
+	var builder strings.Builder
+	for i := 0; i < len(value); {
+		if value[i] != '$' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			i++
+			continue
+		}
+		switch next := value[i+1]; {
+		case next == '$':
+			builder.WriteByte('$')
+			i += 2
+		case next == '{':
+			end := matchingBrace(value, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("invalid interpolation format for %q: missing }", value)
+			}
+			expanded, err := expandComposeVariable(value[i+2:end], env, warnings)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(expanded)
+			i = end + 1
+		case isVariableStart(next):
+			end := i + 2
+			for end < len(value) && (isVariableStart(value[end]) || value[end] >= '0' && value[end] <= '9') {
+				end++
+			}
+			builder.WriteString(lookupComposeVariable(value[i+1:end], env, warnings))
+			i = end
+		default:
+			builder.WriteByte('$')
+			i++
+		}
+	}
+	return builder.String(), nil
+}
+
+func isVariableStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// 返回与 open 位置的 { 匹配的 } 的位置，嵌套的括号一起计数，找不到时返回 -1
+func matchingBrace(value string, open int) int {
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// 展开 ${...} 中的表达式，默认值只在用到时才插值
+func expandComposeVariable(expression string, env map[string]string, warnings *[]string) (string, error) {
+	name := expression
+	operator, argument := "", ""
+	if i := strings.IndexAny(expression, ":-?+"); i >= 0 {
+		name = expression[:i]
+		operator = expression[i:]
+		for _, candidate := range []string{":-", ":?", ":+", "-", "?", "+"} {
+			if rest, ok := strings.CutPrefix(operator, candidate); ok {
+				operator, argument = candidate, rest
+				break
+			}
+		}
+	}
+	current, set := env[name]
+	switch operator {
+	case ":-":
+		if current == "" {
+			return interpolateCompose(argument, env, warnings)
+		}
+	case "-":
+		if !set {
+			return interpolateCompose(argument, env, warnings)
+		}
+	case ":?", "?":
+		if operator == ":?" && current == "" || operator == "?" && !set {
+			message, err := interpolateCompose(argument, env, warnings)
+			if err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("required variable %s is missing a value: %s", name, message)
+		}
+	case ":+":
+		if current != "" {
+			return interpolateCompose(argument, env, warnings)
+		}
+		return "", nil
+	case "+":
+		if set {
+			return interpolateCompose(argument, env, warnings)
+		}
+		return "", nil
+	case "":
+		return lookupComposeVariable(name, env, warnings), nil
+	}
+	return current, nil
+}
+
+// 读取变量的值，未设置时与 compose 一样警告并使用空字符串
+func lookupComposeVariable(name string, env map[string]string, warnings *[]string) string {
+	value, ok := env[name]
+	if warning := fmt.Sprintf("variable %s is not set, defaulting to a blank string", name); !ok && !slices.Contains(*warnings, warning) {
+		*warnings = append(*warnings, warning)
+	}
+	return value
+}
+
+// 递归对 compose 文件中的字符串进行插值，map 的 key 不参与插值
+func interpolateComposeValue(value interface{}, env map[string]string, warnings *[]string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return interpolateCompose(v, env, warnings)
+	case map[string]interface{}:
+		for key, item := range v {
+			interpolated, err := interpolateComposeValue(item, env, warnings)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = interpolated
+		}
+	case []interface{}:
+		for i, item := range v {
+			interpolated, err := interpolateComposeValue(item, env, warnings)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = interpolated
+		}
+	}
+	return value, nil
+}
+
+// 启用的 profile：命令行参数与 COMPOSE_PROFILES 环境变量
+func activeComposeProfiles(project *composeProject, profiles []string) []string {
+	active := append([]string{}, profiles...)
+	if value := project.Env["COMPOSE_PROFILES"]; value != "" {
+		active = append(active, strings.Split(value, ",")...)
+	}
+	return active
+}
+
+// 按 depends_on 排序需要启动的 service，指定 service 时同时包含其依赖
+func orderComposeServices(project *composeProject, selected []string, profiles []string) ([]string, error) {
+	enabled := func(name string) bool {
+		serviceProfiles := composeList(project.Services[name]["profiles"])
+		if len(serviceProfiles) == 0 || slices.Contains(profiles, "*") {
+			return true
+		}
+		for _, profile := range serviceProfiles {
+			if slices.Contains(profiles, profile) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var roots []string
+	if len(selected) > 0 {
+		for _, name := range selected {
+			if _, ok := project.Services[name]; !ok {
+				return nil, fmt.Errorf("no such service: %s", name)
+			}
+			roots = append(roots, name)
+		}
+	} else {
+		for name := range project.Services {
+			if enabled(name) {
+				roots = append(roots, name)
+			}
+		}
+		sort.Strings(roots)
+	}
+
+	// 深度优先遍历，依赖排在被依赖的 service 之前
+	var order []string
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		if _, ok := project.Services[name]; !ok {
+			return fmt.Errorf("service %s depends on undefined service %s", path[len(path)-1], name)
+		}
+		state[name] = 1
+		for _, dependency := range composeDependencies(project.Services[name]) {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range roots {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// depends_on 支持列表与 map 两种写法，network_mode 与 volumes_from 引用的 service 也需要先启动
+func composeDependencies(service map[string]interface{}) []string {
+	var dependencies []string
+	switch v := service["depends_on"].(type) {
+	case []interface{}:
+		for _, item := range v {
+			dependencies = append(dependencies, fmt.Sprint(item))
+		}
+	case map[string]interface{}:
+		for name := range v {
+			dependencies = append(dependencies, name)
+		}
+	}
+	if target, ok := strings.CutPrefix(composeString(service["network_mode"]), "service:"); ok {
+		dependencies = append(dependencies, target)
+	}
+	for _, from := range composeList(service["volumes_from"]) {
+		if !strings.HasPrefix(from, "container:") {
+			name, _, _ := strings.Cut(strings.TrimPrefix(from, "service:"), ":")
+			dependencies = append(dependencies, name)
+		}
+	}
+	sort.Strings(dependencies)
+	return slices.Compact(dependencies)
+}
+
+// 字符串或列表统一转换为列表，与 composeStringList 不同，字符串不会按空白拆分
+func composeList(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, composeString(item))
+		}
+		return result
+	}
+	return []string{composeString(value)}
+}
+
+func composeString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// 网络与卷的实际名称：显式的 name 优先，外部资源使用 key，其余加上项目名称前缀
+func composeResourceName(project *composeProject, key string, entry map[string]interface{}) string {
+	if name := composeString(entry["name"]); name != "" {
+		return name
+	}
+	if external, ok := entry["external"].(map[string]interface{}); ok && external["name"] != nil {
+		return composeString(external["name"])
+	}
+	if isComposeExternal(entry) {
+		return key
+	}
+	return project.Name + "_" + key
+}
+
+func isComposeExternal(entry map[string]interface{}) bool {
+	switch v := entry["external"].(type) {
+	case bool:
+		return v
+	case map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// compose 创建的容器名称
+func composeContainerName(project *composeProject, service string) string {
+	if name := composeString(project.Services[service]["container_name"]); name != "" {
+		return name
+	}
+	return fmt.Sprintf("%s-%s-1", project.Name, service)
+}
+
+// service 连接的网络及其配置，没有声明网络时使用项目的 default 网络
+func composeServiceNetworks(service map[string]interface{}) ([]string, map[string]map[string]interface{}) {
+	config := make(map[string]map[string]interface{})
+	switch v := service["networks"].(type) {
+	case []interface{}:
+		for _, item := range v {
+			config[composeString(item)] = nil
+		}
+	case map[string]interface{}:
+		for name, item := range v {
+			entry, _ := item.(map[string]interface{})
+			config[name] = entry
+		}
+	default:
+		config["default"] = nil
+	}
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	// 按 priority 从高到低排列，相同时按名称排序
+	sort.SliceStable(names, func(i, j int) bool {
+		pi, _ := strconv.Atoi(composeString(config[names[i]]["priority"]))
+		pj, _ := strconv.Atoi(composeString(config[names[j]]["priority"]))
+		if pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+	return names, config
+}
+
+// compose 中可以直接映射为 docker run 参数的 key
+var composeRunFlags = map[string]struct {
+	Group int
+	Flag  string
+}{
+	"hostname":        {convert.GroupGeneral, "--hostname"},
+	"domainname":      {convert.GroupGeneral, "--domainname"},
+	"user":            {convert.GroupGeneral, "--user"},
+	"working_dir":     {convert.GroupGeneral, "--workdir"},
+	"restart":         {convert.GroupGeneral, "--restart"},
+	"platform":        {convert.GroupGeneral, "--platform"},
+	"pull_policy":     {convert.GroupGeneral, "--pull"},
+	"stop_signal":     {convert.GroupGeneral, "--stop-signal"},
+	"mac_address":     {convert.GroupNetwork, "--mac-address"},
+	"expose":          {convert.GroupPorts, "--expose"},
+	"cap_add":         {convert.GroupSecurity, "--cap-add"},
+	"cap_drop":        {convert.GroupSecurity, "--cap-drop"},
+	"security_opt":    {convert.GroupSecurity, "--security-opt"},
+	"devices":         {convert.GroupDevices, "--device"},
+	"group_add":       {convert.GroupGeneral, "--group-add"},
+	"dns":             {convert.GroupNetwork, "--dns"},
+	"dns_search":      {convert.GroupNetwork, "--dns-search"},
+	"dns_opt":         {convert.GroupNetwork, "--dns-option"},
+	"external_links":  {convert.GroupNetwork, "--link"},
+	"cpus":            {convert.GroupResources, "--cpus"},
+	"cpu_shares":      {convert.GroupResources, "--cpu-shares"},
+	"cpuset":          {convert.GroupResources, "--cpuset-cpus"},
+	"cpu_period":      {convert.GroupResources, "--cpu-period"},
+	"cpu_quota":       {convert.GroupResources, "--cpu-quota"},
+	"mem_limit":       {convert.GroupResources, "--memory"},
+	"mem_reservation": {convert.GroupResources, "--memory-reservation"},
+	"memswap_limit":   {convert.GroupResources, "--memory-swap"},
+	"shm_size":        {convert.GroupResources, "--shm-size"},
+	"pids_limit":      {convert.GroupResources, "--pids-limit"},
+	"oom_score_adj":   {convert.GroupResources, "--oom-score-adj"},
+	"ipc":             {convert.GroupNamespaces, "--ipc"},
+	"pid":             {convert.GroupNamespaces, "--pid"},
+	"uts":             {convert.GroupNamespaces, "--uts"},
+	"userns_mode":     {convert.GroupNamespaces, "--userns"},
+	"runtime":         {convert.GroupNamespaces, "--runtime"},
+	"cgroup_parent":   {convert.GroupNamespaces, "--cgroup-parent"},
+	"cgroup":          {convert.GroupNamespaces, "--cgroupns"},
+	"tmpfs":           {convert.GroupVolumes, "--tmpfs"},
+}
+
+// compose 中的布尔参数
+var composeRunSwitches = map[string]string{
+	"privileged":       "--privileged",
+	"read_only":        "--read-only",
+	"init":             "--init",
+	"stdin_open":       "-i",
+	"tty":              "-t",
+	"oom_kill_disable": "--oom-kill-disable",
+}
+
+// 在 docker run 之外单独处理的 key
+var composeHandledKeys = []string{
+	"image", "build", "container_name", "command", "entrypoint", "environment", "env_file",
+	"labels", "ports", "volumes", "volumes_from", "network_mode", "networks", "links",
+	"extra_hosts", "ulimits", "sysctls", "storage_opt", "annotations", "blkio_config", "logging", "healthcheck", "stop_grace_period",
+	"depends_on", "profiles", "deploy", "scale",
+}
+
+// 生成重建 compose 项目所需的 docker 命令：先创建网络与卷，再按依赖顺序启动容器
+func getComposeRunCommands(project *composeProject, order []string, shell string, multiline bool) (string, []string, error) {
+	var lines []string
+	var warnings []string
+	format := convert.Format{Shell: shell, Multiline: multiline}
+
+	// 只创建被用到的网络与卷
+	usedNetworks := make(map[string]bool)
+	usedVolumes := make(map[string]bool)
+	for _, name := range order {
+		service := project.Services[name]
+		if service["network_mode"] == nil {
+			networks, _ := composeServiceNetworks(service)
+			for _, network := range networks {
+				usedNetworks[network] = true
+			}
+		}
+		for _, volume := range composeVolumeMounts(service) {
+			if source := composeString(volume["source"]); composeString(volume["type"]) == "volume" && source != "" {
+				usedVolumes[source] = true
+			}
+		}
+	}
+
+	networkKeys := sortedKeys(usedNetworks)
+	for _, key := range networkKeys {
+		entry, declared := project.Networks[key]
+		if !declared && key != "default" {
+			return "", nil, fmt.Errorf("service refers to undefined network %s", key)
+		}
+		if entry == nil {
+			entry = make(map[string]interface{})
+		}
+		if isComposeExternal(entry) {
+			continue
+		}
+		args := []string{"docker", "network", "create"}
+		if driver := composeString(entry["driver"]); driver != "" {
+			args = append(args, "--driver", driver)
+		}
+		args = append(args, composeMappingFlags("--opt", entry["driver_opts"])...)
+		if internal, _ := entry["internal"].(bool); internal {
+			args = append(args, "--internal")
+		}
+		if attachable, _ := entry["attachable"].(bool); attachable {
+			args = append(args, "--attachable")
+		}
+		if ipv6, _ := entry["enable_ipv6"].(bool); ipv6 {
+			args = append(args, "--ipv6")
+		}
+		if ipam, ok := entry["ipam"].(map[string]interface{}); ok {
+			if configs, ok := ipam["config"].([]interface{}); ok {
+				for _, item := range configs {
+					config, _ := item.(map[string]interface{})
+					for _, field := range []string{"subnet", "gateway", "ip_range"} {
+						if value := composeString(config[field]); value != "" {
+							args = append(args, "--"+strings.ReplaceAll(field, "_", "-"), value)
+						}
+					}
+				}
+			}
+		}
+		args = append(args, composeMappingFlags("--label", entry["labels"])...)
+		args = append(args, composeResourceName(project, key, entry))
+		lines = append(lines, convert.FormatShellCommand(shell, args...))
+	}
+
+	for _, key := range sortedKeys(usedVolumes) {
+		entry, declared := project.Volumes[key]
+		if !declared {
+			return "", nil, fmt.Errorf("service refers to undefined volume %s", key)
+		}
+		if isComposeExternal(entry) {
+			continue
+		}
+		args := []string{"docker", "volume", "create"}
+		if driver := composeString(entry["driver"]); driver != "" {
+			args = append(args, "--driver", driver)
+		}
+		args = append(args, composeMappingFlags("--opt", entry["driver_opts"])...)
+		args = append(args, composeMappingFlags("--label", entry["labels"])...)
+		args = append(args, composeResourceName(project, key, entry))
+		lines = append(lines, convert.FormatShellCommand(shell, args...))
+	}
+
+	for _, name := range order {
+		cmd, connects, serviceWarnings, err := getComposeServiceRun(project, name)
+		if err != nil {
+			return "", nil, fmt.Errorf("service %s: %v", name, err)
+		}
+		for _, warning := range serviceWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, warning))
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "# "+name)
+		lines = append(lines, cmd.Format(format))
+		for _, connect := range connects {
+			lines = append(lines, convert.FormatShellCommand(shell, connect...))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n", warnings, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// map 或 KEY=VALUE 列表转换为重复的参数，例如 --label a=b
+func composeMappingFlags(flag string, value interface{}) []string {
+	mapping := composeMapping(value)
+	var args []string
+	for _, key := range sortedKeys(mapping) {
+		args = append(args, flag, key+"="+mapping[key])
+	}
+	return args
+}
+
+// 将单个 service 转换为 docker run 命令，额外的网络通过 docker network connect 连接
+func getComposeServiceRun(project *composeProject, name string) (*convert.Command, [][]string, []string, error) {
+	service := project.Services[name]
+	containerName := composeContainerName(project, name)
+	cmd := &convert.Command{}
+	var connects [][]string
+	var warnings []string
+
+	for _, key := range sortedKeys(service) {
+		if _, ok := composeRunFlags[key]; ok {
+			continue
+		}
+		if _, ok := composeRunSwitches[key]; ok {
+			continue
+		}
+		if !slices.Contains(composeHandledKeys, key) && !strings.HasPrefix(key, "x-") {
+			warnings = append(warnings, fmt.Sprintf("%s is not supported", key))
+		}
+	}
+
+	cmd.Add(convert.GroupGeneral, "--name", containerName)
+	cmd.Add(convert.GroupGeneral, "-d")
+
+	cmd.Image = composeString(service["image"])
+	if cmd.Image == "" {
+		if service["build"] == nil {
+			return nil, nil, nil, fmt.Errorf("image or build is required")
+		}
+		// compose 构建的镜像默认命名为 项目名-service名
+		cmd.Image = project.Name + "-" + name
+		warnings = append(warnings, fmt.Sprintf("build is not supported, build the image as %s first", cmd.Image))
+	}
+
+	for _, key := range sortedKeys(composeRunSwitches) {
+		if enabled, _ := service[key].(bool); enabled {
+			group := convert.GroupGeneral
+			if key == "privileged" || key == "read_only" || key == "init" {
+				group = convert.GroupSecurity
+			}
+			cmd.Add(group, composeRunSwitches[key])
+		}
+	}
+	for _, key := range sortedKeys(composeRunFlags) {
+		flag := composeRunFlags[key]
+		for _, value := range composeList(service[key]) {
+			if value != "" {
+				cmd.Add(flag.Group, flag.Flag, value)
+			}
+		}
+	}
+
+	// deploy.resources 中的限制
+	if deploy, ok := service["deploy"].(map[string]interface{}); ok {
+		if replicas, err := strconv.Atoi(composeString(deploy["replicas"])); err == nil && replicas > 1 {
+			warnings = append(warnings, fmt.Sprintf("replicas %d are not supported, only one container is started", replicas))
+		}
+		resources, _ := deploy["resources"].(map[string]interface{})
+		limits, _ := resources["limits"].(map[string]interface{})
+		reservations, _ := resources["reservations"].(map[string]interface{})
+		if value := composeString(limits["cpus"]); value != "" && service["cpus"] == nil {
+			cmd.Add(convert.GroupResources, "--cpus", value)
+		}
+		if value := composeString(limits["memory"]); value != "" && service["mem_limit"] == nil {
+			cmd.Add(convert.GroupResources, "--memory", value)
+		}
+		if value := composeString(limits["pids"]); value != "" && service["pids_limit"] == nil {
+			cmd.Add(convert.GroupResources, "--pids-limit", value)
+		}
+		if value := composeString(reservations["memory"]); value != "" && service["mem_reservation"] == nil {
+			cmd.Add(convert.GroupResources, "--memory-reservation", value)
+		}
+		for _, item := range composeListItems(reservations["devices"]) {
+			device, _ := item.(map[string]interface{})
+			request := convert.DeviceRequestSpec{
+				Driver:       composeString(device["driver"]),
+				DeviceIDs:    composeList(device["device_ids"]),
+				Capabilities: composeList(device["capabilities"]),
+			}
+			// 没有指定 count 与 device_ids 时 compose 使用全部设备
+			if count := composeString(device["count"]); count == "all" || (count == "" && len(request.DeviceIDs) == 0) {
+				request.Count = -1
+			} else {
+				request.Count, _ = strconv.Atoi(count)
+			}
+			if options := composeMapping(device["options"]); len(options) > 0 {
+				request.Options = options
+			}
+			value, ok := convert.GpusValue(request)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("device reservation with capabilities %s is not supported", strings.Join(request.Capabilities, ",")))
+				continue
+			}
+			cmd.Add(convert.GroupDevices, "--gpus", value)
+		}
+	}
+
+	// 环境变量：env_file 先生效，environment 覆盖
+	for _, item := range composeEnvFiles(service["env_file"]) {
+		path := item
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(project.Dir, path)
+		}
+		cmd.Add(convert.GroupEnv, "--env-file", path)
+	}
+	switch env := service["environment"].(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(env) {
+			if env[key] == nil {
+				// 没有值时由 docker run 从当前环境读取
+				cmd.Add(convert.GroupEnv, "-e", key)
+				continue
+			}
+			cmd.Add(convert.GroupEnv, "-e", key+"="+composeString(env[key]))
+		}
+	case []interface{}:
+		for _, item := range env {
+			cmd.Add(convert.GroupEnv, "-e", composeString(item))
+		}
+	}
+
+	// 标签，附加 compose 的项目标签，方便 doke command -j 再导出
+	labels := composeMapping(service["labels"])
+	labels[convert.ComposeProjectLabel] = project.Name
+	labels[convert.ComposeServiceLabel] = name
+	for _, key := range sortedKeys(labels) {
+		cmd.Add(convert.GroupLabels, "--label", key+"="+labels[key])
+	}
+
+	// 端口
+	for _, port := range composeListItems(service["ports"]) {
+		published, err := composePortFlag(port)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		cmd.Add(convert.GroupPorts, "-p", published)
+	}
+
+	// 挂载
+	for _, volume := range composeVolumeMounts(service) {
+		mount, err := composeMountFlag(project, volume)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		cmd.Add(convert.GroupVolumes, "--mount", mount)
+	}
+	for _, from := range composeList(service["volumes_from"]) {
+		if target, ok := strings.CutPrefix(from, "container:"); ok {
+			cmd.Add(convert.GroupVolumes, "--volumes-from", target)
+			continue
+		}
+		source, mode, _ := strings.Cut(strings.TrimPrefix(from, "service:"), ":")
+		from = composeContainerName(project, source)
+		if mode != "" {
+			from += ":" + mode
+		}
+		cmd.Add(convert.GroupVolumes, "--volumes-from", from)
+	}
+
+	// 网络
+	switch mode := composeString(service["network_mode"]); {
+	case strings.HasPrefix(mode, "service:"):
+		cmd.Add(convert.GroupNetwork, "--network", "container:"+composeContainerName(project, strings.TrimPrefix(mode, "service:")))
+	case mode != "":
+		cmd.Add(convert.GroupNetwork, "--network", mode)
+	default:
+		networks, config := composeServiceNetworks(service)
+		for i, network := range networks {
+			entry := project.Networks[network]
+			if entry == nil {
+				entry = make(map[string]interface{})
+			}
+			networkName := composeResourceName(project, network, entry)
+			aliases := append([]string{name}, composeList(config[network]["aliases"])...)
+			ipv4 := composeString(config[network]["ipv4_address"])
+			ipv6 := composeString(config[network]["ipv6_address"])
+			if i == 0 {
+				cmd.Add(convert.GroupNetwork, "--network", networkName)
+				for _, alias := range aliases {
+					cmd.Add(convert.GroupNetwork, "--network-alias", alias)
+				}
+				if ipv4 != "" {
+					cmd.Add(convert.GroupNetwork, "--ip", ipv4)
+				}
+				if ipv6 != "" {
+					cmd.Add(convert.GroupNetwork, "--ip6", ipv6)
+				}
+				continue
+			}
+			// docker run 只能连接一个网络，其余网络在容器创建后连接
+			connect := []string{"docker", "network", "connect"}
+			for _, alias := range aliases {
+				connect = append(connect, "--alias", alias)
+			}
+			if ipv4 != "" {
+				connect = append(connect, "--ip", ipv4)
+			}
+			if ipv6 != "" {
+				connect = append(connect, "--ip6", ipv6)
+			}
+			connects = append(connects, append(connect, networkName, containerName))
+		}
+	}
+	for _, link := range composeList(service["links"]) {
+		target, alias, _ := strings.Cut(link, ":")
+		if alias == "" {
+			alias = target
+		}
+		cmd.Add(convert.GroupNetwork, "--link", composeContainerName(project, target)+":"+alias)
+	}
+	switch hosts := service["extra_hosts"].(type) {
+	case map[string]interface{}:
+		for _, host := range sortedKeys(hosts) {
+			cmd.Add(convert.GroupNetwork, "--add-host", host+":"+composeString(hosts[host]))
+		}
+	default:
+		for _, host := range composeList(hosts) {
+			// compose 也支持 host=ip 的写法
+			cmd.Add(convert.GroupNetwork, "--add-host", strings.Replace(host, "=", ":", 1))
+		}
+	}
+
+	// ulimits 与 sysctls
+	if ulimits, ok := service["ulimits"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(ulimits) {
+			switch limit := ulimits[key].(type) {
+			case map[string]interface{}:
+				cmd.Add(convert.GroupSecurity, "--ulimit", fmt.Sprintf("%s=%s:%s", key, composeString(limit["soft"]), composeString(limit["hard"])))
+			default:
+				cmd.Add(convert.GroupSecurity, "--ulimit", fmt.Sprintf("%s=%s", key, composeString(limit)))
+			}
+		}
+	}
+	sysctls := composeMapping(service["sysctls"])
+	for _, key := range sortedKeys(sysctls) {
+		cmd.Add(convert.GroupSecurity, "--sysctl", key+"="+sysctls[key])
+	}
+	storageOpt := composeMapping(service["storage_opt"])
+	for _, key := range sortedKeys(storageOpt) {
+		cmd.Add(convert.GroupResources, "--storage-opt", key+"="+storageOpt[key])
+	}
+	annotations := composeMapping(service["annotations"])
+	for _, key := range sortedKeys(annotations) {
+		cmd.Add(convert.GroupLabels, "--annotation", key+"="+annotations[key])
+	}
+
+	// 块设备 IO
+	if blkio, ok := service["blkio_config"].(map[string]interface{}); ok {
+		if weight := composeString(blkio["weight"]); weight != "" {
+			cmd.Add(convert.GroupResources, "--blkio-weight", weight)
+		}
+		for _, limits := range []struct {
+			field string
+			flag  string
+			value string
+		}{
+			{"weight_device", "--blkio-weight-device", "weight"},
+			{"device_read_bps", "--device-read-bps", "rate"},
+			{"device_write_bps", "--device-write-bps", "rate"},
+			{"device_read_iops", "--device-read-iops", "rate"},
+			{"device_write_iops", "--device-write-iops", "rate"},
+		} {
+			for _, item := range composeListItems(blkio[limits.field]) {
+				device, _ := item.(map[string]interface{})
+				cmd.Add(convert.GroupResources, limits.flag, composeString(device["path"])+":"+composeString(device[limits.value]))
+			}
+		}
+	}
+
+	// 日志
+	if logging, ok := service["logging"].(map[string]interface{}); ok {
+		if driver := composeString(logging["driver"]); driver != "" {
+			cmd.Add(convert.GroupLogging, "--log-driver", driver)
+		}
+		options := composeMapping(logging["options"])
+		for _, key := range sortedKeys(options) {
+			cmd.Add(convert.GroupLogging, "--log-opt", key+"="+options[key])
+		}
+	}
+
+	if value := composeString(service["stop_grace_period"]); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid stop_grace_period %q", value)
+		}
+		cmd.Add(convert.GroupGeneral, fmt.Sprintf("--stop-timeout=%d", int(duration.Seconds())))
+	}
+
+	// 健康检查
+	if health, ok := service["healthcheck"].(map[string]interface{}); ok {
+		test := composeList(health["test"])
+		_, isString := health["test"].(string)
+		switch {
+		case health["disable"] == true || (len(test) > 0 && test[0] == "NONE"):
+			cmd.Add(convert.GroupHealth, "--no-healthcheck")
+		case isString:
+			cmd.Add(convert.GroupHealth, "--health-cmd", test[0])
+		case len(test) > 1 && test[0] == "CMD-SHELL":
+			cmd.Add(convert.GroupHealth, "--health-cmd", strings.Join(test[1:], " "))
+		case len(test) > 1 && test[0] == "CMD":
+			words := make([]string, len(test)-1)
+			for i, word := range test[1:] {
+				words[i] = convert.QuotePosix(word)
+			}
+			cmd.Add(convert.GroupHealth, "--health-cmd", strings.Join(words, " "))
+		}
+		for _, field := range []string{"interval", "timeout", "start_period", "start_interval", "retries"} {
+			if value := composeString(health[field]); value != "" {
+				cmd.Add(convert.GroupHealth, fmt.Sprintf("--health-%s=%s", strings.ReplaceAll(field, "_", "-"), value))
+			}
+		}
+	}
+
+	// entrypoint 与 command：字符串按 shell 规则拆分
+	entrypoint, err := composeCommandWords(service["entrypoint"])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid entrypoint: %v", err)
+	}
+	command, err := composeCommandWords(service["command"])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid command: %v", err)
+	}
+	if len(entrypoint) > 0 {
+		cmd.Add(convert.GroupGeneral, "--entrypoint", entrypoint[0])
+		cmd.Args = append(cmd.Args, entrypoint[1:]...)
+	} else if service["entrypoint"] != nil {
+		cmd.Add(convert.GroupGeneral, "--entrypoint", "")
+	}
+	cmd.Args = append(cmd.Args, command...)
+
+	return cmd, connects, warnings, nil
+}
+
+// env_file 支持字符串、列表以及 {path, required} 对象，required: false 的文件不存在时跳过
+func composeEnvFiles(value interface{}) []string {
+	var files []string
+	for _, item := range composeListItems(value) {
+		switch v := item.(type) {
+		case map[string]interface{}:
+			path := composeString(v["path"])
+			if required, ok := v["required"].(bool); ok && !required {
+				if _, err := os.Stat(path); err != nil {
+					continue
+				}
+			}
+			files = append(files, path)
+		default:
+			files = append(files, composeString(v))
+		}
+	}
+	return files
+}
+
+// 单个值或列表统一转换为列表，保留列表项的原始类型
+func composeListItems(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	}
+	return []interface{}{value}
+}
+
+// 端口的短格式直接使用，长格式转换为 [host_ip:]published:target/protocol
+func composePortFlag(port interface{}) (string, error) {
+	entry, ok := port.(map[string]interface{})
+	if !ok {
+		return composeString(port), nil
+	}
+	target := composeString(entry["target"])
+	if target == "" {
+		return "", fmt.Errorf("port %v has no target", port)
+	}
+	published := target
+	if value := composeString(entry["published"]); value != "" {
+		published = value + ":" + target
+	}
+	if hostIP := composeString(entry["host_ip"]); hostIP != "" {
+		if strings.Contains(hostIP, ":") {
+			hostIP = "[" + hostIP + "]"
+		}
+		if !strings.Contains(published, ":") {
+			published = ":" + published
+		}
+		published = hostIP + ":" + published
+	}
+	if protocol := composeString(entry["protocol"]); protocol != "" && protocol != "tcp" {
+		published += "/" + protocol
+	}
+	return published, nil
+}
+
+// service 的挂载统一转换为长格式，source 为卷的 key 或宿主机路径
+func composeVolumeMounts(service map[string]interface{}) []map[string]interface{} {
+	var mounts []map[string]interface{}
+	for _, item := range composeListItems(service["volumes"]) {
+		if entry, ok := item.(map[string]interface{}); ok {
+			mounts = append(mounts, entry)
+			continue
+		}
+		mounts = append(mounts, parseComposeShortVolume(composeString(item)))
+	}
+	return mounts
+}
+
+// 解析 source:target:mode 格式，只有一段时为匿名卷
+func parseComposeShortVolume(volume string) map[string]interface{} {
+	parts := strings.Split(volume, ":")
+	// Windows 路径中的盘符也包含冒号，例如 C:\data:/data
+	if len(parts) > 2 && len(parts[0]) == 1 && strings.HasPrefix(parts[1], `\`) {
+		parts = append([]string{parts[0] + ":" + parts[1]}, parts[2:]...)
+	}
+	mount := map[string]interface{}{"type": "volume"}
+	switch len(parts) {
+	case 1:
+		mount["target"] = parts[0]
+		return mount
+	default:
+		mount["source"] = parts[0]
+		mount["target"] = parts[1]
+		if len(parts) > 2 {
+			for _, option := range strings.Split(parts[2], ",") {
+				if option == "ro" {
+					mount["read_only"] = true
+				}
+			}
+		}
+	}
+	source := parts[0]
+	if strings.HasPrefix(source, ".") || strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~") || strings.Contains(source, `\`) {
+		mount["type"] = "bind"
+	}
+	return mount
+}
+
+// 长格式的挂载转换为 --mount 参数，相对路径相对于 compose 文件所在目录
+func composeMountFlag(project *composeProject, volume map[string]interface{}) (string, error) {
+	mountType := composeString(volume["type"])
+	source := composeString(volume["source"])
+	target := composeString(volume["target"])
+	if target == "" {
+		return "", fmt.Errorf("volume %v has no target", volume)
+	}
+
+	fields := []string{"type=" + mountType}
+	switch mountType {
+	case "bind":
+		if strings.HasPrefix(source, "~") {
+			if home, err := os.UserHomeDir(); err == nil {
+				source = filepath.Join(home, strings.TrimPrefix(source, "~"))
+			}
+		}
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(project.Dir, source)
+		}
+		fields = append(fields, "source="+source)
+	case "volume":
+		if source != "" {
+			entry, declared := project.Volumes[source]
+			if !declared {
+				return "", fmt.Errorf("service refers to undefined volume %s", source)
+			}
+			fields = append(fields, "source="+composeResourceName(project, source, entry))
+		}
+	case "tmpfs":
+		if tmpfs, ok := volume["tmpfs"].(map[string]interface{}); ok {
+			if size := composeString(tmpfs["size"]); size != "" {
+				fields = append(fields, "tmpfs-size="+size)
+			}
+		}
+	default:
+		if source != "" {
+			fields = append(fields, "source="+source)
+		}
+	}
+	fields = append(fields, "target="+target)
+	if readOnly, _ := volume["read_only"].(bool); readOnly {
+		fields = append(fields, "readonly")
+	}
+	if options, ok := volume["volume"].(map[string]interface{}); ok && options["nocopy"] == true {
+		fields = append(fields, "volume-nocopy")
+	}
+
+	// 包含逗号或引号的字段需要按 CSV 规则加引号
+	for i, field := range fields {
+		if strings.ContainsAny(field, ",\"") {
+			fields[i] = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(fields, ","), nil
+}
+
+// command 与 entrypoint：列表直接使用，字符串按 shell 规则拆分但不展开变量
+func composeCommandWords(value interface{}) ([]string, error) {
+	if list, ok := value.([]interface{}); ok {
+		words := make([]string, len(list))
+		for i, item := range list {
+			words[i] = composeString(item)
+		}
+		return words, nil
+	}
+	command := composeString(value)
+	if command == "" {
+		return nil, nil
+	}
+	return splitCommandLine(command)
+}
+
+// 按引号与反斜杠拆分命令行，不处理变量、注释与命令分隔符
+func splitCommandLine(command string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", command)
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInterpolateCompose(t *testing.T) {
+	env := map[string]string{"TAG": "1.25", "EMPTY": "", "FALLBACK": "stable"}
+	tests := []struct {
+		name     string
+		value    string
+		want     string
+		warnings []string
+		err      string
+	}{
+		{name: "braced", value: "nginx:${TAG}", want: "nginx:1.25"},
+		{name: "bare", value: "nginx:$TAG", want: "nginx:1.25"},
+		{
+			name:     "unset",
+			value:    "${MISSING}/$MISSING",
+			want:     "/",
+			warnings: []string{"variable MISSING is not set, defaulting to a blank string"},
+		},
+		{name: "set but empty", value: "[${EMPTY}]", want: "[]"},
+		{name: "default when unset", value: "${MISSING:-latest}", want: "latest"},
+		{name: "default when empty", value: "${EMPTY:-latest}", want: "latest"},
+		{name: "dash keeps empty", value: "[${EMPTY-latest}]", want: "[]"},
+		{name: "default not used", value: "${TAG:-latest}", want: "1.25"},
+		{name: "nested default", value: "${MISSING:-${FALLBACK:-latest}}", want: "stable"},
+		{name: "nested default unset", value: "${MISSING:-${OTHER:-latest}}", want: "latest"},
+		{
+			name:     "nested unset without default",
+			value:    "${MISSING:-${OTHER}}",
+			want:     "",
+			warnings: []string{"variable OTHER is not set, defaulting to a blank string"},
+		},
+		{name: "replacement", value: "${TAG:+set}${MISSING:+set}", want: "set"},
+		{name: "required set", value: "${TAG?tag is required}", want: "1.25"},
+		{name: "required unset", value: "${MISSING?tag is required}", err: "required variable MISSING is missing a value: tag is required"},
+		{name: "required empty", value: "${EMPTY:?must not be empty}", err: "required variable EMPTY is missing a value: must not be empty"},
+		{name: "escaped dollar", value: "cost $$5 $${TAG}", want: "cost $5 ${TAG}"},
+		{name: "lone dollar", value: "a $ b", want: "a $ b"},
+		{name: "missing brace", value: "${TAG", err: `invalid interpolation format for "${TAG": missing }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []string
+			got, err := interpolateCompose(tt.value, env, &warnings)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 镜像默认配置的缓存，多个容器使用同一镜像时只查询一次，可以在多个协程中使用
+type imageConfigCache struct {
+	cli     dockerClient
+	mu      sync.Mutex
+	entries map[string]func() (*container.Config, error)
+}
+
+func newImageConfigCache(cli dockerClient) *imageConfigCache {
+	return &imageConfigCache{cli: cli, entries: make(map[string]func() (*container.Config, error))}
+}
+
+// 获取镜像的默认配置
+func (c *imageConfigCache) get(imageID string) (*container.Config, error) {
+	c.mu.Lock()
+	load, ok := c.entries[imageID]
+	if !ok {
+		load = sync.OnceValues(func() (*container.Config, error) {
+			return getDockerImageConfig(c.cli, imageID)
+		})
+		c.entries[imageID] = load
+	}
+	c.mu.Unlock()
+	return load()
+}
+
+// 获取镜像的默认配置
+func getDockerImageConfig(cli dockerClient, imageID string) (*container.Config, error) {
+	imageInfo, _, err := cli.ImageInspectWithRaw(context.Background(), imageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %v", err)
+	}
+	if imageInfo.Config == nil {
+		return &container.Config{}, nil
+	}
+
+	return imageInfo.Config, nil
+}
+
+// 去掉与镜像默认值相同的配置，只保留用户实际设置的部分
+// 返回修改后的副本，原来的配置保持不变，对比 compose 文件时使用
+func stripImageDefaults(config *types.ContainerJSON, image *container.Config) *types.ContainerJSON {
+	if config.Config == nil || image == nil {
+		return config
+	}
+	stripped := *config
+	c := *config.Config
+	stripped.Config = &c
+	config = &stripped
+
+	// 环境变量：与镜像中完全相同的 KEY=VALUE 视为默认值
+	var env []string
+	for _, e := range c.Env {
+		if !slices.Contains(image.Env, e) {
+			env = append(env, e)
+		}
+	}
+	c.Env = env
+
+	// 标签：键和值都与镜像相同才去掉
+	if len(c.Labels) > 0 {
+		labels := make(map[string]string)
+		for key, value := range c.Labels {
+			if imageValue, ok := image.Labels[key]; !ok || imageValue != value {
+				labels[key] = value
+			}
+		}
+		c.Labels = labels
+	}
+
+	// 覆盖 entrypoint 时 docker 会清空镜像的 cmd，所以只有 entrypoint 未变时 cmd 才可能是默认值
+	entrypointDefault := slices.Equal(c.Entrypoint, image.Entrypoint)
+	if entrypointDefault {
+		c.Entrypoint = nil
+		if slices.Equal(c.Cmd, image.Cmd) {
+			c.Cmd = nil
+		}
+	}
+
+	if c.WorkingDir == image.WorkingDir {
+		c.WorkingDir = ""
+	}
+	if c.User == image.User {
+		c.User = ""
+	}
+	if c.StopSignal == image.StopSignal {
+		c.StopSignal = ""
+	}
+	if reflect.DeepEqual(c.Healthcheck, image.Healthcheck) {
+		c.Healthcheck = nil
+	}
+
+	// --publish-all 发布镜像暴露的端口，compose 需要逐个列出这些端口
+	if (config.HostConfig == nil || !config.HostConfig.PublishAllPorts) && len(c.ExposedPorts) > 0 {
+		exposed := make(nat.PortSet)
+		for port := range c.ExposedPorts {
+			if _, ok := image.ExposedPorts[port]; !ok {
+				exposed[port] = struct{}{}
+			}
+		}
+		c.ExposedPorts = exposed
+	}
+
+	// 镜像 VOLUME 指令生成的匿名卷
+	if len(c.Volumes) > 0 {
+		volumes := make(map[string]struct{})
+		for target := range c.Volumes {
+			if _, ok := image.Volumes[target]; !ok {
+				volumes[target] = struct{}{}
+			}
+		}
+		c.Volumes = volumes
+	}
+	var mounts []types.MountPoint
+	for _, mount := range config.Mounts {
+		if _, ok := image.Volumes[mount.Destination]; ok && mount.Type == "volume" && convert.IsAnonymousVolume(mount.Name) {
+			continue
+		}
+		mounts = append(mounts, mount)
+	}
+	config.Mounts = mounts
+	return config
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 统一 diff 的上下文行数
+const diffContext = 3
+
+// 生成两个文本的统一 diff，内容相同时返回空字符串
+func unifiedDiff(oldName string, newName string, oldText string, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// 最长公共子序列，compose 文件不大，直接使用动态规划
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 每一行的操作：' ' 不变，'-' 删除，'+' 新增
+	type diffLine struct {
+		op   byte
+		text string
+		a, b int
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(lines); {
+		// 找到下一处修改，前后保留上下文
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		end := first
+		for k := first; k < len(lines); k++ {
+			if lines[k].op != ' ' {
+				end = k
+			} else if k-end > 2*diffContext {
+				break
+			}
+		}
+		from := max(first-diffContext, start)
+		to := min(end+diffContext+1, len(lines))
+
+		oldCount, newCount := 0, 0
+		for _, line := range lines[from:to] {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&builder, "@@ -%s +%s @@\n", hunkRange(lines[from].a, oldCount), hunkRange(lines[from].b, newCount))
+		for _, line := range lines[from:to] {
+			builder.WriteByte(line.op)
+			builder.WriteString(line.text)
+			builder.WriteByte('\n')
+		}
+		start = to
+	}
+	return builder.String()
+}
+
+// hunk 的起始行与行数，行数为 0 时起始行指向前一行
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
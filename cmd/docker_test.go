@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 集成测试使用的镜像，没有时会先拉取
+const integrationImage = "busybox:1.36"
+
+// 需要可以访问的 docker 守护进程，没有 docker 命令或守护进程时跳过
+func requireDocker(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping docker integration test in short mode")
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker CLI not found")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := exec.CommandContext(ctx, "docker", "version").Run(); err != nil {
+		t.Skip("docker daemon not available")
+	}
+	if err := exec.Command("docker", "image", "inspect", integrationImage).Run(); err != nil {
+		if output, err := exec.Command("docker", "pull", integrationImage).CombinedOutput(); err != nil {
+			t.Skipf("failed to pull %s: %v\n%s", integrationImage, err, output)
+		}
+	}
+}
+
+func docker(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("docker %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// 查询容器并转换为 ContainerSpec
+func inspectSpec(t *testing.T, name string) *convert.ContainerSpec {
+	t.Helper()
+	configs, err := parseContainerConfigs([]byte(docker(t, "inspect", name)))
+	if err != nil {
+		t.Fatalf("failed to parse inspect output of %s: %v", name, err)
+	}
+	return convert.NewSpec(configs[0])
+}
+
+// 生成的 docker run 命令交给 docker 执行，新容器的配置应与原容器相同
+func TestRunAgainstDocker(t *testing.T) {
+	requireDocker(t)
+
+	const network = "doke-it-net"
+	docker(t, "network", "create", network)
+	t.Cleanup(func() { exec.Command("docker", "network", "rm", network).Run() })
+
+	// 被其他容器共享网络的容器，自定义的主机名会复制到共享它网络的容器中
+	docker(t, "run", "-d", "--name", "doke-it-app", "--hostname", "app", "--network", network, integrationImage, "sleep", "3600")
+	t.Cleanup(func() { exec.Command("docker", "rm", "-f", "doke-it-app").Run() })
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "doke-it-basic",
+			args: []string{"--hostname", "basic", "--restart", "on-failure:3", "-p", "127.0.0.1:18080:80", "-e", "GREETING=hello world", "-e", "PRICE=$5", "--label", "team=web", "--workdir", "/tmp", "--user", "1000:1000"},
+		},
+		{
+			name: "doke-it-network",
+			args: []string{"--network", network, "--network-alias", "api", "--cap-add", "NET_ADMIN", "--memory", "64m", "--pids-limit", "100", "--tmpfs", "/run:rw,size=16m"},
+		},
+		{
+			name: "doke-it-sidecar",
+			args: []string{"--network", "container:doke-it-app", "-e", "ROLE=sidecar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{"run", "-d", "--name", tt.name}, tt.args...), integrationImage, "sleep", "3600")
+			docker(t, args...)
+			t.Cleanup(func() { exec.Command("docker", "rm", "-f", tt.name).Run() })
+
+			expected := inspectSpec(t, tt.name)
+			command, err := convert.Run(expected, convert.Format{Shell: convert.ShellPosix})
+			if err != nil {
+				t.Fatal(err)
+			}
+			docker(t, "rm", "-f", tt.name)
+			if output, err := exec.Command("sh", "-c", command).CombinedOutput(); err != nil {
+				t.Fatalf("docker rejected the generated command: %v\n%s\n%s", err, command, output)
+			}
+
+			actual := inspectSpec(t, tt.name)
+			want, err := convert.SpecJSON([]*convert.ContainerSpec{expected})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := convert.SpecJSON([]*convert.ContainerSpec{actual})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("container changed after running the generated command\n%s\n--- got ---\n%s\n--- want ---\n%s", command, got, want)
+			}
+		})
+	}
+}
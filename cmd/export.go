@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/helson-lin/doke/i18n"
+	"github.com/helson-lin/doke/pkg/convert"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: i18n.T("export.short"),
+	Long:  i18n.T("export.long"),
+}
+
+var exportHostCmd = &cobra.Command{
+	Use:   "host",
+	Short: i18n.T("export.host.short"),
+	Long:  i18n.T("export.host.long"),
+	Example: `  doke export host -o restore.sh
+  doke export host --label app=shop -m -o restore.sh --force
+  doke export host -f inspect.json > restore.sh`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := compileNameFilter(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		// 导出主机上所有满足过滤条件的容器
+		exportAll = true
+		var configs []*types.ContainerJSON
+		var err error
+		if fromFile != "" {
+			configs, err = readContainerConfigs(fromFile, args)
+		} else {
+			var warnings []string
+			configs, _, warnings, err = getDockerContainerConfigs(args)
+			printWarnings(warnings)
+		}
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		options := convert.HostOptions{Resources: loadDockerResources(convert.NewSpecs(configs)), Multiline: multiline}
+		if fromFile == "" {
+			options.ImageDigests = getImageDigests(configs)
+		}
+		script, warnings, err := convert.HostScript(configs, options)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		printWarnings(warnings)
+		if err := writeOutput(script); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	},
+}
+
+func init() {
+	exportHostCmd.Flags().BoolVar(&runningOnly, "running", false, i18n.T("command.flag.running"))
+	exportHostCmd.Flags().StringArrayVarP(&labelSelectors, "label", "l", nil, i18n.T("command.flag.label"))
+	exportHostCmd.Flags().StringVar(&nameRegex, "name-regex", "", i18n.T("command.flag.name_regex"))
+	exportHostCmd.Flags().IntVar(&inspectJobs, "jobs", 4, i18n.T("command.flag.jobs"))
+	exportHostCmd.Flags().BoolVar(&keepDefaults, "keep-defaults", false, i18n.T("command.flag.keep_defaults"))
+	exportHostCmd.Flags().StringVarP(&fromFile, "from-file", "f", "", i18n.T("command.flag.from_file"))
+	exportHostCmd.Flags().BoolVarP(&multiline, "multiline", "m", false, i18n.T("command.flag.multiline"))
+	exportHostCmd.Flags().StringVarP(&outputPath, "output", "o", "", i18n.T("command.flag.output"))
+	exportHostCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, i18n.T("command.flag.yes"))
+	exportHostCmd.Flags().BoolVar(&forceOverwrite, "force", false, i18n.T("command.flag.force"))
+	exportCmd.AddCommand(exportHostCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+// 查询容器镜像的 registry digest，返回镜像名称到 名称@sha256:... 的映射
+// 本地构建或加载的镜像没有 digest，不会出现在结果中；无法连接 Docker 时返回 nil
+func getImageDigests(configs []*types.ContainerJSON) map[string]string {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		rootCmd.PrintErrln(i18n.T("export.digests_skipped", fmt.Errorf("failed to create Docker client: %v", err)))
+		return nil
+	}
+	defer cli.Close()
+
+	digests := make(map[string]string)
+	for _, config := range configs {
+		name := config.Config.Image
+		if _, ok := digests[name]; ok || name == "" || strings.Contains(name, "@") {
+			continue
+		}
+		image, _, err := cli.ImageInspectWithRaw(context.Background(), config.Image)
+		if err != nil {
+			rootCmd.PrintErrln(i18n.T("export.digests_skipped", fmt.Errorf("failed to inspect image %s: %v", name, err)))
+			continue
+		}
+		if digest := pickRepoDigest(name, image.RepoDigests); digest != "" {
+			digests[name] = digest
+		}
+	}
+	return digests
+}
+
+// 优先使用与镜像名称同一仓库的 digest，例如 nginx:1.25 对应 nginx@sha256:...
+func pickRepoDigest(name string, repoDigests []string) string {
+	repository := name
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		repository = name[:index]
+	}
+	for _, digest := range repoDigests {
+		if strings.HasPrefix(digest, repository+"@") {
+			return digest
+		}
+	}
+	if len(repoDigests) > 0 {
+		return repoDigests[0]
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/helson-lin/doke/pkg/convert"
+	"gopkg.in/yaml.v3"
+)
+
+// go test ./cmd -update 重新生成 testdata/golden 下的期望输出
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// 每个 inspect 样例按所有输出格式生成，与 testdata/golden/<样例>/<格式>.golden 比较
+func TestWritersGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "inspect", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no inspect fixtures in testdata/inspect")
+	}
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, format := range writerNames() {
+			t.Run(name+"/"+format, func(t *testing.T) {
+				configs, err := parseContainerConfigs(data)
+				if err != nil {
+					t.Fatalf("failed to parse %s: %v", fixture, err)
+				}
+				specs := convert.NewSpecs(configs)
+				convert.Normalize(specs, convert.NormalizeOptions{})
+				writer, err := getWriter(format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				output, _, err := writer.Write(specs, convertOptions{Format: convert.Format{Shell: convert.ShellPosix}})
+				if err != nil {
+					t.Fatalf("failed to write %s: %v", format, err)
+				}
+				if format == "compose" {
+					checkComposeReferences(t, output)
+				}
+				checkGolden(t, filepath.Join("testdata", "golden", name, format+".golden"), output)
+			})
+		}
+	}
+}
+
+func checkGolden(t *testing.T, path string, output string) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s (run go test ./cmd -update): %v", path, err)
+	}
+	if output != string(expected) {
+		t.Errorf("output differs from %s\n--- got ---\n%s\n--- want ---\n%s", path, output, expected)
+	}
+}
+
+// schema 校验无法发现引用了不存在的 service，这里检查 volumes_from、links、depends_on 与 service: 引用
+func checkComposeReferences(t *testing.T, output string) {
+	t.Helper()
+	var compose convert.DockerCompose
+	if err := yaml.Unmarshal([]byte(output), &compose); err != nil {
+		t.Fatalf("failed to parse compose output: %v", err)
+	}
+	check := func(service string, field string, ref string) {
+		if _, ok := compose.Services[ref]; !ok || ref == service {
+			t.Errorf("service %s: %s refers to %q, which is not another service in the file", service, field, ref)
+		}
+	}
+	for name, service := range compose.Services {
+		for _, from := range service.VolumesFrom {
+			if !strings.HasPrefix(from, "container:") {
+				source, _, _ := strings.Cut(from, ":")
+				check(name, "volumes_from", source)
+			}
+		}
+		for _, link := range service.Links {
+			target, _, _ := strings.Cut(link, ":")
+			check(name, "links", target)
+		}
+		for dependency := range service.DependsOn {
+			check(name, "depends_on", dependency)
+		}
+		for field, mode := range map[string]string{"network_mode": service.NetworkMode, "ipc": service.Ipc, "pid": service.Pid} {
+			if target, ok := strings.CutPrefix(mode, "service:"); ok {
+				check(name, field, target)
+			}
+		}
+	}
+}
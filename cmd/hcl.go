@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 简单的 HCL 输出，按层级缩进两个空格
+type hclWriter struct {
+	builder strings.Builder
+	depth   int
+}
+
+func (w *hclWriter) line(format string, args ...interface{}) {
+	w.builder.WriteString(strings.Repeat("  ", w.depth))
+	fmt.Fprintf(&w.builder, format, args...)
+	w.builder.WriteString("\n")
+}
+
+// Open 开始一个块，例如 resource "docker_container" "web" {
+func (w *hclWriter) Open(blockType string, labels ...string) {
+	parts := []string{blockType}
+	for _, label := range labels {
+		parts = append(parts, quoteHCL(label))
+	}
+	w.line("%s {", strings.Join(parts, " "))
+	w.depth++
+}
+
+func (w *hclWriter) Close() {
+	w.depth--
+	w.line("}")
+}
+
+func (w *hclWriter) Blank() {
+	w.builder.WriteString("\n")
+}
+
+func (w *hclWriter) Comment(format string, args ...interface{}) {
+	w.line("# "+format, args...)
+}
+
+// Attr 写入属性，零值会被跳过
+func (w *hclWriter) Attr(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			w.line("%s = %s", key, quoteHCL(v))
+		}
+	case bool:
+		if v {
+			w.line("%s = true", key)
+		}
+	case int:
+		if v != 0 {
+			w.line("%s = %d", key, v)
+		}
+	case int64:
+		if v != 0 {
+			w.line("%s = %d", key, v)
+		}
+	case []string:
+		if len(v) > 0 {
+			quoted := make([]string, len(v))
+			for i, item := range v {
+				quoted[i] = quoteHCL(item)
+			}
+			w.line("%s = [%s]", key, strings.Join(quoted, ", "))
+		}
+	case map[string]string:
+		quoted := make(map[string]string, len(v))
+		for k, item := range v {
+			quoted[k] = quoteHCL(item)
+		}
+		w.RawMap(key, quoted)
+	default:
+		panic(fmt.Sprintf("unsupported HCL value: %T", value))
+	}
+}
+
+// Raw 写入不需要转义的表达式，例如资源引用
+func (w *hclWriter) Raw(key string, expression string) {
+	w.line("%s = %s", key, expression)
+}
+
+// RawMap 写入值为表达式的 map，按键排序
+func (w *hclWriter) RawMap(key string, expressions map[string]string) {
+	if len(expressions) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(expressions))
+	for k := range expressions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w.line("%s = {", key)
+	w.depth++
+	for _, k := range keys {
+		w.line("%s = %s", quoteHCL(k), expressions[k])
+	}
+	w.depth--
+	w.line("}")
+}
+
+// Variables 声明输入变量，引用的变量（例如 --redact 替换掉的密钥）在运行时通过 -var 或环境变量提供
+func (w *hclWriter) Variables(names []string, sensitive bool) {
+	for _, name := range names {
+		w.Blank()
+		w.Open("variable", name)
+		w.Raw("type", "string")
+		w.Attr("sensitive", sensitive)
+		w.Close()
+	}
+}
+
+func (w *hclWriter) String() string {
+	return w.builder.String()
+}
+
+// HCL 字符串转义，${ 与 %{ 需要写两次以免被当作模板
+func quoteHCL(value string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		"\"", "\\\"",
+		"\n", "\\n",
+		"\r", "\\r",
+		"\t", "\\t",
+		"${", "$${",
+		"%{", "%%{",
+	)
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+// 字符串模板：字面值后接变量引用，例如 "TOKEN=${var.TOKEN}"
+func hclTemplate(literal string, variable string) string {
+	return strings.TrimSuffix(quoteHCL(literal), "\"") + "${var." + variable + "}\""
+}
+
+// 引用的变量名，按出现顺序去重
+func envRefs(specs ...*convert.ContainerSpec) []string {
+	var refs []string
+	for _, spec := range specs {
+		for _, env := range spec.Env {
+			if env.Ref != "" && !slices.Contains(refs, env.Ref) {
+				refs = append(refs, env.Ref)
+			}
+		}
+	}
+	return refs
+}
+
+var hclInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// HCL 标识符只能包含字母、数字、下划线和横线，且不能以数字开头
+func hclIdentifier(name string) string {
+	name = hclInvalidChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+	return name
+}
+
+// terraform 与 nomad 中内存相关参数以 MB 为单位，不足 1MB 的部分向上取整
+func megabytes(bytes int64) int64 {
+	const megabyte = 1024 * 1024
+	return (bytes + megabyte - 1) / megabyte
+}
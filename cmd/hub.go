@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 读取容器配置时使用的参数
+type convertInput struct {
+	Args     []string
+	File     string
+	Project  string
+	EnvFile  string
+	Profiles []string
+}
+
+// 生成输出时使用的参数
+type convertOptions struct {
+	Project   string
+	Format    convert.Format
+	Resources *convert.DockerResources
+	// --redact 写入密钥的 .env 文件的绝对路径，没有替换密钥时为空
+	EnvFile string
+}
+
+// 输入格式：将容器、inspect 文件、docker run 命令或 compose 文件统一读取为 ContainerSpec
+type containerReader struct {
+	// Docker 为 true 时配置来自 Docker，可以查询网络与卷的详细信息
+	Docker bool
+	Read   func(input convertInput) ([]*convert.ContainerSpec, []string, error)
+}
+
+// 输出格式：由 ContainerSpec 生成文本，同时返回无法转换的配置
+type containerWriter struct {
+	// Resources 为 true 时需要网络与卷的详细信息
+	Resources bool
+	Write     func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error)
+}
+
+var containerReaders = make(map[string]containerReader)
+var containerWriters = make(map[string]containerWriter)
+
+// 注册输入格式，新的输入只需要在 init 中注册
+func registerReader(name string, reader containerReader) {
+	containerReaders[name] = reader
+}
+
+// 注册输出格式，新的输出只需要在 init 中注册
+func registerWriter(name string, writer containerWriter) {
+	containerWriters[name] = writer
+}
+
+func readerNames() []string {
+	return sortedKeys(containerReaders)
+}
+
+func writerNames() []string {
+	return sortedKeys(containerWriters)
+}
+
+func getReader(name string) (containerReader, error) {
+	reader, ok := containerReaders[name]
+	if !ok {
+		return containerReader{}, fmt.Errorf("unsupported input format: %s (supported: %s)", name, strings.Join(readerNames(), ", "))
+	}
+	return reader, nil
+}
+
+func getWriter(name string) (containerWriter, error) {
+	writer, ok := containerWriters[name]
+	if !ok {
+		return containerWriter{}, fmt.Errorf("unsupported output format: %s (supported: %s)", name, strings.Join(writerNames(), ", "))
+	}
+	return writer, nil
+}
+
+// 不能展开变量的格式中标签与日志选项的密钥保留为 REDACTED，提示需要替换的 key
+func withRedactedWarnings(write func([]*convert.ContainerSpec, convertOptions) (string, []string, error)) func([]*convert.ContainerSpec, convertOptions) (string, []string, error) {
+	return func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+		output, warnings, err := write(specs, options)
+		for _, spec := range specs {
+			for _, warning := range convert.RedactedWarnings(spec) {
+				warnings = append(warnings, fmt.Sprintf("%s: %s", spec.Name, warning))
+			}
+		}
+		return output, warnings, err
+	}
+}
+
+// 由 docker run 命令生成的格式（run、systemd、quadlet）中被跳过的配置
+func runWarnings(spec *convert.ContainerSpec) []string {
+	var warnings []string
+	for _, warning := range convert.RunWarnings(spec) {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", spec.Name, warning))
+	}
+	return warnings
+}
+
+// 每个容器单独生成输出，多个容器之间用空行分隔
+func writeEach(generate func(spec *convert.ContainerSpec, options convertOptions) (string, []string)) func([]*convert.ContainerSpec, convertOptions) (string, []string, error) {
+	return func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+		var outputs []string
+		var warnings []string
+		for _, spec := range specs {
+			output, configWarnings := generate(spec, options)
+			outputs = append(outputs, output)
+			warnings = append(warnings, configWarnings...)
+		}
+		return strings.Join(outputs, "\n"), warnings, nil
+	}
+}
+
+func init() {
+	registerReader("container", containerReader{
+		Docker: true,
+		Read: func(input convertInput) ([]*convert.ContainerSpec, []string, error) {
+			configs, _, warnings, err := getDockerContainerConfigs(input.Args)
+			return convert.NewSpecs(configs), warnings, err
+		},
+	})
+	registerReader("inspect", containerReader{
+		Read: func(input convertInput) ([]*convert.ContainerSpec, []string, error) {
+			file := input.File
+			if file == "" {
+				file = "-"
+			}
+			configs, err := readContainerConfigs(file, input.Args)
+			return convert.NewSpecs(configs), nil, err
+		},
+	})
+	registerReader("run", containerReader{Read: readRunContainers})
+	registerReader(formatCompose, containerReader{Read: readComposeContainers})
+
+	registerWriter(formatRun, containerWriter{
+		Write: func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+			var lines []string
+			var warnings []string
+			for _, spec := range specs {
+				line, err := convert.Run(spec, options.Format)
+				if err != nil {
+					return "", nil, err
+				}
+				lines = append(lines, line+"\n")
+				warnings = append(warnings, runWarnings(spec)...)
+			}
+			return strings.Join(lines, ""), warnings, nil
+		},
+	})
+	registerWriter(formatCompose, containerWriter{
+		Resources: true,
+		Write: func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+			var warnings []string
+			for _, spec := range specs {
+				for _, warning := range convert.ComposeWarnings(spec) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s", spec.Name, warning))
+				}
+			}
+			compose := convert.Compose(specs, convert.ComposeOptions{Project: options.Project, Resources: options.Resources})
+			yamlData, err := convert.MarshalCompose(compose)
+			return yamlData, append(warnings, convert.ComposeNetworkWarnings(compose)...), err
+		},
+	})
+	registerWriter(formatK8s, containerWriter{
+		Write: withRedactedWarnings(func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+			return getKubernetesYaml(specs, options.EnvFile)
+		}),
+	})
+	registerWriter(formatSystemd, containerWriter{
+		Write: writeEach(func(spec *convert.ContainerSpec, options convertOptions) (string, []string) {
+			return getSystemdUnit(spec, options.EnvFile), runWarnings(spec)
+		}),
+	})
+	registerWriter(formatQuadlet, containerWriter{
+		Write: writeEach(func(spec *convert.ContainerSpec, options convertOptions) (string, []string) {
+			return getQuadletUnit(spec, options.EnvFile), runWarnings(spec)
+		}),
+	})
+	registerWriter(formatAnsible, containerWriter{
+		Resources: true,
+		Write: withRedactedWarnings(func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+			return getAnsibleYaml(specs, options.Resources)
+		}),
+	})
+	registerWriter(formatTerraform, containerWriter{
+		Resources: true,
+		Write: withRedactedWarnings(func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+			hclData, warnings := getTerraformConfig(specs, options.Resources)
+			return hclData, warnings, nil
+		}),
+	})
+	registerWriter(formatNomad, containerWriter{
+		Write: withRedactedWarnings(writeEach(func(spec *convert.ContainerSpec, options convertOptions) (string, []string) {
+			return getNomadJob(spec)
+		})),
+	})
+	for _, format := range []string{formatSpecJSON, formatSpecYAML} {
+		registerWriter(format, containerWriter{
+			Write: func(specs []*convert.ContainerSpec, options convertOptions) (string, []string, error) {
+				if format == formatSpecJSON {
+					output, err := convert.SpecJSON(specs)
+					return output, nil, err
+				}
+				output, err := convert.SpecYAML(specs)
+				return output, nil, err
+			},
+		})
+	}
+}
@@ -0,0 +1,605 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/helson-lin/doke/pkg/convert"
+	"gopkg.in/yaml.v3"
+)
+
+// Kubernetes 资源的最小结构，只包含导出时会用到的字段
+type k8sMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+type k8sObject struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   k8sMetadata `yaml:"metadata"`
+	Spec       interface{} `yaml:"spec"`
+}
+
+type k8sDeploymentSpec struct {
+	Replicas int                `yaml:"replicas"`
+	Selector k8sSelector        `yaml:"selector"`
+	Template k8sPodTemplateSpec `yaml:"template"`
+}
+
+type k8sSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type k8sPodTemplateSpec struct {
+	Metadata k8sMetadataLabels `yaml:"metadata"`
+	Spec     k8sPodSpec        `yaml:"spec"`
+}
+
+type k8sMetadataLabels struct {
+	Labels map[string]string `yaml:"labels"`
+}
+
+type k8sPodSpec struct {
+	Hostname        string                 `yaml:"hostname,omitempty"`
+	HostNetwork     bool                   `yaml:"hostNetwork,omitempty"`
+	HostPID         bool                   `yaml:"hostPID,omitempty"`
+	HostIPC         bool                   `yaml:"hostIPC,omitempty"`
+	SecurityContext *k8sPodSecurityContext `yaml:"securityContext,omitempty"`
+	DNSConfig       *k8sDNSConfig          `yaml:"dnsConfig,omitempty"`
+	HostAliases     []k8sHostAlias         `yaml:"hostAliases,omitempty"`
+	Containers      []k8sContainer         `yaml:"containers"`
+	Volumes         []k8sVolume            `yaml:"volumes,omitempty"`
+}
+
+type k8sPodSecurityContext struct {
+	SupplementalGroups []int64        `yaml:"supplementalGroups,omitempty"`
+	Sysctls            []k8sNameValue `yaml:"sysctls,omitempty"`
+}
+
+type k8sDNSConfig struct {
+	Nameservers []string `yaml:"nameservers,omitempty"`
+	Searches    []string `yaml:"searches,omitempty"`
+}
+
+type k8sHostAlias struct {
+	IP        string   `yaml:"ip"`
+	Hostnames []string `yaml:"hostnames"`
+}
+
+type k8sContainer struct {
+	Name            string              `yaml:"name"`
+	Image           string              `yaml:"image"`
+	Command         []string            `yaml:"command,omitempty"`
+	Args            []string            `yaml:"args,omitempty"`
+	WorkingDir      string              `yaml:"workingDir,omitempty"`
+	Ports           []k8sContainerPort  `yaml:"ports,omitempty"`
+	Env             []k8sEnvVar         `yaml:"env,omitempty"`
+	Resources       *k8sResources       `yaml:"resources,omitempty"`
+	VolumeMounts    []k8sVolumeMount    `yaml:"volumeMounts,omitempty"`
+	LivenessProbe   *k8sProbe           `yaml:"livenessProbe,omitempty"`
+	ReadinessProbe  *k8sProbe           `yaml:"readinessProbe,omitempty"`
+	SecurityContext *k8sSecurityContext `yaml:"securityContext,omitempty"`
+	Stdin           bool                `yaml:"stdin,omitempty"`
+	TTY             bool                `yaml:"tty,omitempty"`
+}
+
+type k8sContainerPort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+type k8sNameValue struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// 环境变量，引用变量时从 Secret 读取
+type k8sEnvVar struct {
+	Name      string        `yaml:"name"`
+	Value     *string       `yaml:"value,omitempty"`
+	ValueFrom *k8sEnvSource `yaml:"valueFrom,omitempty"`
+}
+
+type k8sEnvSource struct {
+	SecretKeyRef k8sSecretKeyRef `yaml:"secretKeyRef"`
+}
+
+type k8sSecretKeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type k8sResources struct {
+	Limits   map[string]string `yaml:"limits,omitempty"`
+	Requests map[string]string `yaml:"requests,omitempty"`
+}
+
+type k8sVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+type k8sProbe struct {
+	Exec                k8sExecAction `yaml:"exec"`
+	InitialDelaySeconds int           `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int           `yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds      int           `yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold    int           `yaml:"failureThreshold,omitempty"`
+}
+
+type k8sExecAction struct {
+	Command []string `yaml:"command"`
+}
+
+type k8sSecurityContext struct {
+	RunAsUser                *int64           `yaml:"runAsUser,omitempty"`
+	RunAsGroup               *int64           `yaml:"runAsGroup,omitempty"`
+	Privileged               bool             `yaml:"privileged,omitempty"`
+	AllowPrivilegeEscalation *bool            `yaml:"allowPrivilegeEscalation,omitempty"`
+	ReadOnlyRootFilesystem   bool             `yaml:"readOnlyRootFilesystem,omitempty"`
+	Capabilities             *k8sCapabilities `yaml:"capabilities,omitempty"`
+}
+
+type k8sCapabilities struct {
+	Add  []string `yaml:"add,omitempty"`
+	Drop []string `yaml:"drop,omitempty"`
+}
+
+type k8sVolume struct {
+	Name                  string                    `yaml:"name"`
+	HostPath              *k8sHostPathSource        `yaml:"hostPath,omitempty"`
+	PersistentVolumeClaim *k8sPersistentVolumeClaim `yaml:"persistentVolumeClaim,omitempty"`
+	EmptyDir              *k8sEmptyDir              `yaml:"emptyDir,omitempty"`
+}
+
+type k8sHostPathSource struct {
+	Path string `yaml:"path"`
+}
+
+type k8sPersistentVolumeClaim struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+type k8sEmptyDir struct {
+	Medium    string `yaml:"medium,omitempty"`
+	SizeLimit string `yaml:"sizeLimit,omitempty"`
+}
+
+type k8sPVCSpec struct {
+	AccessModes []string     `yaml:"accessModes"`
+	Resources   k8sResources `yaml:"resources"`
+}
+
+type k8sServiceSpec struct {
+	Selector map[string]string `yaml:"selector"`
+	Ports    []k8sServicePort  `yaml:"ports"`
+}
+
+type k8sServicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort"`
+	Protocol   string `yaml:"protocol,omitempty"`
+}
+
+// PVC 默认申请的存储大小，docker 的卷没有容量信息
+const k8sDefaultStorage = "1Gi"
+
+var k8sInvalidName = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// 转换为符合 DNS-1123 的资源名称
+func k8sName(name string) string {
+	name = k8sInvalidName.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == "" {
+		name = "app"
+	}
+	return name
+}
+
+// 生成 Kubernetes 清单，同时返回无法转换的配置的警告
+// envFile 是 --redact 写入密钥的文件，用于提示如何创建 Secret
+func getKubernetesYaml(specs []*convert.ContainerSpec, envFile string) (string, []string, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	var warnings []string
+	claims := make(map[string]bool)
+	for _, spec := range specs {
+		objects, objectWarnings := getKubernetesObjects(spec, envFile)
+		for _, warning := range objectWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", spec.Name, warning))
+		}
+		for _, object := range objects {
+			// 多个容器共享的卷只生成一个 PVC
+			if object.Kind == "PersistentVolumeClaim" {
+				if claims[object.Metadata.Name] {
+					continue
+				}
+				claims[object.Metadata.Name] = true
+			}
+			if err := encoder.Encode(object); err != nil {
+				return "", nil, fmt.Errorf("failed to marshal YAML: %v", err)
+			}
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	return buffer.String(), warnings, nil
+}
+
+// 根据单个容器构建 Deployment、Service 与 PVC
+func getKubernetesObjects(spec *convert.ContainerSpec, envFile string) ([]k8sObject, []string) {
+	name := k8sName(spec.ServiceName())
+	labels := map[string]string{"app": name}
+	var warnings []string
+
+	podSpec := k8sPodSpec{}
+	podContainer := k8sContainer{
+		Name:       name,
+		Image:      spec.Image,
+		WorkingDir: spec.WorkingDir,
+		Stdin:      spec.Interactive,
+		TTY:        spec.Tty,
+		// docker 的 entrypoint 对应 command，cmd 对应 args
+		Command: k8sEscapeAll(spec.Entrypoint),
+		Args:    k8sEscapeAll(spec.Command),
+	}
+
+	// 引用的变量（例如 --redact 替换掉的密钥）从 <name>-env Secret 中读取
+	secretName := name + "-env"
+	var secretKeys []string
+	for _, env := range spec.Env {
+		switch {
+		case env.Ref != "":
+			podContainer.Env = append(podContainer.Env, k8sEnvVar{Name: env.Name, ValueFrom: &k8sEnvSource{SecretKeyRef: k8sSecretKeyRef{Name: secretName, Key: env.Ref}}})
+			if !slices.Contains(secretKeys, env.Ref) {
+				secretKeys = append(secretKeys, env.Ref)
+			}
+		case env.Value == nil:
+			// Pod 中没有运行 docker 的环境可以继承
+			warnings = append(warnings, fmt.Sprintf("environment variable %s is inherited from the Docker client environment and is skipped", env.Name))
+		default:
+			value := k8sEscape(*env.Value)
+			podContainer.Env = append(podContainer.Env, k8sEnvVar{Name: env.Name, Value: &value})
+		}
+	}
+	if len(secretKeys) > 0 {
+		warning := fmt.Sprintf("create Secret %s with keys %s before applying", secretName, strings.Join(secretKeys, ", "))
+		if envFile != "" {
+			warning += fmt.Sprintf(", e.g. kubectl create secret generic %s --from-env-file=%s", secretName, envFile)
+		}
+		warnings = append(warnings, warning)
+	}
+
+	// 端口：发布到主机的端口生成 Service，端口范围展开为单个端口
+	var servicePorts []k8sServicePort
+	containerPorts := make(map[string]bool)
+	addPort := func(port int, protocol string) bool {
+		key := fmt.Sprintf("%d/%s", port, protocol)
+		if containerPorts[key] {
+			return false
+		}
+		containerPorts[key] = true
+		podContainer.Ports = append(podContainer.Ports, k8sContainerPort{ContainerPort: port, Protocol: protocol})
+		return true
+	}
+	for _, port := range spec.Ports {
+		protocol := strings.ToUpper(port.Protocol)
+		hostStart, _, hostErr := nat.ParsePortRange(port.HostPort)
+		for offset, containerPort := range expandPortRange(port) {
+			if !addPort(containerPort, protocol) {
+				continue
+			}
+			servicePort := k8sServicePort{
+				Name:       fmt.Sprintf("%s-%d", strings.ToLower(protocol), containerPort),
+				Port:       containerPort,
+				TargetPort: containerPort,
+				Protocol:   protocol,
+			}
+			if hostErr == nil && port.HostPort != "" {
+				servicePort.Port = int(hostStart) + offset
+			}
+			servicePorts = append(servicePorts, servicePort)
+		}
+	}
+	for _, port := range spec.Expose {
+		for _, containerPort := range expandPortRange(port) {
+			addPort(containerPort, strings.ToUpper(port.Protocol))
+		}
+	}
+	if spec.PublishAll {
+		warnings = append(warnings, "publish-all has no Kubernetes equivalent, exposed ports are not added to the Service")
+	}
+
+	// 资源限制
+	resources := &k8sResources{Limits: make(map[string]string), Requests: make(map[string]string)}
+	if spec.Resources.CPUs > 0 {
+		resources.Limits["cpu"] = fmt.Sprintf("%dm", int64(spec.Resources.CPUs*1000))
+	}
+	if spec.Resources.Memory > 0 {
+		resources.Limits["memory"] = k8sQuantity(spec.Resources.Memory)
+	}
+	if spec.Resources.MemoryReservation > 0 {
+		resources.Requests["memory"] = k8sQuantity(spec.Resources.MemoryReservation)
+	}
+	if len(resources.Limits) > 0 || len(resources.Requests) > 0 {
+		podContainer.Resources = resources
+	}
+
+	// 健康检查转换为 liveness 与 readiness 探针
+	if health := spec.Healthcheck; health != nil && len(health.Test) > 1 {
+		probe := &k8sProbe{
+			InitialDelaySeconds: k8sSeconds(health.StartPeriod),
+			PeriodSeconds:       k8sSeconds(health.Interval),
+			TimeoutSeconds:      k8sSeconds(health.Timeout),
+			FailureThreshold:    health.Retries,
+		}
+		switch health.Test[0] {
+		case "CMD-SHELL":
+			probe.Exec.Command = []string{"/bin/sh", "-c", strings.Join(health.Test[1:], " ")}
+		case "CMD":
+			probe.Exec.Command = health.Test[1:]
+		}
+		if len(probe.Exec.Command) > 0 {
+			readiness := *probe
+			podContainer.LivenessProbe = probe
+			podContainer.ReadinessProbe = &readiness
+		}
+	}
+
+	// 安全配置
+	securityContext := &k8sSecurityContext{
+		Privileged:             spec.Privileged,
+		ReadOnlyRootFilesystem: spec.ReadOnly,
+	}
+	if spec.User != "" {
+		user, group, _ := strings.Cut(spec.User, ":")
+		if uid, err := strconv.ParseInt(user, 10, 64); err == nil {
+			securityContext.RunAsUser = &uid
+		} else {
+			warnings = append(warnings, fmt.Sprintf("user %q is not numeric, runAsUser requires a UID", spec.User))
+		}
+		if gid, err := strconv.ParseInt(group, 10, 64); err == nil {
+			securityContext.RunAsGroup = &gid
+		}
+	}
+	if len(spec.CapAdd) > 0 || len(spec.CapDrop) > 0 {
+		securityContext.Capabilities = &k8sCapabilities{
+			Add:  k8sCapabilityNames(spec.CapAdd),
+			Drop: k8sCapabilityNames(spec.CapDrop),
+		}
+	}
+	for _, opt := range spec.SecurityOpt {
+		if opt == "no-new-privileges" || opt == "no-new-privileges:true" {
+			allow := false
+			securityContext.AllowPrivilegeEscalation = &allow
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("security option %s is not supported", opt))
+	}
+	if *securityContext != (k8sSecurityContext{}) {
+		podContainer.SecurityContext = securityContext
+	}
+
+	// 挂载：绑定挂载使用 hostPath，命名卷使用 PVC，tmpfs 与匿名卷使用 emptyDir
+	var objects []k8sObject
+	claims := make(map[string]bool)
+	for i, mount := range spec.Mounts {
+		volume := k8sVolume{Name: fmt.Sprintf("%s-%d", name, i)}
+		switch mount.Type {
+		case "bind":
+			if strings.HasSuffix(mount.Source, "docker.sock") {
+				warnings = append(warnings, fmt.Sprintf("mount of %s exposes the Docker daemon, which is not available on Kubernetes nodes", mount.Source))
+			}
+			volume.HostPath = &k8sHostPathSource{Path: mount.Source}
+		case "volume":
+			if mount.Source == "" {
+				volume.EmptyDir = &k8sEmptyDir{}
+				break
+			}
+			claimName := k8sName(mount.Source)
+			if claims[claimName] {
+				// 同一个卷挂载到多个路径时只声明一次 Pod 卷
+				podContainer.VolumeMounts = append(podContainer.VolumeMounts, k8sVolumeMount{Name: claimName, MountPath: mount.Target, ReadOnly: mount.ReadOnly})
+				continue
+			}
+			claims[claimName] = true
+			volume.Name = claimName
+			volume.PersistentVolumeClaim = &k8sPersistentVolumeClaim{ClaimName: claimName}
+			objects = append(objects, k8sObject{
+				APIVersion: "v1",
+				Kind:       "PersistentVolumeClaim",
+				Metadata:   k8sMetadata{Name: claimName, Labels: labels},
+				Spec: k8sPVCSpec{
+					AccessModes: []string{"ReadWriteOnce"},
+					Resources:   k8sResources{Requests: map[string]string{"storage": k8sDefaultStorage}},
+				},
+			})
+		case "tmpfs":
+			volume.Name = fmt.Sprintf("%s-tmpfs-%d", name, i)
+			volume.EmptyDir = &k8sEmptyDir{Medium: "Memory"}
+		default:
+			warnings = append(warnings, fmt.Sprintf("mount type %s of %s is not supported", mount.Type, mount.Target))
+			continue
+		}
+		podSpec.Volumes = append(podSpec.Volumes, volume)
+		podContainer.VolumeMounts = append(podContainer.VolumeMounts, k8sVolumeMount{Name: volume.Name, MountPath: mount.Target, ReadOnly: mount.ReadOnly})
+	}
+	// --shm-size 使用内存介质的 emptyDir 挂载到 /dev/shm
+	if spec.Resources.ShmSize > 0 {
+		volume := k8sVolume{Name: name + "-shm", EmptyDir: &k8sEmptyDir{Medium: "Memory", SizeLimit: k8sQuantity(spec.Resources.ShmSize)}}
+		podSpec.Volumes = append(podSpec.Volumes, volume)
+		podContainer.VolumeMounts = append(podContainer.VolumeMounts, k8sVolumeMount{Name: volume.Name, MountPath: "/dev/shm"})
+	}
+
+	// 网络与命名空间
+	switch mode := container.NetworkMode(spec.Network.Mode); {
+	case mode.IsHost():
+		podSpec.HostNetwork = true
+		warnings = append(warnings, "host network is mapped to hostNetwork, which binds ports on every node the pod is scheduled to")
+	case mode.IsContainer():
+		warnings = append(warnings, fmt.Sprintf("network mode %s has no Kubernetes equivalent, run both containers in one pod instead", mode))
+	case mode.IsNone():
+		warnings = append(warnings, "network mode none has no Kubernetes equivalent")
+	}
+	podSpec.HostPID = spec.Pid == "host"
+	podSpec.HostIPC = spec.Ipc == "host"
+	if spec.Hostname != "" {
+		podSpec.Hostname = k8sName(spec.Hostname)
+	}
+	if len(spec.Network.DNS) > 0 || len(spec.Network.DNSSearch) > 0 {
+		podSpec.DNSConfig = &k8sDNSConfig{Nameservers: spec.Network.DNS, Searches: spec.Network.DNSSearch}
+	}
+	for _, host := range spec.Network.ExtraHosts {
+		hostname, ip, ok := strings.Cut(host, ":")
+		if ok {
+			podSpec.HostAliases = append(podSpec.HostAliases, k8sHostAlias{IP: ip, Hostnames: []string{hostname}})
+		}
+	}
+	for _, attachment := range spec.Network.Networks {
+		if len(attachment.Aliases) > 0 {
+			warnings = append(warnings, fmt.Sprintf("network aliases %s on %s are not supported, use the Service name for discovery", strings.Join(attachment.Aliases, ", "), attachment.Name))
+		}
+		for _, address := range []string{attachment.IPv4Address, attachment.IPv6Address} {
+			if address != "" {
+				warnings = append(warnings, fmt.Sprintf("static IP %s on %s is not supported, pod IPs are assigned by the cluster", address, attachment.Name))
+			}
+		}
+	}
+	if spec.Network.MacAddress != "" {
+		warnings = append(warnings, fmt.Sprintf("mac address %s is not supported", spec.Network.MacAddress))
+	}
+	podSecurityContext := &k8sPodSecurityContext{}
+	for _, group := range spec.GroupAdd {
+		// supplementalGroups 只接受 GID
+		if gid, err := strconv.ParseInt(group, 10, 64); err == nil {
+			podSecurityContext.SupplementalGroups = append(podSecurityContext.SupplementalGroups, gid)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("group %q is not numeric, supplementalGroups requires a GID", group))
+		}
+	}
+	for _, key := range sortedKeys(spec.Sysctls) {
+		podSecurityContext.Sysctls = append(podSecurityContext.Sysctls, k8sNameValue{Name: key, Value: spec.Sysctls[key]})
+	}
+	if len(podSecurityContext.SupplementalGroups) > 0 || len(podSecurityContext.Sysctls) > 0 {
+		podSpec.SecurityContext = podSecurityContext
+	}
+
+	// 没有对应配置的项
+	for _, device := range spec.Devices {
+		warnings = append(warnings, fmt.Sprintf("device %s has no Kubernetes equivalent, use a device plugin", device.HostPath))
+	}
+	if len(spec.Network.Links) > 0 {
+		warnings = append(warnings, "links are not supported, use the Service name for discovery")
+	}
+	if len(spec.VolumesFrom) > 0 {
+		warnings = append(warnings, "volumes-from is not supported")
+	}
+	if len(spec.Ulimits) > 0 {
+		warnings = append(warnings, "ulimits are not supported")
+	}
+	if spec.Init {
+		warnings = append(warnings, "init is not supported, the command runs as PID 1")
+	}
+	if spec.StopSignal != "" {
+		warnings = append(warnings, fmt.Sprintf("stop signal %s is not supported, pods are stopped with SIGTERM", spec.StopSignal))
+	}
+	if spec.Logging != nil && spec.Logging.Driver != "" {
+		warnings = append(warnings, fmt.Sprintf("log driver %s is not supported", spec.Logging.Driver))
+	}
+	if policy, _, _ := strings.Cut(spec.Restart, ":"); policy == "" || policy == "on-failure" {
+		warnings = append(warnings, fmt.Sprintf("restart policy %s is replaced by the Deployment restart policy Always", firstNonEmpty(policy, "no")))
+	}
+
+	podSpec.Containers = []k8sContainer{podContainer}
+	deployment := k8sObject{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   k8sMetadata{Name: name, Labels: labels},
+		Spec: k8sDeploymentSpec{
+			Replicas: 1,
+			Selector: k8sSelector{MatchLabels: labels},
+			Template: k8sPodTemplateSpec{
+				Metadata: k8sMetadataLabels{Labels: labels},
+				Spec:     podSpec,
+			},
+		},
+	}
+	objects = append([]k8sObject{deployment}, objects...)
+
+	if len(servicePorts) > 0 {
+		objects = append(objects, k8sObject{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Metadata:   k8sMetadata{Name: name, Labels: labels},
+			Spec:       k8sServiceSpec{Selector: labels, Ports: servicePorts},
+		})
+	}
+
+	return objects, warnings
+}
+
+// 端口范围包含的全部容器端口
+func expandPortRange(port convert.PortSpec) []int {
+	ports := []int{port.ContainerPort}
+	for next := port.ContainerPort + 1; next <= port.ContainerPortEnd; next++ {
+		ports = append(ports, next)
+	}
+	return ports
+}
+
+// ContainerSpec 中的时长转换为秒
+func k8sSeconds(value string) int {
+	duration, _ := time.ParseDuration(value)
+	return int(duration.Seconds())
+}
+
+// 字节数转换为 Kubernetes 的资源数量
+func k8sQuantity(bytes int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10}} {
+		if bytes%unit.size == 0 {
+			return fmt.Sprintf("%d%s", bytes/unit.size, unit.suffix)
+		}
+	}
+	return strconv.FormatInt(bytes, 10)
+}
+
+// Kubernetes 会展开 command、args 与 env 中的 $(VAR)，写成 $$( 保留原样
+func k8sEscape(value string) string {
+	return strings.ReplaceAll(value, "$(", "$$(")
+}
+
+func k8sEscapeAll(values []string) []string {
+	var result []string
+	for _, value := range values {
+		result = append(result, k8sEscape(value))
+	}
+	return result
+}
+
+// Kubernetes 的 capability 名称不带 CAP_ 前缀
+func k8sCapabilityNames(capabilities []string) []string {
+	var result []string
+	for _, capability := range capabilities {
+		result = append(result, strings.TrimPrefix(strings.ToUpper(capability), "CAP_"))
+	}
+	return result
+}
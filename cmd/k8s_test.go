@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// Kubernetes 没有对应配置的项必须给出警告，不能静默丢弃
+func TestKubernetesWarnsUnsupportedSettings(t *testing.T) {
+	spec := &convert.ContainerSpec{
+		Name:        "web",
+		Image:       "nginx",
+		Restart:     "always",
+		Init:        true,
+		StopSignal:  "SIGQUIT",
+		GroupAdd:    []string{"44", "audio"},
+		SecurityOpt: []string{"no-new-privileges", "seccomp=unconfined"},
+		Network: convert.NetworkSpec{
+			Mode:       "shopnet",
+			MacAddress: "02:42:ac:11:00:02",
+			Networks: []convert.NetworkAttachment{
+				{Name: "shopnet", Aliases: []string{"www"}, IPv4Address: "172.20.0.10"},
+			},
+		},
+	}
+	output, warnings, err := getKubernetesYaml([]*convert.ContainerSpec{spec}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"web: init is not supported, the command runs as PID 1",
+		"web: stop signal SIGQUIT is not supported, pods are stopped with SIGTERM",
+		`web: group "audio" is not numeric, supplementalGroups requires a GID`,
+		"web: security option seccomp=unconfined is not supported",
+		"web: network aliases www on shopnet are not supported, use the Service name for discovery",
+		"web: static IP 172.20.0.10 on shopnet is not supported, pod IPs are assigned by the cluster",
+		"web: mac address 02:42:ac:11:00:02 is not supported",
+	} {
+		if !slices.Contains(warnings, want) {
+			t.Errorf("missing warning %q in %q", want, warnings)
+		}
+	}
+	for _, want := range []string{"allowPrivilegeEscalation: false", "supplementalGroups:\n          - 44\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+// $( 会被 Kubernetes 当作变量引用展开，需要写成 $$(
+func TestKubernetesEscapesVariableReferences(t *testing.T) {
+	value := "$(date) $HOME"
+	spec := &convert.ContainerSpec{
+		Name:       "job",
+		Image:      "alpine",
+		Restart:    "always",
+		Entrypoint: []string{"/bin/sh", "-c"},
+		Command:    []string{"echo $(hostname)"},
+		Env:        []convert.EnvSpec{{Name: "STAMP", Value: &value}},
+	}
+	objects, _ := getKubernetesObjects(spec, "")
+	podContainer := objects[0].Spec.(k8sDeploymentSpec).Template.Spec.Containers[0]
+
+	if got := podContainer.Args; !slices.Equal(got, []string{"echo $$(hostname)"}) {
+		t.Errorf("args = %q", got)
+	}
+	if got := *podContainer.Env[0].Value; got != "$$(date) $HOME" {
+		t.Errorf("env = %q", got)
+	}
+	if value != "$(date) $HOME" {
+		t.Errorf("spec env was modified: %q", value)
+	}
+}
+
+// 同一个命名卷无论被几个容器、几个路径使用，都只生成一个 PVC
+func TestKubernetesSharedVolumeClaim(t *testing.T) {
+	mounts := []convert.MountSpec{
+		{Type: "volume", Source: "pgdata", Target: "/var/lib/postgresql/data"},
+		{Type: "volume", Source: "pgdata", Target: "/backup", ReadOnly: true},
+	}
+	specs := []*convert.ContainerSpec{
+		{Name: "db", Image: "postgres", Restart: "always", Mounts: mounts},
+		{Name: "backup", Image: "alpine", Restart: "always", Mounts: mounts[1:]},
+	}
+	output, _, err := getKubernetesYaml(specs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(output, "kind: PersistentVolumeClaim"); count != 1 {
+		t.Errorf("got %d PersistentVolumeClaim objects, want 1:\n%s", count, output)
+	}
+
+	objects, _ := getKubernetesObjects(specs[0], "")
+	pod := objects[0].Spec.(k8sDeploymentSpec).Template.Spec
+	if len(pod.Volumes) != 1 || len(pod.Containers[0].VolumeMounts) != 2 {
+		t.Errorf("volumes = %+v, mounts = %+v", pod.Volumes, pod.Containers[0].VolumeMounts)
+	}
+}
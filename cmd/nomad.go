@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// Nomad 以 MHz 为单位分配 CPU，按每个核心 1000 MHz 估算
+const nomadMHzPerCore = 1000
+
+// docker 健康检查的默认间隔与超时
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 30 * time.Second
+)
+
+// Nomad 端口标签，例如 p80、p53_udp
+func nomadPortLabel(port int, protocol string, index int) string {
+	label := fmt.Sprintf("p%d", port)
+	if protocol != "tcp" {
+		label += "_" + protocol
+	}
+	if index > 0 {
+		label += fmt.Sprintf("_%d", index+1)
+	}
+	return hclIdentifier(label)
+}
+
+// network 块中声明的端口
+type nomadPort struct {
+	Label string
+	To    int
+}
+
+// 健康检查命令中的端口，例如 http://localhost:8080/health
+var healthcheckPortPattern = regexp.MustCompile(`:(\d+)\b`)
+
+// service 的端口：健康检查命令中使用的端口，否则为第一个 TCP 端口，没有 TCP 端口时不指定
+func nomadServicePort(ports []nomadPort, health *convert.HealthcheckSpec) string {
+	if len(ports) == 0 {
+		return ""
+	}
+	if health != nil {
+		for _, match := range healthcheckPortPattern.FindAllStringSubmatch(strings.Join(health.Test, " "), -1) {
+			for _, port := range ports {
+				if strconv.Itoa(port.To) == match[1] {
+					return port.Label
+				}
+			}
+		}
+	}
+	return ports[0].Label
+}
+
+// 生成使用 docker 驱动的 Nomad job，同时返回无法转换的配置的警告
+func getNomadJob(spec *convert.ContainerSpec) (string, []string) {
+	var w hclWriter
+	var warnings []string
+	name := spec.Name
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)))
+	}
+	networkMode := container.NetworkMode(spec.Network.Mode)
+
+	w.Comment("%s.nomad.hcl", name)
+	// 通过 -var 或 NOMAD_VAR_<name> 提供
+	if refs := envRefs(spec); len(refs) > 0 {
+		w.Variables(refs, false)
+		w.Blank()
+	}
+	w.Open("job", name)
+	w.Attr("datacenters", []string{"dc1"})
+	w.Attr("type", "service")
+	w.Blank()
+	w.Open("group", name)
+	w.Raw("count", "1")
+
+	// 端口映射写入 group 的 network 块，未指定宿主机端口时使用动态端口，端口范围展开为单个端口
+	// Nomad 的端口不能指定主机 IP，只有主机 IP 不同的映射合并为一个端口
+	var portLabels []string
+	var tcpPorts []nomadPort
+	if len(spec.Ports) > 0 && !networkMode.IsHost() {
+		w.Blank()
+		w.Open("network")
+		bindings := make(map[string]int)
+		declared := make(map[string]bool)
+		for _, port := range spec.Ports {
+			hostStart, _, hostErr := nat.ParsePortRange(port.HostPort)
+			for offset, to := range expandPortRange(port) {
+				static := 0
+				if hostErr == nil && port.HostPort != "" {
+					static = int(hostStart) + offset
+				}
+				binding := fmt.Sprintf("%d:%d/%s", static, to, port.Protocol)
+				if declared[binding] {
+					continue
+				}
+				declared[binding] = true
+				key := fmt.Sprintf("%d/%s", to, port.Protocol)
+				label := nomadPortLabel(to, port.Protocol, bindings[key])
+				bindings[key]++
+				portLabels = append(portLabels, label)
+				if port.Protocol == "tcp" {
+					tcpPorts = append(tcpPorts, nomadPort{Label: label, To: to})
+				}
+				w.Open("port", label)
+				w.Attr("static", static)
+				w.Attr("to", to)
+				w.Close()
+			}
+			if port.HostIP != "" {
+				containerPort := strconv.Itoa(port.ContainerPort)
+				if port.ContainerPortEnd > port.ContainerPort {
+					containerPort += "-" + strconv.Itoa(port.ContainerPortEnd)
+				}
+				warn("host IP %s of port %s/%s cannot be set per port, use a host_network instead", port.HostIP, containerPort, port.Protocol)
+			}
+		}
+		w.Close()
+	}
+	if spec.PublishAll {
+		warn("publish all ports is not supported, declare each port in the network block")
+	}
+
+	// 重启策略
+	w.Blank()
+	w.Open("restart")
+	switch policy, retries, _ := strings.Cut(spec.Restart, ":"); policy {
+	case "always", "unless-stopped":
+		w.Attr("mode", "delay")
+	case "on-failure":
+		w.Raw("attempts", firstNonEmpty(retries, "0"))
+		w.Attr("mode", "fail")
+	default:
+		w.Raw("attempts", "0")
+		w.Attr("mode", "fail")
+		warn("Nomad restarts service tasks that exit successfully, restart policy no is approximated with zero attempts")
+	}
+	w.Close()
+
+	w.Blank()
+	w.Open("task", name)
+	w.Attr("driver", "docker")
+	w.Attr("user", spec.User)
+	w.Blank()
+	w.Open("config")
+	w.Attr("image", spec.Image)
+	w.Attr("ports", portLabels)
+	w.Attr("entrypoint", spec.Entrypoint)
+	if len(spec.Command) > 0 {
+		w.Attr("command", spec.Command[0])
+		w.Attr("args", spec.Command[1:])
+	}
+	w.Attr("work_dir", spec.WorkingDir)
+	w.Attr("hostname", spec.Hostname)
+	w.Attr("interactive", spec.Interactive)
+	w.Attr("tty", spec.Tty)
+	w.Attr("labels", spec.Labels)
+
+	// 网络模式，docker 驱动只能连接一个网络
+	switch {
+	case networkMode.IsHost(), networkMode.IsNone():
+		w.Attr("network_mode", spec.Network.Mode)
+	case networkMode.IsContainer():
+		warn("network mode %s is not supported, run both containers as tasks of one group instead", networkMode)
+	case len(spec.Network.Networks) > 0:
+		// 与 docker run 相同，使用网络模式指定的网络
+		attachment := spec.Network.Networks[0]
+		if i := slices.IndexFunc(spec.Network.Networks, func(a convert.NetworkAttachment) bool { return a.Name == spec.Network.Mode }); i >= 0 {
+			attachment = spec.Network.Networks[i]
+		}
+		w.Attr("network_mode", attachment.Name)
+		w.Attr("network_aliases", attachment.Aliases)
+		w.Attr("ipv4_address", attachment.IPv4Address)
+		w.Attr("ipv6_address", attachment.IPv6Address)
+		if len(spec.Network.Networks) > 1 {
+			warn("only network %s is kept, the docker driver connects a task to a single network", attachment.Name)
+		}
+	}
+	w.Attr("dns_servers", spec.Network.DNS)
+	w.Attr("dns_search_domains", spec.Network.DNSSearch)
+	w.Attr("dns_options", spec.Network.DNSOptions)
+	w.Attr("extra_hosts", spec.Network.ExtraHosts)
+	if len(spec.Network.Links) > 0 {
+		warn("links are not supported, use service discovery instead")
+	}
+
+	// 挂载卷：绑定挂载使用 volumes，命名卷与 tmpfs 使用 mount 块
+	var binds []string
+	for _, mount := range spec.Mounts {
+		if mount.Type == "bind" {
+			binds = append(binds, mount.String())
+		}
+	}
+	w.Attr("volumes", binds)
+	if len(binds) > 0 {
+		warn("bind mounts require volumes to be enabled in the docker plugin configuration of the Nomad client")
+	}
+	for _, mount := range spec.Mounts {
+		switch mount.Type {
+		case "bind":
+			continue
+		case "volume":
+			w.Open("mount")
+			w.Attr("type", "volume")
+			w.Attr("target", mount.Target)
+			w.Attr("source", mount.Source)
+			w.Attr("readonly", mount.ReadOnly)
+			if mount.Driver != "" {
+				w.Open("volume_options")
+				w.Open("driver_config")
+				w.Attr("name", mount.Driver)
+				w.Close()
+				w.Close()
+			}
+			w.Close()
+		case "tmpfs":
+			w.Open("mount")
+			w.Attr("type", "tmpfs")
+			w.Attr("target", mount.Target)
+			if size := tmpfsSize(mount.Options); size > 0 {
+				w.Open("tmpfs_options")
+				w.Attr("size", size)
+				w.Close()
+			}
+			w.Close()
+		default:
+			warn("mount type %s of %s is not supported", mount.Type, mount.Target)
+		}
+	}
+	if len(spec.VolumesFrom) > 0 {
+		warn("volumes-from is not supported, mount the volumes explicitly")
+	}
+
+	// 安全
+	w.Attr("cap_add", spec.CapAdd)
+	w.Attr("cap_drop", spec.CapDrop)
+	w.Attr("privileged", spec.Privileged)
+	w.Attr("readonly_rootfs", spec.ReadOnly)
+	w.Attr("init", spec.Init)
+	w.Attr("security_opt", spec.SecurityOpt)
+	w.Attr("group_add", spec.GroupAdd)
+	for _, device := range spec.Devices {
+		w.Open("devices")
+		w.Attr("host_path", device.HostPath)
+		w.Attr("container_path", device.ContainerPath)
+		w.Attr("cgroup_permissions", firstNonEmpty(device.Permissions, "rwm"))
+		w.Close()
+	}
+	if len(spec.Ulimits) > 0 {
+		ulimits := make(map[string]string)
+		for _, ulimit := range spec.Ulimits {
+			ulimits[ulimit.Name] = fmt.Sprintf("%d:%d", ulimit.Soft, ulimit.Hard)
+		}
+		w.Attr("ulimit", ulimits)
+	}
+	w.Attr("sysctl", spec.Sysctls)
+
+	// 命名空间
+	w.Attr("ipc_mode", spec.Ipc)
+	w.Attr("pid_mode", spec.Pid)
+	w.Attr("uts_mode", spec.Uts)
+	w.Attr("userns_mode", spec.Userns)
+	w.Attr("runtime", spec.Runtime)
+	resources := spec.Resources
+	if resources.ShmSize > 0 {
+		w.Attr("shm_size", resources.ShmSize)
+	}
+	if resources.PidsLimit > 0 {
+		w.Attr("pids_limit", resources.PidsLimit)
+	}
+	if resources.CPUs > 0 {
+		w.Attr("cpu_hard_limit", true)
+	}
+
+	// 日志
+	if logging := spec.Logging; logging != nil {
+		w.Open("logging")
+		w.Attr("type", firstNonEmpty(logging.Driver, "json-file"))
+		w.Attr("config", logging.Options)
+		w.Close()
+	}
+	w.Close()
+
+	// 环境变量，Nomad 中没有运行 docker 的环境可以继承
+	env := make(map[string]string)
+	for _, item := range spec.Env {
+		switch {
+		case item.Ref != "":
+			env[item.Name] = "var." + item.Ref
+		case item.Value == nil:
+			warn("environment variable %s is inherited from the Docker client environment and is skipped", item.Name)
+		default:
+			env[item.Name] = quoteHCL(*item.Value)
+		}
+	}
+	if len(env) > 0 {
+		w.Blank()
+		w.RawMap("env", env)
+	}
+
+	w.Blank()
+	w.Attr("kill_signal", spec.StopSignal)
+	if spec.StopTimeout != nil {
+		w.Attr("kill_timeout", fmt.Sprintf("%ds", *spec.StopTimeout))
+	}
+
+	// 资源，Nomad 按预留的资源调度任务
+	hasResources := resources.CPUs > 0 || resources.Memory > 0
+	if hasResources {
+		w.Blank()
+		w.Open("resources")
+	}
+	if resources.CPUs > 0 {
+		w.Raw("cpu", strconv.FormatInt(int64(resources.CPUs*nomadMHzPerCore), 10))
+		warn("cpus %s is converted to MHz assuming %d MHz per core", strconv.FormatFloat(resources.CPUs, 'f', -1, 64), nomadMHzPerCore)
+	}
+	if resources.MemoryReservation > 0 && resources.Memory > resources.MemoryReservation {
+		w.Attr("memory", megabytes(resources.MemoryReservation))
+		w.Attr("memory_max", megabytes(resources.Memory))
+	} else {
+		w.Attr("memory", megabytes(resources.Memory))
+	}
+	if hasResources {
+		w.Close()
+	}
+	if resources.CPUShares > 0 {
+		warn("cpu shares are not supported, Nomad derives them from the cpu resource")
+	}
+	if resources.CpusetCpus != "" {
+		warn("cpuset %s is not supported, use resources.cores instead", resources.CpusetCpus)
+	}
+	if resources.MemorySwap > 0 {
+		warn("memory swap is not supported")
+	}
+
+	// 健康检查转换为 service 的 script 检查
+	if health := spec.Healthcheck; health != nil && len(health.Test) > 1 || len(portLabels) > 0 {
+		w.Blank()
+		w.Open("service")
+		w.Attr("name", k8sName(name))
+		w.Attr("port", nomadServicePort(tcpPorts, spec.Healthcheck))
+		if health != nil && len(health.Test) > 1 {
+			w.Blank()
+			w.Open("check")
+			w.Attr("type", "script")
+			switch health.Test[0] {
+			case "CMD-SHELL":
+				w.Attr("command", "/bin/sh")
+				w.Attr("args", []string{"-c", strings.Join(health.Test[1:], " ")})
+			default:
+				w.Attr("command", health.Test[1])
+				w.Attr("args", health.Test[2:])
+			}
+			w.Attr("interval", healthDuration(health.Interval, defaultHealthInterval).String())
+			w.Attr("timeout", healthDuration(health.Timeout, defaultHealthTimeout).String())
+			w.Close()
+			if health.StartPeriod != "" || health.Retries > 0 {
+				warn("healthcheck start period and retries are not supported by Nomad checks")
+			}
+		}
+		w.Close()
+	}
+
+	w.Close()
+	w.Close()
+	w.Close()
+	return w.String(), warnings
+}
+
+// ContainerSpec 中的健康检查时长，未设置时使用 docker 的默认值
+func healthDuration(value string, fallback time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration
+	}
+	return fallback
+}
+
+// 从 tmpfs 参数中解析 size，单位为字节
+func tmpfsSize(options string) int64 {
+	for _, option := range strings.Split(options, ",") {
+		if value, ok := strings.CutPrefix(option, "size="); ok {
+			if size, err := units.RAMInBytes(value); err == nil {
+				return size
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// service 使用健康检查中的端口，否则使用第一个 TCP 端口，只有 UDP 端口时不指定
+func TestNomadServicePort(t *testing.T) {
+	ports := []convert.PortSpec{
+		{HostPort: "53", ContainerPort: 53, Protocol: "udp"},
+		{HostPort: "8080", ContainerPort: 80, Protocol: "tcp"},
+		{HostIP: "127.0.0.1", HostPort: "9090", ContainerPort: 9090, Protocol: "tcp"},
+		{HostIP: "::1", HostPort: "9090", ContainerPort: 9090, Protocol: "tcp"},
+	}
+	tests := []struct {
+		name   string
+		ports  []convert.PortSpec
+		health *convert.HealthcheckSpec
+		want   string
+	}{
+		{name: "first tcp port", ports: ports, want: `port = "p80"`},
+		{
+			name:   "healthcheck port",
+			ports:  ports,
+			health: &convert.HealthcheckSpec{Test: []string{"CMD-SHELL", "wget -qO- http://localhost:9090/metrics"}},
+			want:   `port = "p9090"`,
+		},
+		{name: "udp only", ports: ports[:1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _ := getNomadJob(&convert.ContainerSpec{Name: "web", Image: "nginx", Ports: tt.ports, Healthcheck: tt.health})
+			if strings.Contains(job, "p9090_2") {
+				t.Errorf("port 9090 bound on two host IPs was declared twice:\n%s", job)
+			}
+			if tt.want == "" {
+				if strings.Contains(job, "port = ") {
+					t.Errorf("service port set without a tcp port:\n%s", job)
+				}
+				return
+			}
+			if !strings.Contains(job, tt.want) {
+				t.Errorf("job does not contain %s:\n%s", tt.want, job)
+			}
+		})
+	}
+}
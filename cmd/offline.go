@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/helson-lin/doke/i18n"
+)
+
+// 读取 docker inspect 的输出，支持单个对象或数组，path 为 - 时从标准输入读取
+// 指定 args 或标签选择器时只保留匹配的容器
+func readContainerConfigs(path string, args []string) ([]*types.ContainerJSON, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	configs, err := parseContainerConfigs(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	configs = filterContainerConfigs(configs, args, getLabelSelectors())
+	if len(configs) == 0 {
+		return nil, fmt.Errorf(i18n.T("command.no_container_matched", path))
+	}
+	return configs, nil
+}
+
+// 解析 docker inspect 的 JSON
+func parseContainerConfigs(data []byte) ([]*types.ContainerJSON, error) {
+	data = bytes.TrimSpace(data)
+	var configs []*types.ContainerJSON
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, err
+		}
+	} else {
+		var config types.ContainerJSON
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+		configs = append(configs, &config)
+	}
+
+	for i, config := range configs {
+		// 镜像、网络等其他对象的 inspect 输出没有这些字段
+		if config == nil || config.ContainerJSONBase == nil || config.Config == nil || config.HostConfig == nil {
+			return nil, fmt.Errorf(i18n.T("command.invalid_inspect", i+1))
+		}
+	}
+	return configs, nil
+}
+
+// 按容器名称、ID 前缀以及标签过滤
+func filterContainerConfigs(configs []*types.ContainerJSON, args []string, selectors []string) []*types.ContainerJSON {
+	var result []*types.ContainerJSON
+	for _, config := range configs {
+		if len(args) > 0 && !matchContainer(config, args) {
+			continue
+		}
+		if !matchLabels(config.Config.Labels, selectors) {
+			continue
+		}
+		if !matchContainerFilters(config.Name, config.State != nil && config.State.Running) {
+			continue
+		}
+		result = append(result, config)
+	}
+	return result
+}
+
+func matchContainer(config *types.ContainerJSON, args []string) bool {
+	name := strings.TrimPrefix(config.Name, "/")
+	for _, arg := range args {
+		if arg == name || strings.HasPrefix(config.ID, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// 标签选择器格式为 key 或 key=value，需要全部匹配
+func matchLabels(labels map[string]string, selectors []string) bool {
+	for _, selector := range selectors {
+		key, value, hasValue := strings.Cut(selector, "=")
+		actual, ok := labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/helson-lin/doke/i18n"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+var outputPath string
+var assumeYes bool = false
+var forceOverwrite bool = false
+
+// --all 未指定 -o 时使用的输出目录
+const defaultExportDir = "doke-export"
+
+// 批量导出时记录每个文件对应的容器的索引文件
+const indexFileName = "index.json"
+
+// 写入磁盘的单个输出文件
+type outputFile struct {
+	Path  string
+	Data  string
+	Specs []*convert.ContainerSpec
+	// 文件权限，为 0 时使用 0644
+	Mode os.FileMode
+}
+
+// 索引文件中的一个容器
+type indexEntry struct {
+	Name    string `json:"name"`
+	ID      string `json:"id"`
+	Image   string `json:"image"`
+	Status  string `json:"status,omitempty"`
+	Project string `json:"project,omitempty"`
+	File    string `json:"file"`
+}
+
+// 每种格式写入目录时使用的扩展名
+var formatExtensions = map[string]string{
+	formatRun:       ".sh",
+	formatCompose:   ".yml",
+	formatK8s:       ".yaml",
+	formatSystemd:   ".service",
+	formatQuadlet:   ".container",
+	formatAnsible:   ".yml",
+	formatTerraform: ".tf",
+	formatNomad:     ".nomad.hcl",
+	formatSpecJSON:  ".json",
+	formatSpecYAML:  ".yaml",
+}
+
+// 标准输入是否为终端，CI 与管道中不能交互确认；/dev/null 也是字符设备，需要单独排除
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// -o 指定的是目录：已存在的目录，或以路径分隔符结尾
+func isOutputDirectory(path string) bool {
+	if path == "" || path == "-" {
+		return false
+	}
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// 批量导出到目录：compose 每个项目一个文件，没有项目的容器单独一个文件，其他格式每个容器一个文件
+// perContainer 为 true 时 compose 也每个容器一个文件
+func renderOutputFiles(writer containerWriter, specs []*convert.ContainerSpec, options convertOptions, format string, dir string, perContainer bool) ([]outputFile, []string, error) {
+	var names []string
+	groups := make(map[string][]*convert.ContainerSpec)
+	for _, spec := range specs {
+		name := spec.Name
+		if format == formatCompose && !perContainer {
+			name = getComposeFileName([]*convert.ContainerSpec{spec}, options.Project)
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], spec)
+	}
+
+	extension := formatExtensions[format]
+	if format == formatRun && options.Format.Shell == convert.ShellPowerShell {
+		extension = ".ps1"
+	}
+	var files []outputFile
+	var warnings []string
+	for _, name := range names {
+		output, groupWarnings, err := writer.Write(groups[name], options)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", name, err)
+		}
+		warnings = append(warnings, groupWarnings...)
+		files = append(files, outputFile{Path: filepath.Join(dir, name+extension), Data: output, Specs: groups[name]})
+	}
+	return files, warnings, nil
+}
+
+// 生成索引文件，列出每个容器与对应的文件，不包含时间等每次都会变化的内容，方便提交到 git
+// 容器状态不在 ContainerSpec 中，按 ID 从容器配置中查找
+func renderIndexFile(files []outputFile, configs []*types.ContainerJSON, dir string) (outputFile, error) {
+	status := make(map[string]string)
+	for _, config := range configs {
+		if config.State != nil {
+			status[config.ID] = config.State.Status
+		}
+	}
+	entries := []indexEntry{}
+	for _, file := range files {
+		for _, spec := range file.Specs {
+			entry := indexEntry{
+				Name:   spec.Name,
+				ID:     spec.ID,
+				Image:  spec.Image,
+				Status: status[spec.ID],
+				File:   filepath.Base(file.Path),
+			}
+			if spec.Compose != nil {
+				entry.Project = spec.Compose.Project
+			}
+			entries = append(entries, entry)
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return outputFile{}, fmt.Errorf("failed to marshal index: %v", err)
+	}
+	return outputFile{Path: filepath.Join(dir, indexFileName), Data: string(data) + "\n"}, nil
+}
+
+// 写入 compose 文件：-o - 输出到标准输出，-o 目录写入 <name>.yml，未指定时写入当前目录并确认
+// extra 是同时写入的其他文件（例如 .env），与 compose 文件一起检查是否覆盖
+func writeComposeOutput(name string, data string, extra ...outputFile) error {
+	switch {
+	case outputPath == "-":
+		fmt.Print(data)
+		return writeOutputFiles(extra, false)
+	case isOutputDirectory(outputPath):
+		return writeOutputFiles(append([]outputFile{{Path: filepath.Join(outputPath, name+".yml"), Data: data}}, extra...), false)
+	case outputPath != "":
+		return writeOutputFiles(append([]outputFile{{Path: outputPath, Data: data}}, extra...), false)
+	}
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf(i18n.T("error.get_current_dir", err))
+	}
+	return writeOutputFiles(append([]outputFile{{Path: filepath.Join(currentDir, name+".yml"), Data: data}}, extra...), true)
+}
+
+// 写入默认输出到标准输出的格式：未指定 -o 或 -o - 时输出到标准输出，否则写入文件
+func writeOutput(data string, extra ...outputFile) error {
+	if outputPath == "" || outputPath == "-" {
+		fmt.Print(data)
+		return writeOutputFiles(extra, false)
+	}
+	return writeOutputFiles(append([]outputFile{{Path: outputPath, Data: data}}, extra...), false)
+}
+
+// 写入输出文件，已存在的文件只有确认、指定 --yes 或 --force 后才覆盖
+// confirmNew 为 true 时新文件也需要确认，用于没有通过 -o 指定路径的情况
+// 先检查并确认所有文件再写入，批量导出时不会因为某个文件冲突只写入一部分
+func writeOutputFiles(files []outputFile, confirmNew bool) error {
+	var conflicts []string
+	for _, file := range files {
+		if _, err := os.Stat(file.Path); err == nil && !forceOverwrite && !assumeYes && !stdinTerminal() {
+			conflicts = append(conflicts, file.Path)
+		}
+	}
+	switch {
+	case len(conflicts) == 1:
+		return fmt.Errorf(i18n.T("command.output_exists", conflicts[0]))
+	case len(conflicts) > 1:
+		return fmt.Errorf(i18n.T("command.outputs_exist", strings.Join(conflicts, ", ")))
+	}
+
+	var selected []outputFile
+	for _, file := range files {
+		prompt := ""
+		if _, err := os.Stat(file.Path); err == nil {
+			if !forceOverwrite {
+				prompt = i18n.T("command.overwrite_confirm", file.Path)
+			}
+		} else if confirmNew && stdinTerminal() {
+			prompt = i18n.T("command.compose_confirm", file.Path)
+		}
+		if prompt != "" {
+			confirmed, err := confirm(prompt)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				rootCmd.PrintErrln(i18n.T("command.compose_cancelled"))
+				continue
+			}
+		}
+		selected = append(selected, file)
+	}
+
+	for _, file := range selected {
+		if dir := filepath.Dir(file.Path); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %v", dir, err)
+			}
+		}
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Data), mode); err != nil {
+			return fmt.Errorf(i18n.T("error.write_file", err))
+		}
+		// 已存在的文件不会被 WriteFile 修改权限
+		if err := os.Chmod(file.Path, mode); err != nil {
+			return fmt.Errorf("failed to chmod %s: %v", file.Path, err)
+		}
+		// 主要输出可能写到标准输出（例如 .env 与输出到标准输出的命令），提示信息写到标准错误
+		rootCmd.PrintErrln(i18n.T("command.output_written", file.Path))
+	}
+	return nil
+}
+
+// 标准输入是否为终端与读取确认的输入，测试中替换
+var (
+	stdinTerminal           = stdinIsTerminal
+	promptInput   io.Reader = os.Stdin
+)
+
+// 交互确认，--yes 时直接确认，标准输入不是终端时报错而不是等待输入
+func confirm(prompt string) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+	if !stdinTerminal() {
+		return false, fmt.Errorf(i18n.T("command.not_interactive"))
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	var answer string
+	if _, err := fmt.Fscanln(promptInput, &answer); err != nil {
+		return false, fmt.Errorf(i18n.T("error.read_user_input", err))
+	}
+	return strings.ToLower(answer) == "y", nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 覆盖已存在文件的确认：--yes、--force、终端中确认与非终端的冲突
+func TestWriteOutputFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   []string
+		yes        bool
+		force      bool
+		terminal   bool
+		input      string
+		confirmNew bool
+		want       map[string]string
+		err        string
+	}{
+		{
+			name: "new files",
+			want: map[string]string{"web.yml": "new", "sub/db.yml": "new"},
+		},
+		{
+			name:     "non-interactive conflict writes nothing",
+			existing: []string{"sub/db.yml"},
+			want:     map[string]string{"web.yml": "", "sub/db.yml": "old"},
+			err:      "db.yml",
+		},
+		{
+			name:     "non-interactive conflicts are listed together",
+			existing: []string{"web.yml", "sub/db.yml"},
+			want:     map[string]string{"web.yml": "old", "sub/db.yml": "old"},
+			err:      "web.yml, ",
+		},
+		{
+			name:     "yes overwrites",
+			existing: []string{"web.yml"},
+			yes:      true,
+			want:     map[string]string{"web.yml": "new", "sub/db.yml": "new"},
+		},
+		{
+			name:     "force overwrites",
+			existing: []string{"web.yml"},
+			force:    true,
+			want:     map[string]string{"web.yml": "new", "sub/db.yml": "new"},
+		},
+		{
+			name:     "terminal confirms overwrite",
+			existing: []string{"web.yml"},
+			terminal: true,
+			input:    "y\n",
+			want:     map[string]string{"web.yml": "new", "sub/db.yml": "new"},
+		},
+		{
+			name:     "terminal declines overwrite",
+			existing: []string{"web.yml"},
+			terminal: true,
+			input:    "n\n",
+			want:     map[string]string{"web.yml": "old", "sub/db.yml": "new"},
+		},
+		{
+			name:       "terminal confirms new files",
+			terminal:   true,
+			confirmNew: true,
+			input:      "y\nn\n",
+			want:       map[string]string{"web.yml": "new", "sub/db.yml": ""},
+		},
+		{
+			name:       "new files without terminal are written",
+			confirmNew: true,
+			want:       map[string]string{"web.yml": "new", "sub/db.yml": "new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			assumeYes, forceOverwrite = tt.yes, tt.force
+			stdinTerminal = func() bool { return tt.terminal }
+			promptInput = strings.NewReader(tt.input)
+			t.Cleanup(func() {
+				assumeYes, forceOverwrite = false, false
+				stdinTerminal, promptInput = stdinIsTerminal, os.Stdin
+			})
+
+			files := []outputFile{
+				{Path: filepath.Join(dir, "web.yml"), Data: "new"},
+				{Path: filepath.Join(dir, "sub", "db.yml"), Data: "new"},
+			}
+			err := writeOutputFiles(files, tt.confirmNew)
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			for name, want := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil && !os.IsNotExist(err) {
+					t.Fatal(err)
+				}
+				if string(data) != want {
+					t.Errorf("%s = %q, want %q", name, data, want)
+				}
+			}
+		})
+	}
+}
+
+// -o 指定文件或目录
+func TestWriteOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		output string
+		path   string
+	}{
+		{name: "file", output: filepath.Join(dir, "stack.yml"), path: filepath.Join(dir, "stack.yml")},
+		{name: "directory", output: filepath.Join(dir, "out") + "/", path: filepath.Join(dir, "out", "shop.yml")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath = tt.output
+			stdinTerminal = func() bool { return false }
+			t.Cleanup(func() {
+				outputPath = ""
+				stdinTerminal = stdinIsTerminal
+			})
+			if err := writeComposeOutput("shop", "services: {}\n"); err != nil {
+				t.Fatal(err)
+			}
+			if data, err := os.ReadFile(tt.path); err != nil || string(data) != "services: {}\n" {
+				t.Errorf("%s = %q, %v", tt.path, data, err)
+			}
+		})
+	}
+}
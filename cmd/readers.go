@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/helson-lin/doke/i18n"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 读取 docker run 命令，每个命令对应一个容器
+func readRunContainers(input convertInput) ([]*convert.ContainerSpec, []string, error) {
+	commands, connects, err := readDockerRunCommands(input.File, input.Args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var specs []*convert.ContainerSpec
+	var warnings []string
+	names := make(map[string]bool)
+	for i, args := range commands {
+		parsed, err := parseDockerRun(args)
+		if err != nil {
+			if len(commands) > 1 {
+				return nil, nil, fmt.Errorf(i18n.T("convert.invalid_command", i+1, err))
+			}
+			return nil, nil, err
+		}
+
+		// 没有 --name 的命令使用镜像名称，重复时追加序号
+		name := parsed.Name
+		for n := 2; names[name]; n++ {
+			name = fmt.Sprintf("%s-%d", parsed.Name, n)
+		}
+		names[name] = true
+
+		spec := parsed.Spec
+		if spec.Name == "" {
+			spec.Name = name
+		}
+		for _, warning := range append(parsed.Warnings, applyEnvFiles(spec, parsed.EnvFiles)...) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, warning))
+		}
+		specs = append(specs, spec)
+	}
+	connectWarnings, err := applyNetworkConnects(specs, connects)
+	if err != nil {
+		return nil, nil, err
+	}
+	return specs, append(warnings, connectWarnings...), nil
+}
+
+// 读取 compose 文件，每个启用的 service 对应一个容器，args 为需要转换的 service
+func readComposeContainers(input convertInput) ([]*convert.ContainerSpec, []string, error) {
+	file, err := findComposeFile(input.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	project, err := loadComposeProject(file, input.EnvFile, input.Project)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err := orderComposeServices(project, input.Args, activeComposeProfiles(project, input.Profiles))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var specs []*convert.ContainerSpec
+	warnings := slices.Clone(project.Warnings)
+	for _, name := range order {
+		// 先转换为 docker run 参数，再按 docker run 命令读取
+		// 插值后的值都是字面值，转义 $ 后按 compose 的插值语义解析
+		cmd, _, serviceWarnings, err := getComposeServiceRun(project, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service %s: %v", name, err)
+		}
+		words := cmd.Words()
+		for i, word := range words {
+			words[i] = strings.ReplaceAll(word, "$", "$$")
+		}
+		parsed, err := parseDockerRun(words)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service %s: %v", name, err)
+		}
+		spec := parsed.Spec
+		for _, warning := range append(append(serviceWarnings, parsed.Warnings...), applyEnvFiles(spec, parsed.EnvFiles)...) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, warning))
+		}
+
+		// docker run 只能指定一个网络，其余网络直接加入 ContainerSpec
+		service := project.Services[name]
+		if service["network_mode"] == nil {
+			networks, networkConfig := composeServiceNetworks(service)
+			for _, key := range networks[1:] {
+				entry := project.Networks[key]
+				if entry == nil {
+					entry = make(map[string]interface{})
+				}
+				spec.Network.Networks = append(spec.Network.Networks, convert.NetworkAttachment{
+					Name:        composeResourceName(project, key, entry),
+					Aliases:     composeList(networkConfig[key]["aliases"]),
+					IPv4Address: composeString(networkConfig[key]["ipv4_address"]),
+					IPv6Address: composeString(networkConfig[key]["ipv6_address"]),
+				})
+			}
+			slices.SortStableFunc(spec.Network.Networks, func(a, b convert.NetworkAttachment) int { return strings.Compare(a.Name, b.Name) })
+		}
+		markComposeExternal(spec, project)
+
+		// depends_on 与 compose 创建的容器一样记录在 Compose 中
+		dependsOn := make(map[string]convert.DependsOn)
+		switch value := service["depends_on"].(type) {
+		case []interface{}:
+			for _, item := range value {
+				dependsOn[composeString(item)] = convert.DependsOn{Condition: "service_started"}
+			}
+		case map[string]interface{}:
+			for _, key := range sortedKeys(value) {
+				entry, _ := value[key].(map[string]interface{})
+				dependsOn[key] = convert.DependsOn{
+					Condition: firstNonEmpty(composeString(entry["condition"]), "service_started"),
+					Restart:   composeString(entry["restart"]) == "true",
+				}
+			}
+		}
+		if len(dependsOn) > 0 {
+			if spec.Compose == nil {
+				spec.Compose = &convert.ComposeInfo{Service: name}
+			}
+			spec.Compose.DependsOn = dependsOn
+		}
+		specs = append(specs, spec)
+	}
+	return specs, warnings, nil
+}
+
+// --env-file 中的变量先生效，-e 指定的同名变量覆盖文件中的值，返回读取失败的警告
+func applyEnvFiles(spec *convert.ContainerSpec, files []string) []string {
+	var warnings []string
+	var env []convert.EnvSpec
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("env file %s is skipped: %v", file, err))
+			continue
+		}
+		values := parseDotEnv(string(data))
+		for _, key := range sortedKeys(values) {
+			value := values[key]
+			// 后面的文件覆盖前面的文件
+			env = slices.DeleteFunc(env, func(item convert.EnvSpec) bool { return item.Name == key })
+			env = append(env, convert.EnvSpec{Name: key, Value: &value})
+		}
+	}
+	for _, item := range spec.Env {
+		env = slices.DeleteFunc(env, func(existing convert.EnvSpec) bool { return existing.Name == item.Name })
+	}
+	if len(env) > 0 {
+		spec.Env = append(env, spec.Env...)
+	}
+	return warnings
+}
+
+// 按 compose 文件中的声明标记外部网络与卷，其余资源由 compose 创建
+func markComposeExternal(spec *convert.ContainerSpec, project *composeProject) {
+	networks := make(map[string]bool)
+	for key, entry := range project.Networks {
+		if isComposeExternal(entry) {
+			networks[composeResourceName(project, key, entry)] = true
+		}
+	}
+	for i, attachment := range spec.Network.Networks {
+		spec.Network.Networks[i].External = networks[attachment.Name]
+	}
+
+	volumes := make(map[string]bool)
+	for key, entry := range project.Volumes {
+		if isComposeExternal(entry) {
+			volumes[composeResourceName(project, key, entry)] = true
+		}
+	}
+	for i, mount := range spec.Mounts {
+		if mount.Type == "volume" {
+			spec.Mounts[i].External = volumes[mount.Source]
+		}
+	}
+}
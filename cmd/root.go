@@ -6,6 +6,7 @@ import (
 
 	"github.com/helson-lin/doke/i18n"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -58,6 +59,28 @@ func updateAllCommandsText() {
 		case "command":
 			cmd.Short = i18n.T("command.short")
 			cmd.Long = i18n.T("command.long")
+		case "convert":
+			cmd.Short = i18n.T("convert.short")
+			cmd.Long = i18n.T("convert.long")
+			for _, sub := range cmd.Commands() {
+				switch sub.Name() {
+				case "run-to-compose":
+					sub.Short = i18n.T("convert.run_to_compose.short")
+					sub.Long = i18n.T("convert.run_to_compose.long")
+				case "compose-to-run":
+					sub.Short = i18n.T("convert.compose_to_run.short")
+					sub.Long = i18n.T("convert.compose_to_run.long")
+				}
+			}
+		case "export":
+			cmd.Short = i18n.T("export.short")
+			cmd.Long = i18n.T("export.long")
+			for _, sub := range cmd.Commands() {
+				if sub.Name() == "host" {
+					sub.Short = i18n.T("export.host.short")
+					sub.Long = i18n.T("export.host.long")
+				}
+			}
 		case "completion":
 			cmd.Short = i18n.T("completion.short")
 			cmd.Long = i18n.T("completion.long")
@@ -71,6 +94,27 @@ func updateAllCommandsText() {
 	if versionFlag := rootCmd.PersistentFlags().Lookup("version"); versionFlag != nil {
 		versionFlag.Usage = i18n.T("version.print")
 	}
+	updateFlagUsages(rootCmd)
+}
+
+// 标志注册时的描述，init 中 i18n 尚未初始化，描述是翻译 key
+var flagUsageKeys = make(map[*pflag.Flag]string)
+
+// 按当前语言重新翻译所有子命令的标志描述
+func updateFlagUsages(cmd *cobra.Command) {
+	translate := func(flag *pflag.Flag) {
+		key, ok := flagUsageKeys[flag]
+		if !ok {
+			key = flag.Usage
+			flagUsageKeys[flag] = key
+		}
+		flag.Usage = i18n.T(key)
+	}
+	cmd.LocalFlags().VisitAll(translate)
+	cmd.PersistentFlags().VisitAll(translate)
+	for _, sub := range cmd.Commands() {
+		updateFlagUsages(sub)
+	}
 }
 
 func init() {
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 去掉 docker run 命令无法表达的差异：
+// 网络与卷是否在 compose 之外创建只有 Docker 能确定，compose 标签不会输出到 docker run，
+// --entrypoint 只能是一个参数，其余部分移到命令中，容器执行的参数不变
+func normalizeRoundTrip(specs []*convert.ContainerSpec) {
+	for _, spec := range specs {
+		for i := range spec.Network.Networks {
+			spec.Network.Networks[i].External = false
+		}
+		for i := range spec.Mounts {
+			spec.Mounts[i].External = false
+		}
+		spec.Compose = nil
+		if len(spec.Entrypoint) > 1 {
+			spec.Command = append(slices.Clone(spec.Entrypoint[1:]), spec.Command...)
+			spec.Entrypoint = spec.Entrypoint[:1]
+		}
+	}
+}
+
+// inspect 样例生成的 docker run 命令重新解析后应得到相同的 ContainerSpec
+func TestRunRoundTrip(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "inspect", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, multiline := range []bool{false, true} {
+			name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+			if multiline {
+				name += "/multiline"
+			}
+			t.Run(name, func(t *testing.T) {
+				configs, err := parseContainerConfigs(data)
+				if err != nil {
+					t.Fatalf("failed to parse %s: %v", fixture, err)
+				}
+				specs := convert.NewSpecs(configs)
+
+				var lines []string
+				for _, spec := range specs {
+					line, err := convert.Run(spec, convert.Format{Shell: convert.ShellPosix, Multiline: multiline})
+					if err != nil {
+						t.Fatal(err)
+					}
+					lines = append(lines, line)
+				}
+				text := strings.Join(lines, "\n")
+
+				commands, connects, err := splitDockerRunCommands(text)
+				if err != nil {
+					t.Fatalf("failed to split commands: %v\n%s", err, text)
+				}
+				var parsed []*convert.ContainerSpec
+				for _, args := range commands {
+					result, err := parseDockerRun(args)
+					if err != nil {
+						t.Fatalf("failed to parse %q: %v", args, err)
+					}
+					parsed = append(parsed, result.Spec)
+				}
+				if _, err := applyNetworkConnects(parsed, connects); err != nil {
+					t.Fatal(err)
+				}
+
+				normalizeRoundTrip(specs)
+				normalizeRoundTrip(parsed)
+				expected, err := convert.SpecJSON(specs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual, err := convert.SpecJSON(parsed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if actual != expected {
+					t.Errorf("spec changed after run round trip\n%s\n--- got ---\n%s\n--- want ---\n%s", text, actual, expected)
+				}
+			})
+		}
+	}
+}
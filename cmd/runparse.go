@@ -0,0 +1,1154 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 解析后的 docker run 命令
+type parsedRunCommand struct {
+	Name string
+	Spec *convert.ContainerSpec
+	// --env-file 指定的文件，由调用方读取
+	EnvFiles []string
+	Warnings []string
+}
+
+// shell 分词结果，Operator 为 ;、&&、|| 等命令分隔符
+type shellWord struct {
+	Text     string
+	Operator bool
+}
+
+var shellNameChar = regexp.MustCompile(`^[A-Za-z0-9_]`)
+
+// 按 POSIX shell 规则分词，支持单引号、双引号、反斜杠转义与续行
+// 结果中的 $ 保留 compose 的变量插值语义：单引号或转义中的 $ 写成 $$，
+// 未加引号或双引号中的 $VAR 由 compose 从环境变量读取，$(pwd) 转换为 ${PWD}
+func splitShellWords(text string) ([]shellWord, error) {
+	var words []shellWord
+	var current strings.Builder
+	inWord := false
+	runes := []rune(text)
+
+	flush := func() {
+		if inWord {
+			words = append(words, shellWord{Text: current.String()})
+			current.Reset()
+			inWord = false
+		}
+	}
+	// 命令替换只支持 $(pwd) 与 `pwd`
+	substitute := func(i int) (int, bool) {
+		rest := string(runes[i:])
+		for _, form := range []string{"$(pwd)", "$PWD", "${PWD}", "`pwd`"} {
+			if strings.HasPrefix(rest, form) && (form != "$PWD" || !shellNameChar.MatchString(strings.TrimPrefix(rest, form))) {
+				current.WriteString("${PWD}")
+				return i + len([]rune(form)), true
+			}
+		}
+		return i, false
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) {
+				next := runes[i+1]
+				i += 2
+				switch next {
+				case '\n':
+					// 续行，不会产生新的单词
+				case '$':
+					inWord = true
+					current.WriteString("$$")
+				default:
+					inWord = true
+					current.WriteRune(next)
+				}
+				continue
+			}
+			inWord = true
+			i++
+		case r == '\'':
+			inWord = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			current.WriteString(strings.ReplaceAll(string(runes[i+1:end]), "$", "$$"))
+			i = end + 1
+		case r == '"':
+			inWord = true
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated double quote")
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`\n", runes[i+1]) {
+					switch runes[i+1] {
+					case '\n':
+					case '$':
+						current.WriteString("$$")
+					default:
+						current.WriteRune(runes[i+1])
+					}
+					i += 2
+					continue
+				}
+				if next, ok := substitute(i); ok {
+					i = next
+					continue
+				}
+				if runes[i] == '`' || strings.HasPrefix(string(runes[i:]), "$(") {
+					return nil, fmt.Errorf("command substitution is not supported")
+				}
+				current.WriteRune(runes[i])
+				i++
+			}
+		case r == '#' && !inWord:
+			// 注释到行尾
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == ' ' || r == '\t' || r == '\r':
+			flush()
+			i++
+		case r == '\n' || r == ';':
+			flush()
+			words = append(words, shellWord{Text: string(r), Operator: true})
+			i++
+		case r == '&' || r == '|':
+			flush()
+			operator := string(r)
+			if i+1 < len(runes) && runes[i+1] == r {
+				operator += string(r)
+			}
+			words = append(words, shellWord{Text: operator, Operator: true})
+			i += len(operator)
+		case r == '<' || r == '>' || r == '(' || r == ')':
+			return nil, fmt.Errorf("unsupported shell syntax: %c", r)
+		default:
+			if next, ok := substitute(i); ok {
+				inWord = true
+				i = next
+				continue
+			}
+			if r == '`' || strings.HasPrefix(string(runes[i:]), "$(") {
+				return nil, fmt.Errorf("command substitution is not supported")
+			}
+			inWord = true
+			current.WriteRune(r)
+			i++
+		}
+	}
+	flush()
+	return words, nil
+}
+
+// 从文本中找出所有 docker run 命令与之后连接其他网络的 docker network connect 命令，忽略其他命令与提示符
+func splitDockerRunCommands(text string) ([][]string, [][]string, error) {
+	words, err := splitShellWords(extractDockerCommandLines(text))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var commands [][]string
+	var connects [][]string
+	var current []string
+	finish := func() {
+		if args, ok := trimDockerRun(current); ok {
+			commands = append(commands, args)
+		} else if args, ok := trimNetworkConnect(current); ok {
+			connects = append(connects, args)
+		}
+		current = nil
+	}
+	for _, word := range words {
+		if word.Operator {
+			finish()
+			continue
+		}
+		current = append(current, word.Text)
+	}
+	finish()
+	return commands, connects, nil
+}
+
+// 只保留以 docker run 或 docker network connect 开头的行及其 \ 续行
+// README 等文档中的说明文字可能包含括号与撇号，不能交给 shell 分词
+func extractDockerCommandLines(text string) string {
+	var lines []string
+	continued := false
+	for _, line := range strings.Split(text, "\n") {
+		if !continued {
+			words := strings.Fields(line)
+			_, run := trimDockerRun(words)
+			_, connect := trimNetworkConnect(words)
+			if !run && !connect {
+				continue
+			}
+		}
+		lines = append(lines, line)
+		// 行尾奇数个反斜杠表示续行
+		trimmed := strings.TrimRight(line, "\r")
+		continued = (len(trimmed)-len(strings.TrimRight(trimmed, "\\")))%2 == 1
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 去掉命令开头的 $ 提示符与 sudo
+func trimPrompt(words []string) []string {
+	for len(words) > 0 && (words[0] == "$" || words[0] == "sudo") {
+		words = words[1:]
+	}
+	return words
+}
+
+// 去掉命令开头的 docker network connect，返回之后的参数
+func trimNetworkConnect(words []string) ([]string, bool) {
+	words = trimPrompt(words)
+	if len(words) >= 3 && words[0] == "docker" && words[1] == "network" && words[2] == "connect" {
+		return words[3:], true
+	}
+	return nil, false
+}
+
+// 去掉命令开头的 $ 提示符、sudo 与 docker run，返回 run 之后的参数
+func trimDockerRun(words []string) ([]string, bool) {
+	words = trimPrompt(words)
+	if len(words) >= 2 && words[0] == "docker" && words[1] == "run" {
+		return words[2:], true
+	}
+	if len(words) >= 3 && words[0] == "docker" && words[1] == "container" && words[2] == "run" {
+		return words[3:], true
+	}
+	return nil, false
+}
+
+// docker run 参数的解析方式
+type runFlagSpec struct {
+	// 布尔参数不需要值
+	Bool bool
+	// Raw 为 true 时值保留 compose 的 $ 插值语义，由 Apply 自行处理变量引用
+	Raw   bool
+	Apply func(p *runParser, value string) error
+}
+
+// 解析单个 docker run 命令的中间状态
+type runParser struct {
+	result      *parsedRunCommand
+	labels      map[string]string
+	publish     []string
+	expose      []string
+	networks    []string
+	aliases     []string
+	ipv4Address string
+	ipv6Address string
+	variables   map[string]bool
+}
+
+// docker run 参数的短名称
+var runShortFlags = map[string]string{
+	"a": "attach",
+	"c": "cpu-shares",
+	"d": "detach",
+	"e": "env",
+	"h": "hostname",
+	"i": "interactive",
+	"l": "label",
+	"m": "memory",
+	"p": "publish",
+	"P": "publish-all",
+	"q": "quiet",
+	"t": "tty",
+	"u": "user",
+	"v": "volume",
+	"w": "workdir",
+}
+
+// docker run 参数的别名
+var runFlagAliases = map[string]string{
+	"net":       "network",
+	"net-alias": "network-alias",
+	"dns-opt":   "dns-option",
+}
+
+// 支持的 docker run 参数及其对应的 ContainerSpec 字段
+var runFlagSpecs map[string]runFlagSpec
+
+func init() {
+	runFlagSpecs = map[string]runFlagSpec{
+		// 只影响 docker 客户端行为的参数，不属于容器配置；-d 与 docker compose up -d 相同，不需要提示
+		"detach":      {Bool: true, Apply: func(p *runParser, v string) error { return nil }},
+		"quiet":       {Bool: true, Apply: clientOnlyFlag("quiet")},
+		"sig-proxy":   {Bool: true, Apply: clientOnlyFlag("sig-proxy")},
+		"attach":      {Apply: clientOnlyFlag("attach")},
+		"detach-keys": {Apply: clientOnlyFlag("detach-keys")},
+		"cidfile":     {Apply: clientOnlyFlag("cidfile")},
+		"pull": {Apply: func(p *runParser, v string) error {
+			p.warn("pull policy %s is not part of the container configuration", v)
+			return nil
+		}},
+
+		"name":       {Apply: func(p *runParser, v string) error { p.result.Name = v; p.spec().Name = v; return nil }},
+		"platform":   {Apply: func(p *runParser, v string) error { p.spec().Platform = v; return nil }},
+		"restart":    {Apply: (*runParser).setRestart},
+		"rm":         {Bool: true, Apply: func(p *runParser, v string) error { p.spec().AutoRemove = v == "true"; return nil }},
+		"entrypoint": {Apply: func(p *runParser, v string) error { p.spec().Entrypoint = []string{v}; return nil }},
+		"workdir":    {Apply: func(p *runParser, v string) error { p.spec().WorkingDir = v; return nil }},
+		"user":       {Apply: func(p *runParser, v string) error { p.spec().User = v; return nil }},
+		"hostname":   {Apply: func(p *runParser, v string) error { p.spec().Hostname = v; return nil }},
+		"domainname": {Apply: func(p *runParser, v string) error { p.spec().Domainname = v; return nil }},
+		"interactive": {Bool: true, Apply: func(p *runParser, v string) error {
+			p.spec().Interactive = v == "true"
+			return nil
+		}},
+		"tty": {Bool: true, Apply: func(p *runParser, v string) error { p.spec().Tty = v == "true"; return nil }},
+
+		// 端口在全部解析后统一排序合并
+		"publish":     {Apply: func(p *runParser, v string) error { p.publish = append(p.publish, v); return nil }},
+		"publish-all": {Bool: true, Apply: func(p *runParser, v string) error { p.spec().PublishAll = v == "true"; return nil }},
+		"expose":      {Apply: func(p *runParser, v string) error { p.expose = append(p.expose, v); return nil }},
+
+		// 环境变量与标签
+		"env":      {Raw: true, Apply: (*runParser).addEnv},
+		"env-file": {Apply: func(p *runParser, v string) error { p.result.EnvFiles = append(p.result.EnvFiles, v); return nil }},
+		"label": {Apply: func(p *runParser, v string) error {
+			key, value, _ := strings.Cut(v, "=")
+			setMapValue(&p.labels, key, value)
+			return nil
+		}},
+		"annotation": {Apply: func(p *runParser, v string) error {
+			key, value, _ := strings.Cut(v, "=")
+			setMapValue(&p.spec().Annotations, key, value)
+			return nil
+		}},
+
+		// 挂载
+		"volume": {Raw: true, Apply: (*runParser).addVolume},
+		"mount":  {Raw: true, Apply: (*runParser).addMount},
+		"tmpfs": {Apply: func(p *runParser, v string) error {
+			target, options, _ := strings.Cut(v, ":")
+			p.spec().Mounts = append(p.spec().Mounts, convert.MountSpec{Type: "tmpfs", Target: target, Options: options})
+			return nil
+		}},
+		"volumes-from": {Apply: func(p *runParser, v string) error {
+			p.spec().VolumesFrom = append(p.spec().VolumesFrom, v)
+			return nil
+		}},
+
+		// 网络
+		"network":       {Apply: func(p *runParser, v string) error { p.networks = append(p.networks, v); return nil }},
+		"network-alias": {Apply: func(p *runParser, v string) error { p.aliases = append(p.aliases, v); return nil }},
+		"ip":            {Apply: func(p *runParser, v string) error { p.ipv4Address = v; return nil }},
+		"ip6":           {Apply: func(p *runParser, v string) error { p.ipv6Address = v; return nil }},
+		"link": {Apply: func(p *runParser, v string) error {
+			// 与 NewSpec 相同，统一为 name:alias
+			name, alias, ok := strings.Cut(v, ":")
+			if !ok {
+				alias = name
+			}
+			p.network().Links = append(p.network().Links, name+":"+alias)
+			return nil
+		}},
+		"dns": {Apply: func(p *runParser, v string) error { p.network().DNS = append(p.network().DNS, v); return nil }},
+		"dns-search": {Apply: func(p *runParser, v string) error {
+			p.network().DNSSearch = append(p.network().DNSSearch, v)
+			return nil
+		}},
+		"dns-option": {Apply: func(p *runParser, v string) error {
+			p.network().DNSOptions = append(p.network().DNSOptions, v)
+			return nil
+		}},
+		"add-host": {Apply: func(p *runParser, v string) error {
+			p.network().ExtraHosts = append(p.network().ExtraHosts, v)
+			return nil
+		}},
+		"mac-address": {Apply: func(p *runParser, v string) error { p.network().MacAddress = v; return nil }},
+
+		// 安全
+		"privileged": {Bool: true, Apply: func(p *runParser, v string) error { p.spec().Privileged = v == "true"; return nil }},
+		"cap-add":    {Apply: func(p *runParser, v string) error { p.spec().CapAdd = append(p.spec().CapAdd, v); return nil }},
+		"cap-drop":   {Apply: func(p *runParser, v string) error { p.spec().CapDrop = append(p.spec().CapDrop, v); return nil }},
+		"security-opt": {Apply: func(p *runParser, v string) error {
+			p.spec().SecurityOpt = append(p.spec().SecurityOpt, v)
+			return nil
+		}},
+		"read-only": {Bool: true, Apply: func(p *runParser, v string) error { p.spec().ReadOnly = v == "true"; return nil }},
+		"init":      {Bool: true, Apply: func(p *runParser, v string) error { p.spec().Init = v == "true"; return nil }},
+		"device":    {Apply: (*runParser).addDevice},
+		"gpus":      {Apply: (*runParser).addGpus},
+		"group-add": {Apply: func(p *runParser, v string) error { p.spec().GroupAdd = append(p.spec().GroupAdd, v); return nil }},
+		"ulimit":    {Apply: (*runParser).addUlimit},
+		"sysctl": {Apply: func(p *runParser, v string) error {
+			key, value, _ := strings.Cut(v, "=")
+			setMapValue(&p.spec().Sysctls, key, value)
+			return nil
+		}},
+
+		// 资源限制，内存统一换算为字节
+		"cpus": {Apply: func(p *runParser, v string) error {
+			cpus, err := strconv.ParseFloat(v, 64)
+			p.resources().CPUs = cpus
+			return err
+		}},
+		"cpu-shares":         {Apply: func(p *runParser, v string) error { return parseRunInt(v, &p.resources().CPUShares) }},
+		"cpuset-cpus":        {Apply: func(p *runParser, v string) error { p.resources().CpusetCpus = v; return nil }},
+		"cpu-period":         {Apply: func(p *runParser, v string) error { return parseRunInt(v, &p.resources().CPUPeriod) }},
+		"cpu-quota":          {Apply: func(p *runParser, v string) error { return parseRunInt(v, &p.resources().CPUQuota) }},
+		"memory":             {Apply: func(p *runParser, v string) error { return parseRunBytes(v, &p.resources().Memory) }},
+		"memory-reservation": {Apply: func(p *runParser, v string) error { return parseRunBytes(v, &p.resources().MemoryReservation) }},
+		"memory-swap":        {Apply: func(p *runParser, v string) error { return parseRunBytes(v, &p.resources().MemorySwap) }},
+		"shm-size": {Apply: func(p *runParser, v string) error {
+			if err := parseRunBytes(v, &p.resources().ShmSize); err != nil {
+				return err
+			}
+			// 与 NewSpec 相同，默认大小不输出
+			if p.resources().ShmSize == convert.DefaultShmSize {
+				p.resources().ShmSize = 0
+			}
+			return nil
+		}},
+		"kernel-memory": {Apply: func(p *runParser, v string) error { return parseRunBytes(v, &p.resources().KernelMemory) }},
+		"pids-limit":    {Apply: func(p *runParser, v string) error { return parseRunInt(v, &p.resources().PidsLimit) }},
+		"oom-score-adj": {Apply: func(p *runParser, v string) error {
+			value, err := strconv.Atoi(v)
+			p.resources().OomScoreAdj = value
+			return err
+		}},
+		"oom-kill-disable": {Bool: true, Apply: func(p *runParser, v string) error {
+			p.resources().OomKillDisable = v == "true"
+			return nil
+		}},
+		"storage-opt": {Apply: func(p *runParser, v string) error {
+			key, value, _ := strings.Cut(v, "=")
+			setMapValue(&p.spec().StorageOpt, key, value)
+			return nil
+		}},
+		"blkio-weight": {Apply: func(p *runParser, v string) error {
+			weight, err := strconv.ParseUint(v, 10, 16)
+			p.blkio().Weight = uint16(weight)
+			return err
+		}},
+		"blkio-weight-device": {Apply: (*runParser).addBlkioWeightDevice},
+		"device-read-bps": {Apply: func(p *runParser, v string) error {
+			return addBlkioLimit(&p.blkio().DeviceReadBps, v, true)
+		}},
+		"device-write-bps": {Apply: func(p *runParser, v string) error {
+			return addBlkioLimit(&p.blkio().DeviceWriteBps, v, true)
+		}},
+		"device-read-iops": {Apply: func(p *runParser, v string) error {
+			return addBlkioLimit(&p.blkio().DeviceReadIOps, v, false)
+		}},
+		"device-write-iops": {Apply: func(p *runParser, v string) error {
+			return addBlkioLimit(&p.blkio().DeviceWriteIOps, v, false)
+		}},
+
+		// 命名空间，docker 的默认值不输出
+		"ipc": {Apply: func(p *runParser, v string) error {
+			if v != "private" && v != "shareable" {
+				p.spec().Ipc = v
+			}
+			return nil
+		}},
+		"pid":    {Apply: func(p *runParser, v string) error { p.spec().Pid = v; return nil }},
+		"uts":    {Apply: func(p *runParser, v string) error { p.spec().Uts = v; return nil }},
+		"userns": {Apply: func(p *runParser, v string) error { p.spec().Userns = v; return nil }},
+		"runtime": {Apply: func(p *runParser, v string) error {
+			if v != "runc" {
+				p.spec().Runtime = v
+			}
+			return nil
+		}},
+		"cgroup-parent": {Apply: func(p *runParser, v string) error { p.spec().CgroupParent = v; return nil }},
+		"cgroupns": {Apply: func(p *runParser, v string) error {
+			if v != "private" {
+				p.spec().Cgroupns = v
+			}
+			return nil
+		}},
+
+		// 日志
+		"log-driver": {Apply: func(p *runParser, v string) error { p.logging().Driver = v; return nil }},
+		"log-opt": {Apply: func(p *runParser, v string) error {
+			key, value, _ := strings.Cut(v, "=")
+			setMapValue(&p.logging().Options, key, value)
+			return nil
+		}},
+
+		// 停止
+		"stop-signal": {Apply: func(p *runParser, v string) error { p.spec().StopSignal = v; return nil }},
+		"stop-timeout": {Apply: func(p *runParser, v string) error {
+			timeout, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			p.spec().StopTimeout = &timeout
+			return nil
+		}},
+
+		// 健康检查，时长与 NewSpec 一样使用 Go 的时长格式
+		"health-cmd": {Apply: func(p *runParser, v string) error {
+			p.healthcheck().Test = []string{"CMD-SHELL", v}
+			return nil
+		}},
+		"health-interval":     {Apply: func(p *runParser, v string) error { return parseRunDuration(v, &p.healthcheck().Interval) }},
+		"health-timeout":      {Apply: func(p *runParser, v string) error { return parseRunDuration(v, &p.healthcheck().Timeout) }},
+		"health-start-period": {Apply: func(p *runParser, v string) error { return parseRunDuration(v, &p.healthcheck().StartPeriod) }},
+		"health-start-interval": {Apply: func(p *runParser, v string) error {
+			return parseRunDuration(v, &p.healthcheck().StartInterval)
+		}},
+		"health-retries": {Apply: func(p *runParser, v string) error {
+			retries, err := strconv.Atoi(v)
+			p.healthcheck().Retries = retries
+			return err
+		}},
+		"no-healthcheck": {Bool: true, Apply: func(p *runParser, v string) error {
+			if v == "true" {
+				p.healthcheck().Test = []string{"NONE"}
+			}
+			return nil
+		}},
+	}
+}
+
+// 只影响 docker 客户端的参数直接跳过并提示
+func clientOnlyFlag(name string) func(p *runParser, value string) error {
+	return func(p *runParser, value string) error {
+		p.warn("--%s only affects the docker client and is skipped", name)
+		return nil
+	}
+}
+
+func (p *runParser) spec() *convert.ContainerSpec {
+	return p.result.Spec
+}
+
+func (p *runParser) network() *convert.NetworkSpec {
+	return &p.result.Spec.Network
+}
+
+func (p *runParser) resources() *convert.ResourceSpec {
+	return &p.result.Spec.Resources
+}
+
+func (p *runParser) logging() *convert.LoggingSpec {
+	if p.result.Spec.Logging == nil {
+		p.result.Spec.Logging = &convert.LoggingSpec{}
+	}
+	return p.result.Spec.Logging
+}
+
+func (p *runParser) blkio() *convert.BlkioSpec {
+	if p.result.Spec.Resources.Blkio == nil {
+		p.result.Spec.Resources.Blkio = &convert.BlkioSpec{}
+	}
+	return p.result.Spec.Resources.Blkio
+}
+
+func (p *runParser) healthcheck() *convert.HealthcheckSpec {
+	if p.result.Spec.Healthcheck == nil {
+		p.result.Spec.Healthcheck = &convert.HealthcheckSpec{}
+	}
+	return p.result.Spec.Healthcheck
+}
+
+func (p *runParser) warn(format string, args ...interface{}) {
+	p.result.Warnings = append(p.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+func setMapValue(m *map[string]string, key string, value string) {
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+	(*m)[key] = value
+}
+
+func parseRunInt(value string, target *int64) error {
+	number, err := strconv.ParseInt(value, 10, 64)
+	*target = number
+	return err
+}
+
+// 内存大小，例如 512m，-1 表示不限制
+func parseRunBytes(value string, target *int64) error {
+	if value == "-1" {
+		*target = -1
+		return nil
+	}
+	bytes, err := units.RAMInBytes(value)
+	*target = bytes
+	return err
+}
+
+func parseRunDuration(value string, target *string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*target = duration.String()
+	return nil
+}
+
+// 重启策略，例如 on-failure:3，no 与 NewSpec 一样为空
+func (p *runParser) setRestart(value string) error {
+	policy, retries, hasRetries := strings.Cut(value, ":")
+	if hasRetries {
+		count, err := strconv.Atoi(retries)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			value = policy
+		}
+	}
+	if policy == "no" {
+		value = ""
+	}
+	p.spec().Restart = value
+	return nil
+}
+
+// $VAR 或 ${VAR} 形式的 shell 变量
+var shellVariable = regexp.MustCompile(`^\$(\{[^}]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+// 整个值只是一个变量引用，例如 -e TOKEN=$TOKEN
+var shellVariableValue = regexp.MustCompile(`^\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))$`)
+
+// 分词结果保留 compose 的 $ 插值语义，ContainerSpec 中的值都是字面值：$$ 还原为 $，
+// 其余变量无法在转换时展开，原样保留并给出警告
+func (p *runParser) literal(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' {
+			builder.WriteByte(value[i])
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == '$' {
+			builder.WriteByte('$')
+			i++
+			continue
+		}
+		if variable := shellVariable.FindString(value[i:]); variable != "" && !p.variables[variable] {
+			if p.variables == nil {
+				p.variables = make(map[string]bool)
+			}
+			p.variables[variable] = true
+			p.warn("variable %s cannot be expanded and is kept literally", variable)
+		}
+		builder.WriteByte('$')
+	}
+	return builder.String()
+}
+
+// -e KEY 表示从运行 docker 的环境中继承，-e KEY=$VAR 记录为变量引用
+func (p *runParser) addEnv(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	env := convert.EnvSpec{Name: p.literal(key)}
+	switch match := shellVariableValue.FindStringSubmatch(val); {
+	case !ok:
+	case match != nil:
+		env.Ref = firstNonEmpty(match[1], match[2])
+	default:
+		val = p.literal(val)
+		env.Value = &val
+	}
+	// 同名变量后面的值生效
+	spec := p.spec()
+	spec.Env = slices.DeleteFunc(spec.Env, func(item convert.EnvSpec) bool { return item.Name == env.Name })
+	spec.Env = append(spec.Env, env)
+	return nil
+}
+
+// 挂载来源：$(pwd) 开头的路径转换为相对路径
+func (p *runParser) mountSource(source string) string {
+	if rest, ok := strings.CutPrefix(source, "${PWD}"); ok {
+		source = "." + rest
+	}
+	return p.literal(source)
+}
+
+// -v [source:]target[:options]
+func (p *runParser) addVolume(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	item := convert.MountSpec{Type: "volume"}
+	if len(parts) == 1 {
+		item.Target = p.literal(parts[0])
+	} else {
+		item.Source = p.mountSource(parts[0])
+		item.Target = p.literal(parts[1])
+		if !convert.IsVolumeName(item.Source) {
+			item.Type = "bind"
+		}
+	}
+	if len(parts) > 2 {
+		var options []string
+		for _, option := range strings.Split(p.literal(parts[2]), ",") {
+			switch option {
+			case "ro":
+				item.ReadOnly = true
+			case "rw", "":
+			default:
+				options = append(options, option)
+			}
+		}
+		item.Options = strings.Join(options, ",")
+	}
+	p.spec().Mounts = append(p.spec().Mounts, item)
+	return nil
+}
+
+// --mount 的值按 CSV 解析，例如 type=bind,"source=/a,b",target=/data
+func (p *runParser) addMount(value string) error {
+	fields, err := csv.NewReader(strings.NewReader(value)).Read()
+	if err != nil {
+		return err
+	}
+	item := convert.MountSpec{Type: "volume"}
+	var options []string
+	for _, field := range fields {
+		key, val, ok := strings.Cut(field, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case "type":
+			item.Type = val
+		case "source", "src":
+			item.Source = p.mountSource(val)
+		case "target", "destination", "dst":
+			item.Target = p.literal(val)
+		case "readonly", "ro":
+			readOnly := !ok || val == "1" || val == "true"
+			item.ReadOnly = readOnly
+		case "volume-driver":
+			if val != "local" {
+				item.Driver = val
+			}
+		case "volume-nocopy":
+			if !ok || val == "1" || val == "true" {
+				options = append(options, "nocopy")
+			}
+		case "bind-propagation":
+			options = append(options, val)
+		case "tmpfs-size":
+			options = append(options, "size="+val)
+		case "tmpfs-mode":
+			options = append(options, "mode="+val)
+		default:
+			p.warn("mount option %s is not supported and is skipped", key)
+		}
+	}
+	if item.Target == "" {
+		return fmt.Errorf("mount %q has no target", value)
+	}
+	switch item.Type {
+	case "bind", "volume":
+	case "tmpfs":
+		item.Source, item.ReadOnly = "", false
+	default:
+		return fmt.Errorf("mount type %s is not supported", item.Type)
+	}
+	item.Options = strings.Join(options, ",")
+	p.spec().Mounts = append(p.spec().Mounts, item)
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// --device /dev/sda[:/dev/xvda[:rwm]]，容器内路径可以省略
+func (p *runParser) addDevice(value string) error {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return fmt.Errorf("invalid device %q", value)
+	}
+	device := convert.DeviceSpec{HostPath: parts[0], ContainerPath: parts[0]}
+	switch {
+	case len(parts) == 3:
+		device.ContainerPath, device.Permissions = parts[1], parts[2]
+	case len(parts) == 2 && strings.Trim(parts[1], "rwm") == "":
+		device.Permissions = parts[1]
+	case len(parts) == 2:
+		device.ContainerPath = parts[1]
+	}
+	// 与 NewSpec 相同，默认的 rwm 为空
+	if device.Permissions == "rwm" {
+		device.Permissions = ""
+	}
+	p.spec().Devices = append(p.spec().Devices, device)
+	return nil
+}
+
+// --gpus all、--gpus 2 或 CSV 形式，例如 --gpus '"device=0,1",capabilities=utility'，与 docker 的解析规则相同
+func (p *runParser) addGpus(value string) error {
+	fields, err := csv.NewReader(strings.NewReader(value)).Read()
+	if err != nil {
+		return fmt.Errorf("invalid gpus %q: %v", value, err)
+	}
+	request := convert.DeviceRequestSpec{}
+	hasCount := false
+	var capabilities []string
+	for _, field := range fields {
+		key, val, hasValue := strings.Cut(field, "=")
+		if !hasValue {
+			key, val = "count", key
+		}
+		switch key {
+		case "driver":
+			request.Driver = val
+		case "count":
+			hasCount = true
+			if val == "all" {
+				request.Count = -1
+			} else if request.Count, err = strconv.Atoi(val); err != nil {
+				return fmt.Errorf("invalid gpus count %q", val)
+			}
+		case "device":
+			request.DeviceIDs = strings.Split(val, ",")
+		case "capabilities":
+			capabilities = strings.Split(val, ",")
+		case "options":
+			options, err := csv.NewReader(strings.NewReader(val)).Read()
+			if err != nil {
+				return fmt.Errorf("invalid gpus options %q: %v", val, err)
+			}
+			for _, option := range options {
+				key, val, _ := strings.Cut(option, "=")
+				setMapValue(&request.Options, key, val)
+			}
+		default:
+			return fmt.Errorf("unexpected key %q in gpus %q", key, value)
+		}
+	}
+	// 与 docker 相同，没有指定数量与设备时为 1 个，gpu 能力放在最后
+	if !hasCount && request.DeviceIDs == nil {
+		request.Count = 1
+	}
+	request.Capabilities = append(capabilities, "gpu")
+	p.spec().DeviceRequests = append(p.spec().DeviceRequests, request)
+	return nil
+}
+
+// --blkio-weight-device /dev/sda:200
+func (p *runParser) addBlkioWeightDevice(value string) error {
+	path, weightValue, ok := strings.Cut(value, ":")
+	weight, err := strconv.ParseUint(weightValue, 10, 16)
+	if !ok || err != nil {
+		return fmt.Errorf("invalid blkio weight device %q", value)
+	}
+	p.blkio().WeightDevice = append(p.blkio().WeightDevice, convert.BlkioWeightSpec{Path: path, Weight: uint16(weight)})
+	return nil
+}
+
+// --device-read-bps /dev/sda:1mb 与 --device-read-iops /dev/sda:1000，bps 可以使用 kb、mb 等单位
+func addBlkioLimit(target *[]convert.BlkioLimitSpec, value string, bytes bool) error {
+	path, rateValue, ok := strings.Cut(value, ":")
+	if !ok || path == "" {
+		return fmt.Errorf("invalid device rate %q", value)
+	}
+	var rate uint64
+	if bytes {
+		size, err := units.RAMInBytes(rateValue)
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid device rate %q", value)
+		}
+		rate = uint64(size)
+	} else {
+		var err error
+		if rate, err = strconv.ParseUint(rateValue, 10, 64); err != nil {
+			return fmt.Errorf("invalid device rate %q", value)
+		}
+	}
+	*target = append(*target, convert.BlkioLimitSpec{Path: path, Rate: rate})
+	return nil
+}
+
+// --ulimit nofile=1024:2048
+func (p *runParser) addUlimit(value string) error {
+	name, limits, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("invalid ulimit %q", value)
+	}
+	softValue, hardValue, hasHard := strings.Cut(limits, ":")
+	if !hasHard {
+		hardValue = softValue
+	}
+	soft, err := strconv.ParseInt(softValue, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ulimit %q", value)
+	}
+	hard, err := strconv.ParseInt(hardValue, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ulimit %q", value)
+	}
+	p.spec().Ulimits = append(p.spec().Ulimits, convert.UlimitSpec{Name: name, Soft: soft, Hard: hard})
+	return nil
+}
+
+// 解析 docker run 之后的参数，第一个非参数的值为镜像，其后为容器命令
+func parseDockerRun(args []string) (*parsedRunCommand, error) {
+	p := &runParser{result: &parsedRunCommand{Spec: &convert.ContainerSpec{}}}
+
+	i := 0
+	apply := func(name string, value string, hasValue bool, flag string) error {
+		if alias, ok := runFlagAliases[name]; ok {
+			name = alias
+		}
+		spec, ok := runFlagSpecs[name]
+		if !ok {
+			return fmt.Errorf("unsupported docker run flag: %s", flag)
+		}
+		if spec.Bool {
+			enabled := true
+			if hasValue {
+				var err error
+				if enabled, err = strconv.ParseBool(value); err != nil {
+					return fmt.Errorf("invalid value %q for %s", value, flag)
+				}
+			}
+			value = strconv.FormatBool(enabled)
+		} else if !hasValue {
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag needs an argument: %s", flag)
+			}
+			i++
+			value = args[i]
+		}
+		if !spec.Raw {
+			value = p.literal(value)
+		}
+		if err := spec.Apply(p, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", value, flag, err)
+		}
+		return nil
+	}
+
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if long, ok := strings.CutPrefix(arg, "--"); ok {
+			name, value, hasValue := strings.Cut(long, "=")
+			if err := apply(name, value, hasValue, "--"+name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if short, ok := strings.CutPrefix(arg, "-"); ok && short != "" {
+			// 组合的短参数，例如 -dit 或 -p8080:80
+			for j := 0; j < len(short); j++ {
+				flag := "-" + short[j:j+1]
+				name, ok := runShortFlags[short[j:j+1]]
+				if !ok {
+					return nil, fmt.Errorf("unsupported docker run flag: %s", flag)
+				}
+				if spec := runFlagSpecs[name]; spec.Bool || j == len(short)-1 {
+					if err := apply(name, "", false, flag); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				if err := apply(name, strings.TrimPrefix(short[j+1:], "="), true, flag); err != nil {
+					return nil, err
+				}
+				break
+			}
+			continue
+		}
+		break
+	}
+
+	if i >= len(args) {
+		return nil, fmt.Errorf("image is required")
+	}
+	spec := p.spec()
+	spec.Image = p.literal(args[i])
+	for _, word := range args[i+1:] {
+		spec.Command = append(spec.Command, p.literal(word))
+	}
+	if p.result.Name == "" {
+		p.result.Name = imageServiceName(spec.Image)
+	}
+	if err := p.finish(); err != nil {
+		return nil, err
+	}
+	return p.result, nil
+}
+
+// 全部参数解析后按 NewSpec 的规则整理，使同一个容器的命令与 inspect 结果生成相同的 ContainerSpec
+func (p *runParser) finish() error {
+	spec := p.spec()
+	var err error
+	if spec.Ports, spec.Expose, err = convert.ParsePorts(p.publish, p.expose); err != nil {
+		return fmt.Errorf("invalid port: %v", err)
+	}
+	spec.Mounts = convert.SortMounts(spec.Mounts)
+	spec.Labels, spec.Compose = convert.SpecLabels(p.labels)
+
+	if logging := spec.Logging; logging != nil {
+		if logging.Driver == "json-file" {
+			logging.Driver = ""
+		}
+		if logging.Driver == "" && len(logging.Options) == 0 {
+			spec.Logging = nil
+		}
+	}
+	if health := spec.Healthcheck; health != nil && len(health.Test) == 0 {
+		p.warn("health check options without --health-cmd are skipped")
+		spec.Healthcheck = nil
+	}
+	return p.finishNetwork()
+}
+
+// 网络参数需要在全部解析后处理：别名与固定 IP 只能用于第一个自定义网络
+func (p *runParser) finishNetwork() error {
+	network := p.network()
+	var aliases []string
+	for _, alias := range p.aliases {
+		// 与 NewSpec 相同，去掉 docker 自动生成的容器名称与 service 名称别名
+		if alias != p.spec().Name && alias != p.spec().ServiceName() {
+			aliases = append(aliases, alias)
+		}
+	}
+	hasEndpoint := len(p.aliases) > 0 || p.ipv4Address != "" || p.ipv6Address != ""
+
+	for i, name := range p.networks {
+		userDefined := name != "default" && name != "bridge" && name != "host" && name != "none" && !strings.HasPrefix(name, "container:")
+		switch {
+		case i == 0 && userDefined:
+			network.Mode = name
+			// docker run 只能连接已经存在的网络
+			network.Networks = append(network.Networks, convert.NetworkAttachment{
+				Name:        name,
+				Aliases:     aliases,
+				IPv4Address: p.ipv4Address,
+				IPv6Address: p.ipv6Address,
+				External:    true,
+			})
+		case i == 0:
+			if hasEndpoint {
+				return fmt.Errorf("--network-alias and --ip require a user-defined network")
+			}
+			network.Mode = name
+		case userDefined && network.Networks != nil:
+			network.Networks = append(network.Networks, convert.NetworkAttachment{Name: name, External: true})
+		default:
+			return fmt.Errorf("network %s cannot be combined with other networks", name)
+		}
+	}
+	if len(p.networks) == 0 && hasEndpoint {
+		return fmt.Errorf("--network-alias and --ip require a user-defined network")
+	}
+	if network.Mode == "" || network.Mode == "default" {
+		network.Mode = "bridge"
+	}
+	// 与 NewSpec 相同，按名称排序
+	slices.SortStableFunc(network.Networks, func(a, b convert.NetworkAttachment) int { return strings.Compare(a.Name, b.Name) })
+	return nil
+}
+
+// 将 docker network connect 连接的网络加入对应的容器，返回无法处理的命令的警告
+// 参数保留 compose 的 $ 插值语义，与 docker run 的参数一样还原为字面值
+func applyNetworkConnects(specs []*convert.ContainerSpec, connects [][]string) ([]string, error) {
+	var warnings []string
+	for _, args := range connects {
+		attachment := convert.NetworkAttachment{External: true}
+		var positional []string
+		for i := 0; i < len(args); i++ {
+			arg := strings.ReplaceAll(args[i], "$$", "$")
+			name, value, hasValue := strings.Cut(arg, "=")
+			if !strings.HasPrefix(name, "-") {
+				positional = append(positional, arg)
+				continue
+			}
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("docker network connect: flag %s needs an argument", name)
+				}
+				i++
+				value = strings.ReplaceAll(args[i], "$$", "$")
+			}
+			switch name {
+			case "--alias":
+				attachment.Aliases = append(attachment.Aliases, value)
+			case "--ip":
+				attachment.IPv4Address = value
+			case "--ip6":
+				attachment.IPv6Address = value
+			default:
+				warnings = append(warnings, fmt.Sprintf("docker network connect %s is not supported and is skipped", name))
+			}
+		}
+		if len(positional) != 2 {
+			return nil, fmt.Errorf("docker network connect requires a network and a container")
+		}
+		attachment.Name = positional[0]
+
+		index := slices.IndexFunc(specs, func(spec *convert.ContainerSpec) bool { return spec.Name == positional[1] })
+		if index < 0 {
+			warnings = append(warnings, fmt.Sprintf("docker network connect %s %s: container is not in the input and is skipped", positional[0], positional[1]))
+			continue
+		}
+		spec := specs[index]
+		// 与 NewSpec 相同，默认 bridge 网络的容器连接其他网络时也记录 bridge 网络
+		if len(spec.Network.Networks) == 0 {
+			if spec.Network.Mode != "bridge" {
+				warnings = append(warnings, fmt.Sprintf("%s: network %s can only be added to a container on a bridge or user-defined network and is skipped", spec.Name, attachment.Name))
+				continue
+			}
+			spec.Network.Networks = []convert.NetworkAttachment{{Name: "bridge"}}
+		}
+		if slices.ContainsFunc(spec.Network.Networks, func(a convert.NetworkAttachment) bool { return a.Name == attachment.Name }) {
+			warnings = append(warnings, fmt.Sprintf("%s: network %s is already connected and is skipped", spec.Name, attachment.Name))
+			continue
+		}
+		// 与 NewSpec 相同，去掉 docker 自动生成的容器名称别名
+		attachment.Aliases = slices.DeleteFunc(attachment.Aliases, func(alias string) bool {
+			return alias == spec.Name || alias == spec.ServiceName()
+		})
+		spec.Network.Networks = append(spec.Network.Networks, attachment)
+		slices.SortStableFunc(spec.Network.Networks, func(a, b convert.NetworkAttachment) int { return strings.Compare(a.Name, b.Name) })
+	}
+	return warnings, nil
+}
+
+var composeServiceInvalid = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// 没有 --name 时使用镜像名称作为 service 名称，例如 library/nginx:1.25 -> nginx
+func imageServiceName(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	name = path.Base(name)
+	if i := strings.LastIndex(name, ":"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.Trim(composeServiceInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		name = "app"
+	}
+	return name
+}
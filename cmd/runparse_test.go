@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// README 中的说明文字不参与分词，只解析 docker run 与 docker network connect 命令
+func TestSplitDockerRunCommandsFromReadme(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "run", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, connects, err := splitDockerRunCommands(string(data))
+	if err != nil {
+		t.Fatalf("splitDockerRunCommands: %v", err)
+	}
+	expected := [][]string{
+		{"-d", "--name", "db", "--network", "shopnet", "-e", "POSTGRES_PASSWORD=s3cr3t", "-v", "pgdata:/var/lib/postgresql/data", "postgres:16"},
+		{"--rm", "-it", "--name", "web", "-p", "8080:80", "--network", "shopnet", "nginx:1.25"},
+	}
+	if !slices.EqualFunc(commands, expected, slices.Equal) {
+		t.Errorf("commands = %q, want %q", commands, expected)
+	}
+	if want := [][]string{{"bridge", "web"}}; !slices.EqualFunc(connects, want, slices.Equal) {
+		t.Errorf("connects = %q, want %q", connects, want)
+	}
+}
+
+// compose 无法表示的参数都需要提示
+func TestRunComposeWarnsDroppedFlags(t *testing.T) {
+	commands, connects, err := splitDockerRunCommands("docker run --rm --kernel-memory 64m --cidfile /tmp/id --sig-proxy=false -d --name web nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, warnings, err := getRunComposeYaml(commands, connects, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, flag := range []string{"--rm", "--kernel-memory", "--cidfile", "--sig-proxy"} {
+		if !slices.ContainsFunc(warnings, func(warning string) bool { return strings.Contains(warning, flag+" ") }) {
+			t.Errorf("no warning for %s in %q", flag, warnings)
+		}
+	}
+	if slices.ContainsFunc(warnings, func(warning string) bool { return strings.Contains(warning, "-d ") }) {
+		t.Errorf("unexpected warning for -d in %q", warnings)
+	}
+}
+
+// 默认 bridge 网络的容器也可以连接其他网络，bridge 网络保留
+func TestNetworkConnectOnBridge(t *testing.T) {
+	commands, connects, err := splitDockerRunCommands("docker run -d --name web nginx\ndocker network connect --alias www backend web\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseDockerRun(commands[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := applyNetworkConnects([]*convert.ContainerSpec{parsed.Spec}, connects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+	var names []string
+	for _, attachment := range parsed.Spec.Network.Networks {
+		names = append(names, attachment.Name)
+	}
+	if want := []string{"backend", "bridge"}; parsed.Spec.Network.Mode != "bridge" || !slices.Equal(names, want) {
+		t.Errorf("network = %s %q, want bridge %q", parsed.Spec.Network.Mode, names, want)
+	}
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// systemd 中调用的 docker 路径
+const systemdDockerPath = "/usr/bin/docker"
+
+// 由 systemd 接管的参数，不再传给 docker run
+var systemdManagedFlags = []string{"-d", "--restart", "--rm", "--name"}
+
+// docker 重启策略对应的 systemd Restart 值
+func getSystemdRestart(spec *convert.ContainerSpec) string {
+	policy, _, _ := strings.Cut(spec.Restart, ":")
+	switch policy {
+	case "always", "unless-stopped":
+		return "always"
+	case "on-failure":
+		return "on-failure"
+	}
+	return "no"
+}
+
+// 生成包装 docker run 的 systemd 单元文件
+// envFile 不为空时通过 EnvironmentFile= 读取 --redact 替换掉的密钥，-e NAME=${VAR} 由 systemd 展开
+func getSystemdUnit(spec *convert.ContainerSpec, envFile string) string {
+	name := spec.Name
+	cmd := convert.RunCommand(spec).Without(systemdManagedFlags...)
+
+	// 以前台方式运行，systemd 才能跟踪容器进程，每个参数单独一行
+	// 连接多个网络时先创建容器并连接其余网络，再以前台方式启动
+	run := "run"
+	if len(cmd.Connects) > 0 {
+		run = "create"
+	}
+	lines := []string{joinSystemd(systemdDockerPath, run, "--rm", "--name", name)}
+	for _, flag := range cmd.SortedFlags() {
+		lines = append(lines, joinSystemdFlag(flag))
+	}
+	lines = append(lines, joinSystemd(append([]string{cmd.Image}, cmd.Args...)...))
+
+	var unit strings.Builder
+	fmt.Fprintf(&unit, "# %s.service\n", name)
+	unit.WriteString("[Unit]\n")
+	fmt.Fprintf(&unit, "Description=%s container\n", name)
+	unit.WriteString("After=docker.service network-online.target\n")
+	unit.WriteString("Wants=network-online.target\n")
+	unit.WriteString("Requires=docker.service\n")
+	if policy, retries, _ := strings.Cut(spec.Restart, ":"); policy == "on-failure" && retries != "" {
+		fmt.Fprintf(&unit, "StartLimitBurst=%s\n", retries)
+	}
+	unit.WriteString("\n[Service]\n")
+	unit.WriteString("TimeoutStartSec=0\n")
+	fmt.Fprintf(&unit, "Restart=%s\n", getSystemdRestart(spec))
+	writeSystemdEnvFile(&unit, cmd, envFile)
+	fmt.Fprintf(&unit, "ExecStartPre=-%s rm -f %s\n", systemdDockerPath, quoteSystemd(name))
+	if spec.Image != "" {
+		fmt.Fprintf(&unit, "ExecStartPre=%s pull %s\n", systemdDockerPath, quoteSystemd(spec.Image))
+	}
+	if len(cmd.Connects) == 0 {
+		fmt.Fprintf(&unit, "ExecStart=%s\n", strings.Join(lines, " \\\n    "))
+	} else {
+		fmt.Fprintf(&unit, "ExecStartPre=%s\n", strings.Join(lines, " \\\n    "))
+		for _, connect := range cmd.Connects {
+			fmt.Fprintf(&unit, "ExecStartPre=%s\n", joinSystemd(append([]string{systemdDockerPath}, connect[1:]...)...))
+		}
+		fmt.Fprintf(&unit, "ExecStart=%s start -a %s\n", systemdDockerPath, quoteSystemd(name))
+	}
+	fmt.Fprintf(&unit, "ExecStop=%s stop %s\n", systemdDockerPath, quoteSystemd(name))
+	unit.WriteString("\n[Install]\n")
+	unit.WriteString("WantedBy=multi-user.target\n")
+
+	return unit.String()
+}
+
+// docker run 参数对应的 Quadlet 键
+var quadletKeys = map[string]string{
+	"--name":                "ContainerName",
+	"-p":                    "PublishPort",
+	"-v":                    "Volume",
+	"--tmpfs":               "Tmpfs",
+	"-e":                    "Environment",
+	"--label":               "Label",
+	"--device":              "AddDevice",
+	"--cap-add":             "AddCapability",
+	"--cap-drop":            "DropCapability",
+	"--group-add":           "GroupAdd",
+	"--workdir":             "WorkingDir",
+	"--hostname":            "HostName",
+	"--network":             "Network",
+	"--ip":                  "IP",
+	"--ip6":                 "IP6",
+	"--dns":                 "DNS",
+	"--dns-search":          "DNSSearch",
+	"--dns-option":          "DNSOption",
+	"--add-host":            "AddHost",
+	"--ulimit":              "Ulimit",
+	"--sysctl":              "Sysctl",
+	"--shm-size":            "ShmSize",
+	"--pids-limit":          "PidsLimit",
+	"--log-driver":          "LogDriver",
+	"--userns":              "UserNS",
+	"--stop-signal":         "StopSignal",
+	"--stop-timeout":        "StopTimeout",
+	"--entrypoint":          "Entrypoint",
+	"--health-cmd":          "HealthCmd",
+	"--health-interval":     "HealthInterval",
+	"--health-timeout":      "HealthTimeout",
+	"--health-retries":      "HealthRetries",
+	"--health-start-period": "HealthStartPeriod",
+}
+
+// 生成 Podman Quadlet 的 .container 文件
+// Quadlet 没有对应键的参数（例如 --cpus、--memory）通过 PodmanArgs 传递
+// Environment= 不展开变量，引用变量的 -e 放在 PodmanArgs 中，由 systemd 从 EnvironmentFile= 读取后展开
+func getQuadletUnit(spec *convert.ContainerSpec, envFile string) string {
+	name := spec.Name
+	cmd := convert.RunCommand(spec).Without("-d", "--rm", "--restart")
+
+	var container strings.Builder
+	var podmanArgs []string
+	for _, flag := range cmd.SortedFlags() {
+		flagName, value := flag.Name(), flag.Value()
+		if flag.Ref != "" {
+			podmanArgs = append(podmanArgs, joinSystemdFlag(flag))
+			continue
+		}
+		if key, ok := quadletKeys[flagName]; ok {
+			fmt.Fprintf(&container, "%s=%s\n", key, quoteQuadlet(value))
+			continue
+		}
+		switch flagName {
+		case "--user":
+			user, group, hasGroup := strings.Cut(value, ":")
+			fmt.Fprintf(&container, "User=%s\n", quoteQuadlet(user))
+			if hasGroup {
+				fmt.Fprintf(&container, "Group=%s\n", quoteQuadlet(group))
+			}
+		case "--read-only":
+			container.WriteString("ReadOnly=true\n")
+		case "--init":
+			container.WriteString("RunInit=true\n")
+		case "--no-healthcheck":
+			container.WriteString("HealthCmd=none\n")
+		case "--security-opt":
+			switch {
+			case value == "no-new-privileges" || value == "no-new-privileges:true":
+				container.WriteString("NoNewPrivileges=true\n")
+			case value == "label=disable":
+				container.WriteString("SecurityLabelDisable=true\n")
+			case strings.HasPrefix(value, "seccomp="):
+				fmt.Fprintf(&container, "SeccompProfile=%s\n", quoteQuadlet(strings.TrimPrefix(value, "seccomp=")))
+			default:
+				podmanArgs = append(podmanArgs, joinSystemd(flag.Args...))
+			}
+		default:
+			podmanArgs = append(podmanArgs, joinSystemd(flag.Args...))
+		}
+	}
+	// 其余网络直接写为 Network=，别名与地址使用 podman 的网络选项
+	for _, attachment := range spec.Network.Networks {
+		if attachment.Name != spec.Network.Mode {
+			fmt.Fprintf(&container, "Network=%s\n", quoteQuadlet(quadletNetwork(attachment)))
+		}
+	}
+	if len(cmd.Args) > 0 {
+		fmt.Fprintf(&container, "Exec=%s\n", joinSystemd(cmd.Args...))
+	}
+	if len(podmanArgs) > 0 {
+		fmt.Fprintf(&container, "PodmanArgs=%s\n", strings.Join(podmanArgs, " "))
+	}
+
+	var unit strings.Builder
+	fmt.Fprintf(&unit, "# %s.container\n", name)
+	unit.WriteString("[Unit]\n")
+	fmt.Fprintf(&unit, "Description=%s container\n", name)
+	unit.WriteString("\n[Container]\n")
+	fmt.Fprintf(&unit, "Image=%s\n", quoteQuadlet(cmd.Image))
+	unit.WriteString(container.String())
+	unit.WriteString("\n[Service]\n")
+	fmt.Fprintf(&unit, "Restart=%s\n", getSystemdRestart(spec))
+	writeSystemdEnvFile(&unit, cmd, envFile)
+	unit.WriteString("\n[Install]\n")
+	unit.WriteString("WantedBy=multi-user.target default.target\n")
+
+	return unit.String()
+}
+
+// podman 的 --network name:alias=a,ip=10.0.0.2 格式
+func quadletNetwork(attachment convert.NetworkAttachment) string {
+	var options []string
+	for _, alias := range attachment.Aliases {
+		options = append(options, "alias="+alias)
+	}
+	if attachment.IPv4Address != "" {
+		options = append(options, "ip="+attachment.IPv4Address)
+	}
+	if attachment.IPv6Address != "" {
+		options = append(options, "ip6="+attachment.IPv6Address)
+	}
+	if len(options) == 0 {
+		return attachment.Name
+	}
+	return attachment.Name + ":" + strings.Join(options, ",")
+}
+
+// 命令中有引用变量的参数时，由 EnvironmentFile= 提供变量的值
+func writeSystemdEnvFile(unit *strings.Builder, cmd *convert.Command, envFile string) {
+	if envFile == "" {
+		return
+	}
+	for _, flag := range cmd.Flags {
+		if flag.Ref != "" {
+			fmt.Fprintf(unit, "EnvironmentFile=%s\n", quoteQuadlet(envFile))
+			return
+		}
+	}
+}
+
+// 与 joinSystemd 相同，引用变量的部分保留为 ${VAR}，由 systemd 展开
+func joinSystemdFlag(flag convert.Flag) string {
+	if flag.Ref == "" {
+		return joinSystemd(flag.Args...)
+	}
+	last := len(flag.Args) - 1
+	prefix := strings.TrimSuffix(flag.Args[last], "${"+flag.Ref+"}")
+	return joinSystemd(append(flag.Args[:last:last], prefix)...) + "${" + flag.Ref + "}"
+}
+
+// 转义后用空格连接
+func joinSystemd(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteSystemd(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemd 命令行（ExecStart=、Quadlet 的 Exec= 与 PodmanArgs=）转义：% 与 $ 需要写两次以免被当作说明符和变量展开
+func quoteSystemd(arg string) string {
+	return quoteUnitValue(strings.NewReplacer("%", "%%", "$", "$$").Replace(arg))
+}
+
+// Quadlet 普通键的转义：Environment=、Label=、Volume= 等不展开 $，只有 % 说明符需要写两次
+func quoteQuadlet(arg string) string {
+	return quoteUnitValue(strings.ReplaceAll(arg, "%", "%%"))
+}
+
+// 包含空白或引号时使用双引号包裹
+func quoteUnitValue(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\r\n'\"\\;") {
+		return arg
+	}
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t", "\r", "\\r")
+	return "\"" + replacer.Replace(arg) + "\""
+}
@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/helson-lin/doke/pkg/convert"
+)
+
+// 生成 kreuzwerker/docker provider 的资源定义，同时返回无法转换的配置的警告
+// 镜像、网络与卷按名称去重，容器通过引用依赖这些资源
+func getTerraformConfig(specs []*convert.ContainerSpec, resources *convert.DockerResources) (string, []string) {
+	var w hclWriter
+	var warnings []string
+	images := make(map[string]string)
+	networks := make(map[string]string)
+	volumes := make(map[string]string)
+
+	w.Open("terraform")
+	w.Open("required_providers")
+	w.Attr("docker", map[string]string{"source": "kreuzwerker/docker"})
+	w.Close()
+	w.Close()
+	// 通过 -var 或 TF_VAR_<name> 提供
+	w.Variables(envRefs(specs...), true)
+
+	for _, spec := range specs {
+		if image := spec.Image; image != "" && images[image] == "" {
+			id := hclIdentifier(uniqueTerraformName(images, image))
+			images[image] = id
+			w.Blank()
+			w.Open("resource", "docker_image", id)
+			w.Attr("name", image)
+			w.Raw("keep_locally", "true")
+			w.Close()
+		}
+
+		for _, attachment := range spec.Network.Networks {
+			name := attachment.Name
+			if isSystemNetwork(name) || networks[name] != "" {
+				continue
+			}
+			id := hclIdentifier(name)
+			networks[name] = id
+			w.Blank()
+			network, ok := getNetworkResource(name, resources)
+			if ok {
+				w.Comment("terraform import docker_network.%s %s", id, network.ID)
+			} else if attachment.ID != "" {
+				w.Comment("terraform import docker_network.%s %s", id, attachment.ID)
+			}
+			w.Open("resource", "docker_network", id)
+			w.Attr("name", name)
+			if ok {
+				w.Attr("driver", network.Driver)
+				w.Attr("options", network.Options)
+				w.Attr("internal", network.Internal)
+				w.Attr("attachable", network.Attachable)
+				w.Attr("ipv6", network.EnableIPv6)
+				for _, ipam := range network.IPAM.Config {
+					w.Open("ipam_config")
+					w.Attr("subnet", ipam.Subnet)
+					w.Attr("gateway", ipam.Gateway)
+					w.Attr("ip_range", ipam.IPRange)
+					w.Close()
+				}
+			}
+			w.Close()
+		}
+
+		for _, mount := range spec.Mounts {
+			if mount.Type != "volume" || mount.Source == "" || volumes[mount.Source] != "" {
+				continue
+			}
+			id := hclIdentifier(mount.Source)
+			volumes[mount.Source] = id
+			w.Blank()
+			w.Comment("terraform import docker_volume.%s %s", id, mount.Source)
+			w.Open("resource", "docker_volume", id)
+			w.Attr("name", mount.Source)
+			if mount.Driver != "local" {
+				w.Attr("driver", mount.Driver)
+			}
+			if vol, ok := getVolumeResource(mount.Source, resources); ok {
+				w.Attr("driver_opts", vol.Options)
+			}
+			w.Close()
+		}
+
+		containerWarnings := writeTerraformContainer(&w, spec, images, networks, volumes)
+		for _, warning := range containerWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", spec.Name, warning))
+		}
+	}
+
+	return w.String(), warnings
+}
+
+// 镜像名称转换为资源名称，不同镜像转换后重名时追加序号
+func uniqueTerraformName(existing map[string]string, image string) string {
+	name := image
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, "@")
+	used := func(candidate string) bool {
+		for _, id := range existing {
+			if id == hclIdentifier(candidate) {
+				return true
+			}
+		}
+		return false
+	}
+	candidate := name
+	for i := 2; used(candidate); i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	return candidate
+}
+
+// 写入 docker_container 资源
+func writeTerraformContainer(w *hclWriter, spec *convert.ContainerSpec, images, networks, volumes map[string]string) []string {
+	var warnings []string
+	id := hclIdentifier(spec.Name)
+
+	w.Blank()
+	if spec.ID != "" {
+		w.Comment("terraform import docker_container.%s %s", id, spec.ID)
+	}
+	w.Open("resource", "docker_container", id)
+	w.Attr("name", spec.Name)
+	w.Raw("image", fmt.Sprintf("docker_image.%s.image_id", images[spec.Image]))
+
+	if restart, retries, ok := strings.Cut(spec.Restart, ":"); restart != "" {
+		w.Attr("restart", restart)
+		if ok {
+			count, _ := strconv.Atoi(retries)
+			w.Attr("max_retry_count", count)
+		}
+	}
+	w.Attr("entrypoint", spec.Entrypoint)
+	w.Attr("command", spec.Command)
+	w.Attr("user", spec.User)
+	w.Attr("working_dir", spec.WorkingDir)
+	w.Attr("hostname", spec.Hostname)
+	w.Attr("domainname", spec.Domainname)
+	w.Attr("tty", spec.Tty)
+	w.Attr("stdin_open", spec.Interactive)
+
+	var env []string
+	for _, item := range spec.Env {
+		switch {
+		case item.Ref != "":
+			env = append(env, hclTemplate(item.Name+"=", item.Ref))
+		case item.Value != nil:
+			env = append(env, quoteHCL(item.Name+"="+*item.Value))
+		default:
+			warnings = append(warnings, fmt.Sprintf("environment variable %s is inherited from the Docker client environment and is skipped", item.Name))
+		}
+	}
+	if len(env) > 0 {
+		w.Raw("env", "["+strings.Join(env, ", ")+"]")
+	}
+
+	for _, key := range sortedKeys(spec.Labels) {
+		w.Open("labels")
+		w.Attr("label", key)
+		w.Raw("value", quoteHCL(spec.Labels[key]))
+		w.Close()
+	}
+
+	// 端口映射，端口范围展开为多个端口
+	for _, port := range spec.Ports {
+		hostStart, _, hostErr := nat.ParsePortRange(port.HostPort)
+		for offset, internal := range expandPortRange(port) {
+			w.Open("ports")
+			w.Attr("internal", internal)
+			if hostErr == nil && port.HostPort != "" {
+				w.Attr("external", int(hostStart)+offset)
+			}
+			w.Attr("ip", port.HostIP)
+			if port.Protocol != "tcp" {
+				w.Attr("protocol", port.Protocol)
+			}
+			w.Close()
+		}
+	}
+	w.Attr("publish_all_ports", spec.PublishAll)
+
+	// 挂载卷，tmpfs 挂载合并为 tmpfs 参数
+	tmpfs := make(map[string]string)
+	for _, mount := range spec.Mounts {
+		switch mount.Type {
+		case "bind":
+			w.Open("volumes")
+			w.Attr("host_path", mount.Source)
+		case "volume":
+			w.Open("volumes")
+			if volumes[mount.Source] != "" {
+				w.Raw("volume_name", fmt.Sprintf("docker_volume.%s.name", volumes[mount.Source]))
+			}
+		case "tmpfs":
+			tmpfs[mount.Target] = mount.Options
+			continue
+		default:
+			warnings = append(warnings, fmt.Sprintf("mount type %s of %s is not supported", mount.Type, mount.Target))
+			continue
+		}
+		w.Attr("container_path", mount.Target)
+		w.Attr("read_only", mount.ReadOnly)
+		w.Close()
+	}
+	for _, from := range spec.VolumesFrom {
+		source, mode, _ := strings.Cut(from, ":")
+		w.Open("volumes")
+		w.Attr("from_container", source)
+		w.Attr("read_only", mode == "ro")
+		w.Close()
+	}
+	w.Attr("tmpfs", tmpfs)
+
+	// 网络
+	switch mode := container.NetworkMode(spec.Network.Mode); {
+	case mode.IsHost(), mode.IsNone(), mode.IsContainer():
+		w.Attr("network_mode", string(mode))
+	case mode.IsBridge(), mode.IsUserDefined():
+		// 默认 bridge 网络的容器只在连接了其他网络时有 Networks，bridge 网络不需要创建
+		for _, attachment := range spec.Network.Networks {
+			w.Open("networks_advanced")
+			if id := networks[attachment.Name]; id != "" {
+				w.Raw("name", fmt.Sprintf("docker_network.%s.name", id))
+			} else {
+				w.Attr("name", attachment.Name)
+			}
+			w.Attr("aliases", attachment.Aliases)
+			w.Attr("ipv4_address", attachment.IPv4Address)
+			w.Attr("ipv6_address", attachment.IPv6Address)
+			w.Close()
+		}
+	}
+	w.Attr("dns", spec.Network.DNS)
+	w.Attr("dns_search", spec.Network.DNSSearch)
+	w.Attr("dns_opts", spec.Network.DNSOptions)
+	for _, host := range spec.Network.ExtraHosts {
+		if hostname, ip, ok := strings.Cut(host, ":"); ok {
+			w.Open("host")
+			w.Attr("host", hostname)
+			w.Attr("ip", ip)
+			w.Close()
+		}
+	}
+	if len(spec.Network.Links) > 0 {
+		warnings = append(warnings, "links are not supported, use a user-defined network instead")
+	}
+
+	// 安全
+	if len(spec.CapAdd) > 0 || len(spec.CapDrop) > 0 {
+		w.Open("capabilities")
+		w.Attr("add", spec.CapAdd)
+		w.Attr("drop", spec.CapDrop)
+		w.Close()
+	}
+	w.Attr("privileged", spec.Privileged)
+	w.Attr("read_only", spec.ReadOnly)
+	w.Attr("init", spec.Init)
+	w.Attr("security_opts", spec.SecurityOpt)
+	w.Attr("group_add", spec.GroupAdd)
+	for _, device := range spec.Devices {
+		w.Open("devices")
+		w.Attr("host_path", device.HostPath)
+		w.Attr("container_path", device.ContainerPath)
+		w.Attr("permissions", firstNonEmpty(device.Permissions, "rwm"))
+		w.Close()
+	}
+	for _, ulimit := range spec.Ulimits {
+		w.Open("ulimit")
+		w.Attr("name", ulimit.Name)
+		w.Raw("soft", strconv.FormatInt(ulimit.Soft, 10))
+		w.Raw("hard", strconv.FormatInt(ulimit.Hard, 10))
+		w.Close()
+	}
+	w.Attr("sysctls", spec.Sysctls)
+
+	// 资源限制
+	resources := spec.Resources
+	w.Attr("cpu_shares", resources.CPUShares)
+	w.Attr("cpu_set", resources.CpusetCpus)
+	if resources.CPUs > 0 {
+		warnings = append(warnings, fmt.Sprintf("cpus %s is not supported, use cpu_shares instead", strconv.FormatFloat(resources.CPUs, 'f', -1, 64)))
+	}
+	w.Attr("memory", megabytes(resources.Memory))
+	if resources.MemorySwap > 0 {
+		w.Attr("memory_swap", megabytes(resources.MemorySwap))
+	}
+	if resources.MemoryReservation > 0 {
+		warnings = append(warnings, "memory reservation is not supported")
+	}
+	if resources.ShmSize > 0 {
+		w.Attr("shm_size", megabytes(resources.ShmSize))
+	}
+	if resources.PidsLimit > 0 {
+		warnings = append(warnings, "pids limit is not supported")
+	}
+
+	// 命名空间
+	w.Attr("ipc_mode", spec.Ipc)
+	w.Attr("pid_mode", spec.Pid)
+	w.Attr("userns_mode", spec.Userns)
+	w.Attr("runtime", spec.Runtime)
+
+	// 日志
+	if spec.Logging != nil {
+		w.Attr("log_driver", spec.Logging.Driver)
+		w.Attr("log_opts", spec.Logging.Options)
+	}
+
+	w.Attr("stop_signal", spec.StopSignal)
+	if spec.StopTimeout != nil {
+		w.Attr("stop_timeout", *spec.StopTimeout)
+	}
+
+	// 健康检查
+	if health := spec.Healthcheck; health != nil && len(health.Test) > 0 {
+		w.Open("healthcheck")
+		w.Attr("test", health.Test)
+		w.Attr("interval", health.Interval)
+		w.Attr("timeout", health.Timeout)
+		w.Attr("start_period", health.StartPeriod)
+		w.Attr("retries", health.Retries)
+		w.Close()
+	}
+
+	w.Close()
+	return warnings
+}
@@ -87,9 +87,9 @@ func T(key string, args ...interface{}) string {
 		}
 	}
 
-	// 如果都没找到，返回key本身
+	// 如果都没找到，返回key本身；key 不是格式字符串，参数直接附加在后面
 	if len(args) > 0 {
-		return fmt.Sprintf(key, args...)
+		return fmt.Sprintf("%s %v", key, args)
 	}
 	return key
 }
@@ -103,16 +103,78 @@ var enTranslations = map[string]string{
 	"inspect.recent_logs":        "📜 Recent logs:",
 
 	// Command conversion
-	"command.short":             "Convert Docker container to docker run command",
-	"command.long":              "Convert running Docker container to equivalent docker run command or generate Docker Compose configuration file",
-	"command.flag.json":         "Export docker compose file",
-	"command.compose_confirm":   "Write Docker Compose configuration to file %s? (y/n): ",
-	"command.compose_cancelled": "User cancelled operation.",
-	"command.compose_written":   "Docker Compose configuration successfully written to file: %s",
-	"completion.short":          "Generate the autocompletion script for the specified shell",
-	"completion.long":           "Generate the autocompletion script for the specified shell.\nSee each sub-command's help for details on how to use the generated script.",
-	"help.short":                "Help about any command",
-	"help.long":                 "Help provides help for any command in the application.",
+	"command.short":                  "Convert Docker container to docker run command",
+	"command.long":                   "Convert running Docker container to equivalent docker run command or generate Docker Compose configuration file",
+	"command.flag.json":              "Export docker compose file",
+	"command.compose_confirm":        "Write Docker Compose configuration to file %s? (y/n): ",
+	"command.compose_cancelled":      "User cancelled operation.",
+	"command.compose_written":        "Docker Compose configuration successfully written to file: %s",
+	"command.flag.keep_defaults":     "Keep settings inherited from the image (env, labels, cmd, healthcheck, etc.)",
+	"command.image_defaults_skipped": "⚠️  Failed to inspect image, image defaults are kept: %v",
+	"command.flag.compose":           "Export one docker compose file for all selected containers",
+	"command.flag.label":             "Select containers by label (key or key=value), can be repeated",
+	"command.flag.project":           "Select containers of a docker compose project",
+	"command.flag.shell":             "Quote the generated command for this shell: posix or powershell",
+	"command.flag.multiline":         "Print the generated command over multiple lines grouped by category",
+	"command.flag.from_file":         "Convert docker inspect JSON from a file (- for stdin) without contacting Docker",
+	"command.invalid_inspect":        "entry %d is not a container inspect object",
+	"command.flag.format":            "Output format: run, compose, k8s, systemd, quadlet, ansible, terraform, nomad, spec-json or spec-yaml",
+	"command.unsupported_format":     "unsupported format: %s",
+	"command.warning":                "⚠️  %s",
+	"command.no_container":           "requires at least one container id, --label or --project",
+	"command.no_container_matched":   "no container matches: %s",
+	"command.drift_title":            "⚠️  Service %s has drifted from %s (file -> running):",
+	"command.drift_failed":           "⚠️  Failed to compare with the original compose file: %v",
+	"command.resources_skipped":      "⚠️  Failed to inspect networks and volumes, they are declared as external: %v",
+	"command.flag.redact":            "Replace secret-looking env vars with ${VAR} references and write their values to --redact-file",
+	"command.flag.redact_file":       "File that receives the redacted values, defaults to .env next to the output (created with 0600 permissions)",
+	"command.flag.anonymize":         "Also replace host paths, IP addresses and hostnames for sharing (implies --redact)",
+	"command.flag.sort_env":          "Sort environment variables by name instead of keeping the container order",
+	"command.flag.merge":             "Merge the exported services into an existing compose file, keeping comments and other services",
+	"command.merge_confirm":          "Apply these changes to %s? (y/n): ",
+	"command.merge_unchanged":        "✅ %s is already up to date",
+	"command.merge_written":          "✅ Updated %s",
+	"command.flag.output":            "Write the output to a file, or one file per container into a directory (ending with /); - writes to stdout",
+	"command.flag.yes":               "Answer yes to all confirmation prompts",
+	"command.flag.force":             "Overwrite existing output files without asking",
+	"command.overwrite_confirm":      "%s already exists, overwrite? (y/n): ",
+	"command.output_exists":          "%s already exists, use --force to overwrite",
+	"command.outputs_exist":          "%s already exist, use --force to overwrite, nothing was written",
+	"command.output_written":         "✅ Wrote %s",
+	"command.not_interactive":        "stdin is not a terminal, use --yes to confirm",
+	"command.flag.all":               "Export every container on the host, one file per container plus index.json (default output directory: doke-export)",
+	"command.flag.running":           "Only select running containers",
+	"command.flag.name_regex":        "Only select containers whose name matches this regular expression",
+	"command.flag.jobs":              "Number of containers inspected concurrently",
+	"command.all_needs_directory":    "--all writes one file per container, -o must be a directory and cannot be combined with --merge",
+	"command.redacted":               "🔒 Moved %d secret(s) to %s",
+	"convert.short":                  "Convert between docker run commands and compose files",
+	"convert.long":                   "Convert container definitions between formats. With --from and --to any input (container, inspect, run, compose) can be written in any output format; the subcommands convert directly between docker run commands and compose files",
+	"convert.run_to_compose.short":   "Convert docker run commands into a Docker Compose file",
+	"convert.run_to_compose.long":    "Parse one or more docker run commands (from the arguments or a file such as a README) and write an equivalent Docker Compose file.\nNetworks used by the commands are declared as external, named volumes keep their names.",
+	"convert.flag.file":              "Read docker run commands from a file, other lines are ignored",
+	"convert.flag.project":           "Compose project name, also used as the file name",
+	"convert.no_input":               "requires a docker run command or --file",
+	"convert.no_command":             "no docker run command found",
+	"convert.invalid_command":        "docker run command %d: %v",
+	"convert.compose_to_run.short":   "Convert a Docker Compose file into docker commands",
+	"convert.compose_to_run.long":    "Print the docker network create, docker volume create and docker run commands that reproduce the services of a compose file.\nServices are ordered by depends_on, variables are read from .env and the environment, services outside the active profiles are skipped.\nServices given as arguments are converted together with their dependencies.",
+	"convert.flag.compose_file":      "Compose file, defaults to compose.yaml or docker-compose.yml in the current directory",
+	"convert.flag.compose_project":   "Project name used for containers, networks and volumes",
+	"convert.flag.env_file":          "Variables file used for interpolation, defaults to .env next to the compose file",
+	"convert.flag.profile":           "Enable services of a profile, can be repeated",
+	"convert.flag.from":              "Input format: container, inspect, run or compose",
+	"convert.flag.to":                "Output format: run, compose, k8s, systemd, quadlet, ansible, terraform, nomad, spec-json or spec-yaml",
+	"convert.flag.input_file":        "Input file: docker inspect JSON (- for stdin), a file with docker run commands, or a compose file",
+	"export.short":                   "Export containers together with the resources they need",
+	"export.long":                    "Export the configuration of containers together with their networks, volumes and images",
+	"export.host.short":              "Generate a script that rebuilds every container on the host",
+	"export.host.long":               "Print one idempotent shell script that recreates the networks (driver, subnet, gateway, options), named volumes (driver, options), pulls images pinned by digest and creates the containers in dependency order (network_mode container:, links, volumes-from).\nNetworks, volumes, images and containers that already exist are skipped, so the script can be run again after a partial failure.",
+	"export.digests_skipped":         "⚠️  Failed to look up image digests, images are pulled by tag: %v",
+	"completion.short":               "Generate the autocompletion script for the specified shell",
+	"completion.long":                "Generate the autocompletion script for the specified shell.\nSee each sub-command's help for details on how to use the generated script.",
+	"help.short":                     "Help about any command",
+	"help.long":                      "Help provides help for any command in the application.",
 
 	// Proxy command
 	"proxy.short":                  "Automatically set Docker image source address",
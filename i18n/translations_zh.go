@@ -103,17 +103,79 @@ var zhTranslations = map[string]string{
 	"inspect.recent_logs":        "📜 最近日志:",
 
 	// 命令转换
-	"command.short":             "将 Docker 容器转换为 docker run 命令",
-	"command.long":              "将运行中的 Docker 容器转换为等效的 docker run 命令或生成 Docker Compose 配置文件",
-	"command.flag.json":         "导出 docker compose 文件",
-	"command.compose_confirm":   "是否将 Docker Compose 配置写入文件 %s？(y/n): ",
-	"command.compose_cancelled": "用户取消操作。",
-	"command.completion":        "为指定的shell生成自动补全脚本",
-	"command.compose_written":   "Docker Compose 配置已成功写入文件: %s",
-	"completion.short":          "为指定的shell生成自动补全脚本",
-	"completion.long":           "为指定的shell生成自动补全脚本。\n有关如何使用生成的脚本的详细信息，请参阅每个子命令的帮助。",
-	"help.short":                "显示任何命令的帮助信息",
-	"help.long":                 "显示任何命令的帮助信息。",
+	"command.short":                  "将 Docker 容器转换为 docker run 命令",
+	"command.long":                   "将运行中的 Docker 容器转换为等效的 docker run 命令或生成 Docker Compose 配置文件",
+	"command.flag.json":              "导出 docker compose 文件",
+	"command.compose_confirm":        "是否将 Docker Compose 配置写入文件 %s？(y/n): ",
+	"command.compose_cancelled":      "用户取消操作。",
+	"command.completion":             "为指定的shell生成自动补全脚本",
+	"command.compose_written":        "Docker Compose 配置已成功写入文件: %s",
+	"command.flag.keep_defaults":     "保留从镜像继承的配置（环境变量、标签、命令、健康检查等）",
+	"command.image_defaults_skipped": "⚠️  获取镜像信息失败，将保留镜像默认配置: %v",
+	"command.flag.compose":           "将所有选中的容器导出到同一个 docker compose 文件",
+	"command.flag.label":             "按标签选择容器（key 或 key=value），可重复指定",
+	"command.flag.project":           "选择 docker compose 项目中的容器",
+	"command.flag.shell":             "按指定 shell 的规则转义生成的命令：posix 或 powershell",
+	"command.flag.multiline":         "按分类多行输出生成的命令",
+	"command.flag.from_file":         "从 docker inspect 的 JSON 文件转换（- 表示标准输入），无需连接 Docker",
+	"command.invalid_inspect":        "第 %d 项不是容器的 inspect 信息",
+	"command.flag.format":            "输出格式：run、compose、k8s、systemd、quadlet、ansible、terraform、nomad、spec-json 或 spec-yaml",
+	"command.unsupported_format":     "不支持的输出格式: %s",
+	"command.warning":                "⚠️  %s",
+	"command.no_container":           "至少需要指定一个容器 ID、--label 或 --project",
+	"command.no_container_matched":   "没有匹配的容器: %s",
+	"command.drift_title":            "⚠️  服务 %s 与 %s 不一致（文件 -> 运行中）:",
+	"command.drift_failed":           "⚠️  无法与原始 compose 文件进行对比: %v",
+	"command.resources_skipped":      "⚠️  获取网络与卷信息失败，将声明为外部资源: %v",
+	"command.flag.redact":            "将疑似密钥的环境变量替换为 ${VAR} 引用，真实的值写入 --redact-file",
+	"command.flag.redact_file":       "保存密钥的文件，默认为输出文件所在目录的 .env（权限为 0600）",
+	"command.flag.anonymize":         "同时替换主机路径、IP 地址与主机名，便于分享（包含 --redact）",
+	"command.flag.sort_env":          "环境变量按名称排序，默认保持容器中的顺序",
+	"command.flag.merge":             "将导出的 service 合并到已有的 compose 文件，保留注释与其他 service",
+	"command.merge_confirm":          "是否将以上修改写入 %s？(y/n): ",
+	"command.merge_unchanged":        "✅ %s 已是最新，无需修改",
+	"command.merge_written":          "✅ 已更新 %s",
+	"command.flag.output":            "将结果写入文件，或以 / 结尾的目录（每个容器一个文件）；- 表示输出到标准输出",
+	"command.flag.yes":               "所有确认提示都回答 yes",
+	"command.flag.force":             "直接覆盖已存在的输出文件",
+	"command.overwrite_confirm":      "%s 已存在，是否覆盖？(y/n): ",
+	"command.output_exists":          "%s 已存在，使用 --force 覆盖",
+	"command.outputs_exist":          "%s 已存在，使用 --force 覆盖，没有写入任何文件",
+	"command.output_written":         "✅ 已写入 %s",
+	"command.not_interactive":        "标准输入不是终端，请使用 --yes 确认",
+	"command.flag.all":               "导出主机上的所有容器，每个容器一个文件并生成 index.json（默认输出目录 doke-export）",
+	"command.flag.running":           "只选择运行中的容器",
+	"command.flag.name_regex":        "只选择名称匹配该正则表达式的容器",
+	"command.flag.jobs":              "同时查询的容器数量",
+	"command.all_needs_directory":    "--all 为每个容器写入单独的文件，-o 必须是目录，且不能与 --merge 同时使用",
+	"command.redacted":               "🔒 已将 %d 个密钥写入 %s",
+	"convert.short":                  "在 docker run 命令与 compose 文件之间转换",
+	"convert.long":                   "在不同格式之间转换容器定义。使用 --from 与 --to 时任意输入（container、inspect、run、compose）都可以输出为任意格式；子命令直接在 docker run 命令与 compose 文件之间转换",
+	"convert.run_to_compose.short":   "将 docker run 命令转换为 Docker Compose 文件",
+	"convert.run_to_compose.long":    "解析一个或多个 docker run 命令（来自参数或 README 等文件），生成等效的 Docker Compose 文件。\n命令中使用的网络声明为外部网络，命名卷保留原来的名称。",
+	"convert.flag.file":              "从文件中读取 docker run 命令，忽略其他内容",
+	"convert.flag.project":           "compose 项目名称，同时作为文件名",
+	"convert.no_input":               "需要提供 docker run 命令或 --file",
+	"convert.no_command":             "没有找到 docker run 命令",
+	"convert.invalid_command":        "第 %d 个 docker run 命令：%v",
+	"convert.compose_to_run.short":   "将 Docker Compose 文件转换为 docker 命令",
+	"convert.compose_to_run.long":    "输出重建 compose 文件中各个 service 所需的 docker network create、docker volume create 与 docker run 命令。\n按 depends_on 排序，变量从 .env 文件与当前环境读取，不在启用的 profile 中的 service 会被跳过。\n指定 service 时只转换这些 service 及其依赖。",
+	"convert.flag.compose_file":      "compose 文件，默认查找当前目录下的 compose.yaml 或 docker-compose.yml",
+	"convert.flag.compose_project":   "容器、网络与卷使用的项目名称",
+	"convert.flag.env_file":          "插值使用的变量文件，默认为 compose 文件所在目录下的 .env",
+	"convert.flag.profile":           "启用指定 profile 中的 service，可重复使用",
+	"convert.flag.from":              "输入格式：container、inspect、run 或 compose",
+	"convert.flag.to":                "输出格式：run、compose、k8s、systemd、quadlet、ansible、terraform、nomad、spec-json 或 spec-yaml",
+	"convert.flag.input_file":        "输入文件：docker inspect 的 JSON（- 表示标准输入）、包含 docker run 命令的文件或 compose 文件",
+	"export.short":                   "导出容器及其依赖的资源",
+	"export.long":                    "导出容器的配置，以及容器使用的网络、卷与镜像",
+	"export.host.short":              "生成重建主机上所有容器的脚本",
+	"export.host.long":               "输出一个可重复执行的 shell 脚本：创建网络（驱动、子网、网关、选项）与命名卷（驱动、选项），按 digest 拉取镜像，再按依赖顺序（network_mode container:、link、volumes-from）创建容器。\n已存在的网络、卷、镜像与容器会跳过，部分失败后可以直接重新执行。",
+	"export.digests_skipped":         "⚠️  查询镜像 digest 失败，镜像按标签拉取: %v",
+	"completion.short":               "为指定的shell生成自动补全脚本",
+	"completion.long":                "为指定的shell生成自动补全脚本。\n有关如何使用生成的脚本的详细信息，请参阅每个子命令的帮助。",
+	"help.short":                     "显示任何命令的帮助信息",
+	"help.long":                      "显示任何命令的帮助信息。",
 
 	// 代理命令
 	"proxy.short":                  "自动设置 Docker 镜像源地址",
@@ -0,0 +1,808 @@
+package convert
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"gopkg.in/yaml.v3"
+)
+
+// compose 写入容器、网络和卷的标签
+const (
+	ComposeLabelPrefix    = "com.docker.compose."
+	ComposeProjectLabel   = "com.docker.compose.project"
+	ComposeServiceLabel   = "com.docker.compose.service"
+	ComposeDependsOnLabel = "com.docker.compose.depends_on"
+	ComposeNetworkLabel   = "com.docker.compose.network"
+	ComposeVolumeLabel    = "com.docker.compose.volume"
+)
+
+// Service 是 compose 文件中的单个 service
+type Service struct {
+	Image           string                    `yaml:"image"`
+	ContainerName   string                    `yaml:"container_name,omitempty"`
+	Platform        string                    `yaml:"platform,omitempty"`
+	PullPolicy      string                    `yaml:"pull_policy,omitempty"`
+	Restart         string                    `yaml:"restart,omitempty"`
+	Entrypoint      []string                  `yaml:"entrypoint,omitempty"`
+	Command         []string                  `yaml:"command,omitempty"`
+	WorkingDir      string                    `yaml:"working_dir,omitempty"`
+	User            string                    `yaml:"user,omitempty"`
+	Hostname        string                    `yaml:"hostname,omitempty"`
+	Domainname      string                    `yaml:"domainname,omitempty"`
+	StdinOpen       bool                      `yaml:"stdin_open,omitempty"`
+	Tty             bool                      `yaml:"tty,omitempty"`
+	Ports           []string                  `yaml:"ports,omitempty"`
+	Expose          []string                  `yaml:"expose,omitempty"`
+	EnvFile         []string                  `yaml:"env_file,omitempty"`
+	Environment     []string                  `yaml:"environment,omitempty"`
+	Labels          map[string]string         `yaml:"labels,omitempty"`
+	Annotations     map[string]string         `yaml:"annotations,omitempty"`
+	Volumes         []string                  `yaml:"volumes,omitempty"`
+	VolumesFrom     []string                  `yaml:"volumes_from,omitempty"`
+	Tmpfs           []string                  `yaml:"tmpfs,omitempty"`
+	NetworkMode     string                    `yaml:"network_mode,omitempty"`
+	Networks        map[string]ServiceNetwork `yaml:"networks,omitempty"`
+	Links           []string                  `yaml:"links,omitempty"`
+	ExternalLinks   []string                  `yaml:"external_links,omitempty"`
+	DNS             []string                  `yaml:"dns,omitempty"`
+	DNSSearch       []string                  `yaml:"dns_search,omitempty"`
+	DNSOpt          []string                  `yaml:"dns_opt,omitempty"`
+	ExtraHosts      []string                  `yaml:"extra_hosts,omitempty"`
+	MacAddress      string                    `yaml:"mac_address,omitempty"`
+	Privileged      bool                      `yaml:"privileged,omitempty"`
+	CapAdd          []string                  `yaml:"cap_add,omitempty"`
+	CapDrop         []string                  `yaml:"cap_drop,omitempty"`
+	SecurityOpt     []string                  `yaml:"security_opt,omitempty"`
+	ReadOnly        bool                      `yaml:"read_only,omitempty"`
+	Init            bool                      `yaml:"init,omitempty"`
+	Devices         []string                  `yaml:"devices,omitempty"`
+	GroupAdd        []string                  `yaml:"group_add,omitempty"`
+	Ulimits         map[string]Ulimit         `yaml:"ulimits,omitempty"`
+	Sysctls         map[string]string         `yaml:"sysctls,omitempty"`
+	Cpus            string                    `yaml:"cpus,omitempty"`
+	CPUShares       int64                     `yaml:"cpu_shares,omitempty"`
+	Cpuset          string                    `yaml:"cpuset,omitempty"`
+	CPUPeriod       int64                     `yaml:"cpu_period,omitempty"`
+	CPUQuota        int64                     `yaml:"cpu_quota,omitempty"`
+	MemLimit        string                    `yaml:"mem_limit,omitempty"`
+	MemReservation  string                    `yaml:"mem_reservation,omitempty"`
+	MemswapLimit    string                    `yaml:"memswap_limit,omitempty"`
+	ShmSize         string                    `yaml:"shm_size,omitempty"`
+	PidsLimit       int64                     `yaml:"pids_limit,omitempty"`
+	OomScoreAdj     int                       `yaml:"oom_score_adj,omitempty"`
+	OomKillDisable  bool                      `yaml:"oom_kill_disable,omitempty"`
+	StorageOpt      map[string]string         `yaml:"storage_opt,omitempty"`
+	BlkioConfig     *BlkioConfig              `yaml:"blkio_config,omitempty"`
+	Ipc             string                    `yaml:"ipc,omitempty"`
+	Pid             string                    `yaml:"pid,omitempty"`
+	Uts             string                    `yaml:"uts,omitempty"`
+	UsernsMode      string                    `yaml:"userns_mode,omitempty"`
+	Runtime         string                    `yaml:"runtime,omitempty"`
+	CgroupParent    string                    `yaml:"cgroup_parent,omitempty"`
+	Cgroup          string                    `yaml:"cgroup,omitempty"`
+	Logging         *Logging                  `yaml:"logging,omitempty"`
+	StopSignal      string                    `yaml:"stop_signal,omitempty"`
+	StopGracePeriod string                    `yaml:"stop_grace_period,omitempty"`
+	DependsOn       map[string]DependsOn      `yaml:"depends_on,omitempty"`
+	HealthCheck     *HealthCheck              `yaml:"healthcheck,omitempty"`
+	Deploy          *Deploy                   `yaml:"deploy,omitempty"`
+}
+
+// Ulimit 是 service 的单个 ulimit
+type Ulimit struct {
+	Soft int64 `yaml:"soft"`
+	Hard int64 `yaml:"hard"`
+}
+
+// BlkioConfig 是 service 的块设备 IO 配置
+type BlkioConfig struct {
+	Weight          uint16              `yaml:"weight,omitempty"`
+	WeightDevice    []BlkioWeightDevice `yaml:"weight_device,omitempty"`
+	DeviceReadBps   []BlkioLimit        `yaml:"device_read_bps,omitempty"`
+	DeviceWriteBps  []BlkioLimit        `yaml:"device_write_bps,omitempty"`
+	DeviceReadIOps  []BlkioLimit        `yaml:"device_read_iops,omitempty"`
+	DeviceWriteIOps []BlkioLimit        `yaml:"device_write_iops,omitempty"`
+}
+
+// BlkioWeightDevice 是单个设备的 IO 权重
+type BlkioWeightDevice struct {
+	Path   string `yaml:"path"`
+	Weight uint16 `yaml:"weight"`
+}
+
+// BlkioLimit 是单个设备的 IO 限速
+type BlkioLimit struct {
+	Path string `yaml:"path"`
+	Rate uint64 `yaml:"rate"`
+}
+
+// Deploy 是 service 的 deploy 配置，目前只用于 GPU 等设备请求
+type Deploy struct {
+	Resources DeployResources `yaml:"resources"`
+}
+
+// DeployResources 是 deploy 中的资源配置
+type DeployResources struct {
+	Reservations *DeployReservations `yaml:"reservations,omitempty"`
+}
+
+// DeployReservations 是 deploy 中预留的资源
+type DeployReservations struct {
+	Devices []DeviceReservation `yaml:"devices,omitempty"`
+}
+
+// DeviceReservation 是单个设备请求，Count 为数量或 all
+type DeviceReservation struct {
+	Driver       string            `yaml:"driver,omitempty"`
+	Count        any               `yaml:"count,omitempty"`
+	DeviceIDs    []string          `yaml:"device_ids,omitempty"`
+	Capabilities []string          `yaml:"capabilities"`
+	Options      map[string]string `yaml:"options,omitempty"`
+}
+
+// Logging 是 service 的日志配置
+type Logging struct {
+	Driver  string            `yaml:"driver,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+}
+
+// HealthCheck 是 service 的健康检查
+type HealthCheck struct {
+	Test        []string `yaml:"test,omitempty"`
+	Disable     bool     `yaml:"disable,omitempty"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
+	// 启动期间的检查间隔
+	StartInterval string `yaml:"start_interval,omitempty"`
+}
+
+// DependsOn 是 service 的单个依赖
+type DependsOn struct {
+	Condition string `json:"condition" yaml:"condition"`
+	Restart   bool   `json:"restart,omitempty" yaml:"restart,omitempty"`
+}
+
+// ServiceNetwork 是 service 在单个网络中的配置
+type ServiceNetwork struct {
+	Aliases     []string `yaml:"aliases,omitempty"`
+	Ipv4Address string   `yaml:"ipv4_address,omitempty"`
+	Ipv6Address string   `yaml:"ipv6_address,omitempty"`
+}
+
+// ComposeResource 是顶层 networks 与 volumes 的声明
+type ComposeResource struct {
+	Name       string            `yaml:"name,omitempty"`
+	Driver     string            `yaml:"driver,omitempty"`
+	DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+	External   bool              `yaml:"external,omitempty"`
+	// 以下只用于网络
+	IPAM       *ComposeIPAM `yaml:"ipam,omitempty"`
+	EnableIPv6 bool         `yaml:"enable_ipv6,omitempty"`
+	Internal   bool         `yaml:"internal,omitempty"`
+	Attachable bool         `yaml:"attachable,omitempty"`
+}
+
+// ComposeIPAM 是网络的地址配置，容器的静态地址需要在子网内
+type ComposeIPAM struct {
+	Driver  string              `yaml:"driver,omitempty"`
+	Config  []ComposeIPAMConfig `yaml:"config,omitempty"`
+	Options map[string]string   `yaml:"options,omitempty"`
+}
+
+// ComposeIPAMConfig 是单个子网的配置
+type ComposeIPAMConfig struct {
+	Subnet       string            `yaml:"subnet,omitempty"`
+	IPRange      string            `yaml:"ip_range,omitempty"`
+	Gateway      string            `yaml:"gateway,omitempty"`
+	AuxAddresses map[string]string `yaml:"aux_addresses,omitempty"`
+}
+
+// DockerCompose 是生成的 compose 文件，compose 规范已废弃 version 字段
+type DockerCompose struct {
+	Name     string                     `yaml:"name,omitempty"`
+	Services map[string]Service         `yaml:"services"`
+	Networks map[string]ComposeResource `yaml:"networks,omitempty"`
+	Volumes  map[string]ComposeResource `yaml:"volumes,omitempty"`
+}
+
+// DockerResources 是容器引用的网络与卷的详细信息，用于生成顶层声明
+type DockerResources struct {
+	Networks map[string]types.NetworkResource
+	Volumes  map[string]volume.Volume
+}
+
+// ComposeOptions 是生成 compose 文件时使用的参数
+type ComposeOptions struct {
+	// 项目名称，为空时使用容器上的 compose 项目标签
+	Project string
+	// 网络与卷的详细信息，为 nil 时全部视为外部资源
+	Resources *DockerResources
+}
+
+// Compose 生成 compose 文件的结构，每个容器对应一个 service
+func Compose(specs []*ContainerSpec, options ComposeOptions) *DockerCompose {
+	project := options.Project
+	// 没有指定项目名称时使用容器上的 compose 项目标签
+	if project == "" {
+		project = ComposeProjectName(specs)
+	}
+
+	// 构建 Docker Compose 配置
+	compose := DockerCompose{
+		Name:     project,
+		Services: make(map[string]Service),
+	}
+
+	serviceNames := make([]string, len(specs))
+	for i, spec := range specs {
+		service, networks, volumes := ComposeService(spec, project, options.Resources)
+
+		// service 名称冲突时（例如同一 service 的多个副本）退回到容器名称，仍然冲突时加上序号
+		name := spec.ServiceName()
+		if _, exists := compose.Services[name]; exists {
+			name = service.ContainerName
+		}
+		for i, base := 2, name; ; i++ {
+			if _, exists := compose.Services[name]; !exists {
+				break
+			}
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		compose.Services[name] = service
+		serviceNames[i] = name
+
+		// 多个 service 共享的网络和卷只在顶层声明一次
+		for key, network := range networks {
+			if compose.Networks == nil {
+				compose.Networks = make(map[string]ComposeResource)
+			}
+			compose.Networks[key] = network
+		}
+		for key, vol := range volumes {
+			if compose.Volumes == nil {
+				compose.Volumes = make(map[string]ComposeResource)
+			}
+			compose.Volumes[key] = vol
+		}
+	}
+
+	// 引用的容器按名称、完整 ID 或 ID 前缀对应到导出的 service
+	ResolveComposeReferences(&compose, func(ref string) string {
+		for i, spec := range specs {
+			if ref != "" && (spec.ID != "" && strings.HasPrefix(spec.ID, ref) || spec.Name == ref) {
+				return serviceNames[i]
+			}
+		}
+		return ""
+	})
+
+	// 去掉不在本次导出范围内的依赖，否则 compose 会报错
+	for name, service := range compose.Services {
+		for dependency := range service.DependsOn {
+			if _, ok := compose.Services[dependency]; !ok {
+				delete(service.DependsOn, dependency)
+			}
+		}
+		compose.Services[name] = service
+	}
+
+	return &compose
+}
+
+// ResolveComposeReferences 将 network_mode、ipc、pid、volumes_from 与 links 中引用的容器改为对应的 service
+// service 是 resolve 返回的名称，容器不在导出范围内时 resolve 返回空字符串：
+// volumes_from 改为 container:<name>，links 移到 external_links，network_mode、ipc 与 pid 保持 container:<name>
+func ResolveComposeReferences(compose *DockerCompose, resolve func(container string) string) {
+	for name, service := range compose.Services {
+		for _, mode := range []*string{&service.NetworkMode, &service.Ipc, &service.Pid} {
+			if target, ok := strings.CutPrefix(*mode, "container:"); ok && resolve(target) != "" {
+				*mode = "service:" + resolve(target)
+			}
+		}
+
+		// 生成新的列表，service 的列表与 ContainerSpec 共用底层数组
+		var volumesFrom []string
+		for _, from := range service.VolumesFrom {
+			source, mode, _ := strings.Cut(from, ":")
+			if target := resolve(source); target != "" {
+				volumesFrom = append(volumesFrom, strings.TrimSuffix(target+":"+mode, ":"))
+			} else {
+				volumesFrom = append(volumesFrom, "container:"+from)
+			}
+		}
+		service.VolumesFrom = volumesFrom
+
+		// links 只能引用 service，其余容器改用 external_links
+		var links []string
+		externalLinks := slices.Clone(service.ExternalLinks)
+		for _, link := range service.Links {
+			source, alias, _ := strings.Cut(link, ":")
+			target := resolve(source)
+			if target == "" {
+				externalLinks = append(externalLinks, link)
+				continue
+			}
+			if alias == "" {
+				alias = source
+			}
+			links = append(links, target+":"+alias)
+		}
+		service.Links, service.ExternalLinks = links, externalLinks
+		compose.Services[name] = service
+	}
+}
+
+// ComposeNetworkWarnings 返回 compose 无法满足的网络配置：由 compose 创建、没有子网信息的网络上的静态地址
+func ComposeNetworkWarnings(compose *DockerCompose) []string {
+	var warnings []string
+	for _, name := range sortedKeys(compose.Services) {
+		for _, key := range sortedKeys(compose.Services[name].Networks) {
+			network := compose.Services[name].Networks[key]
+			resource, declared := compose.Networks[key]
+			if !declared || resource.External || resource.IPAM != nil {
+				continue
+			}
+			for _, address := range []string{network.Ipv4Address, network.Ipv6Address} {
+				if address != "" {
+					warnings = append(warnings, fmt.Sprintf("%s: static address %s on network %s needs a subnet, add ipam config to the network", name, address, key))
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// ComposeYAML 生成 compose 文件
+func ComposeYAML(specs []*ContainerSpec, options ComposeOptions) (string, error) {
+	return MarshalCompose(Compose(specs, options))
+}
+
+// MarshalCompose 输出 compose 文件并按 compose 规范校验
+func MarshalCompose(compose *DockerCompose) (string, error) {
+	yamlData, err := yaml.Marshal(compose)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	// 生成的文件不符合规范时直接报错，避免写出 docker compose 无法使用的文件
+	if err := ValidateCompose(yamlData); err != nil {
+		return "", fmt.Errorf("generated compose file is invalid: %v", err)
+	}
+
+	return string(yamlData), nil
+}
+
+// ComposeServiceName 返回 service 名称：优先使用 compose 的 service 标签，否则使用容器名称
+func ComposeServiceName(config *types.ContainerJSON) string {
+	if name := config.Config.Labels[ComposeServiceLabel]; name != "" {
+		return name
+	}
+	return strings.TrimPrefix(config.Name, "/")
+}
+
+// ComposeService 根据 ContainerSpec 构建 compose service，同时返回需要在顶层声明的网络和卷
+func ComposeService(spec *ContainerSpec, project string, resources *DockerResources) (Service, map[string]ComposeResource, map[string]ComposeResource) {
+	// 构建 Service
+	service := Service{
+		Image:         spec.Image,
+		ContainerName: spec.Name,
+		Restart:       spec.Restart,
+		Entrypoint:    composeEscapeAll(spec.Entrypoint),
+		Command:       composeEscapeAll(spec.Command),
+		WorkingDir:    composeEscape(spec.WorkingDir),
+		User:          spec.User,
+		Hostname:      spec.Hostname,
+		Domainname:    spec.Domainname,
+		StdinOpen:     spec.Interactive,
+		Tty:           spec.Tty,
+		VolumesFrom:   spec.VolumesFrom,
+		DNS:           spec.Network.DNS,
+		DNSSearch:     spec.Network.DNSSearch,
+		DNSOpt:        spec.Network.DNSOptions,
+		ExtraHosts:    spec.Network.ExtraHosts,
+		MacAddress:    spec.Network.MacAddress,
+		Links:         spec.Network.Links,
+		Privileged:    spec.Privileged,
+		CapAdd:        spec.CapAdd,
+		CapDrop:       spec.CapDrop,
+		SecurityOpt:   spec.SecurityOpt,
+		ReadOnly:      spec.ReadOnly,
+		Init:          spec.Init,
+		GroupAdd:      spec.GroupAdd,
+		Sysctls:       spec.Sysctls,
+		Ipc:           spec.Ipc,
+		Pid:           spec.Pid,
+		Uts:           spec.Uts,
+		UsernsMode:    spec.Userns,
+		Runtime:       spec.Runtime,
+		CgroupParent:  spec.CgroupParent,
+		Cgroup:        spec.Cgroupns,
+		Annotations:   composeEscapeMap(spec.Annotations),
+		StorageOpt:    composeEscapeMap(spec.StorageOpt),
+		Platform:      spec.Platform,
+		StopSignal:    spec.StopSignal,
+	}
+	if spec.StopTimeout != nil {
+		service.StopGracePeriod = fmt.Sprintf("%ds", *spec.StopTimeout)
+	}
+	if spec.Compose != nil {
+		// 复制一份，Compose 去掉导出范围外的依赖时不能修改传入的 spec
+		service.DependsOn = maps.Clone(spec.Compose.DependsOn)
+	}
+	if spec.Logging != nil {
+		service.Logging = &Logging{Driver: spec.Logging.Driver, Options: spec.Logging.Options}
+		// Redact 替换的日志选项引用 .env 中的变量，复制一份以免修改传入的 spec
+		if len(spec.Logging.OptionRefs) > 0 {
+			service.Logging.Options = maps.Clone(spec.Logging.Options)
+			for key, ref := range spec.Logging.OptionRefs {
+				service.Logging.Options[key] = "${" + ref + "}"
+			}
+		}
+	}
+	for key, value := range spec.Labels {
+		if service.Labels == nil {
+			service.Labels = make(map[string]string)
+		}
+		service.Labels[key] = composeEscape(value)
+		if ref := spec.LabelRefs[key]; ref != "" {
+			service.Labels[key] = "${" + ref + "}"
+		}
+	}
+
+	// 解析端口映射
+	for _, port := range spec.Ports {
+		service.Ports = append(service.Ports, port.String())
+	}
+	for _, port := range spec.Expose {
+		service.Expose = append(service.Expose, port.String())
+	}
+	// compose 没有 --publish-all，暴露的端口改为发布到随机端口
+	if spec.PublishAll {
+		service.Ports = append(service.Ports, service.Expose...)
+		service.Expose = nil
+	}
+
+	// 解析环境变量，从 docker 环境继承的变量改为引用 compose 的同名变量
+	// 使用 KEY=VALUE 列表保持容器中的顺序，--sort-env 时已在 Normalize 中排序
+	for _, env := range spec.Env {
+		switch {
+		case env.Ref != "":
+			service.Environment = append(service.Environment, fmt.Sprintf("%s=${%s}", env.Name, env.Ref))
+		case env.Value == nil:
+			service.Environment = append(service.Environment, fmt.Sprintf("%s=${%s}", env.Name, env.Name))
+		default:
+			service.Environment = append(service.Environment, env.Name+"="+composeEscape(*env.Value))
+		}
+	}
+
+	for _, device := range spec.Devices {
+		service.Devices = append(service.Devices, device.String())
+	}
+	composeResources(&service, spec)
+
+	// 解析挂载卷：绑定挂载、命名卷、匿名卷与 tmpfs
+	volumes := make(map[string]ComposeResource)
+	for _, mount := range spec.Mounts {
+		switch mount.Type {
+		case "tmpfs":
+			service.Tmpfs = append(service.Tmpfs, mount.TmpfsString())
+			continue
+		case "volume":
+			if mount.Source != "" {
+				key, resource := composeVolume(mount.Source, mount.Driver, mount.External, project, resources)
+				volumes[key] = resource
+				mount.Source = key
+			}
+		}
+		service.Volumes = append(service.Volumes, composeEscape(mount.String()))
+	}
+
+	// 解析网络：host、none、container:x 等模式使用 network_mode，其余网络使用 networks
+	networks := make(map[string]ComposeResource)
+	if len(spec.Network.Networks) == 0 {
+		service.NetworkMode = spec.Network.Mode
+	} else {
+		service.Networks = make(map[string]ServiceNetwork)
+		for _, attachment := range spec.Network.Networks {
+			// compose 不能同时使用默认的 bridge 网络与其他网络，ComposeWarnings 中提示
+			if attachment.Name == "bridge" && spec.Network.Mode == "bridge" {
+				continue
+			}
+			key, resource := composeNetwork(attachment.Name, attachment.External, project, resources)
+			networks[key] = resource
+			service.Networks[key] = ServiceNetwork{
+				Aliases:     attachment.Aliases,
+				Ipv4Address: attachment.IPv4Address,
+				Ipv6Address: attachment.IPv6Address,
+			}
+		}
+	}
+
+	// 解析健康检查
+	if health := spec.Healthcheck; health != nil {
+		service.HealthCheck = &HealthCheck{
+			Test:          composeEscapeAll(health.Test),
+			Interval:      health.Interval,
+			Timeout:       health.Timeout,
+			Retries:       health.Retries,
+			StartPeriod:   health.StartPeriod,
+			StartInterval: health.StartInterval,
+		}
+		if health.Test[0] == "NONE" {
+			service.HealthCheck = &HealthCheck{Disable: true}
+		}
+	}
+
+	return service, networks, volumes
+}
+
+// ComposeWarnings 返回 compose service 无法表示而被跳过的配置
+func ComposeWarnings(spec *ContainerSpec) []string {
+	var warnings []string
+	if spec.AutoRemove {
+		warnings = append(warnings, "--rm has no compose equivalent and is skipped")
+	}
+	if spec.Resources.KernelMemory != 0 {
+		warnings = append(warnings, "--kernel-memory has no compose equivalent and is skipped")
+	}
+	if spec.Network.Mode == "bridge" && len(spec.Network.Networks) > 0 {
+		warnings = append(warnings, "the default bridge network cannot be combined with other networks in compose and is skipped")
+	}
+	return warnings
+}
+
+// compose 会对所有值做变量插值，字面的 $ 需要写成 $$，只有引用的变量保留 ${VAR}
+func composeEscape(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+func composeEscapeAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = composeEscape(value)
+	}
+	return result
+}
+
+func composeEscapeMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		result[key] = composeEscape(value)
+	}
+	return result
+}
+
+// 资源限制使用 service 级别的 key，docker compose up 与 docker run 的行为一致
+// deploy.resources 无法表示 memswap、cpuset 等限制，只用于 GPU 设备请求
+func composeResources(service *Service, spec *ContainerSpec) {
+	resources := spec.Resources
+	if resources.CPUs > 0 {
+		service.Cpus = strconv.FormatFloat(resources.CPUs, 'f', -1, 64)
+	}
+	service.CPUShares = resources.CPUShares
+	service.Cpuset = resources.CpusetCpus
+	service.MemLimit = composeBytes(resources.Memory)
+	service.MemReservation = composeBytes(resources.MemoryReservation)
+	service.MemswapLimit = composeBytes(resources.MemorySwap)
+	service.ShmSize = composeBytes(resources.ShmSize)
+	service.PidsLimit = resources.PidsLimit
+	service.OomScoreAdj = resources.OomScoreAdj
+	service.CPUPeriod = resources.CPUPeriod
+	service.CPUQuota = resources.CPUQuota
+	service.OomKillDisable = resources.OomKillDisable
+
+	for _, ulimit := range spec.Ulimits {
+		if service.Ulimits == nil {
+			service.Ulimits = make(map[string]Ulimit)
+		}
+		service.Ulimits[ulimit.Name] = Ulimit{Soft: ulimit.Soft, Hard: ulimit.Hard}
+	}
+
+	if blkio := resources.Blkio; blkio != nil {
+		config := &BlkioConfig{Weight: blkio.Weight}
+		for _, device := range blkio.WeightDevice {
+			config.WeightDevice = append(config.WeightDevice, BlkioWeightDevice{Path: device.Path, Weight: device.Weight})
+		}
+		for _, limits := range []struct {
+			devices []BlkioLimitSpec
+			target  *[]BlkioLimit
+		}{
+			{blkio.DeviceReadBps, &config.DeviceReadBps},
+			{blkio.DeviceWriteBps, &config.DeviceWriteBps},
+			{blkio.DeviceReadIOps, &config.DeviceReadIOps},
+			{blkio.DeviceWriteIOps, &config.DeviceWriteIOps},
+		} {
+			for _, device := range limits.devices {
+				*limits.target = append(*limits.target, BlkioLimit{Path: device.Path, Rate: device.Rate})
+			}
+		}
+		service.BlkioConfig = config
+	}
+
+	var devices []DeviceReservation
+	for _, request := range spec.DeviceRequests {
+		device := DeviceReservation{Driver: request.Driver, DeviceIDs: request.DeviceIDs, Capabilities: request.Capabilities, Options: request.Options}
+		switch {
+		case request.Count == -1:
+			device.Count = "all"
+		case request.Count > 0:
+			device.Count = request.Count
+		}
+		devices = append(devices, device)
+	}
+	if len(devices) > 0 {
+		service.Deploy = &Deploy{Resources: DeployResources{Reservations: &DeployReservations{Devices: devices}}}
+	}
+}
+
+// 字节数转换为 compose 的大小写法，能整除时使用 k、m、g 单位
+func composeBytes(value int64) string {
+	switch {
+	case value == 0:
+		return ""
+	case value < 0:
+		return strconv.FormatInt(value, 10)
+	}
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}} {
+		if value%unit.size == 0 {
+			return fmt.Sprintf("%d%s", value/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%db", value)
+}
+
+// FilterComposeLabels 去掉 compose 内部使用的标签
+func FilterComposeLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range labels {
+		if !strings.HasPrefix(key, ComposeLabelPrefix) {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// ParseComposeDependsOn 解析 depends_on 标签，格式为 service:condition:restart，多个依赖用逗号分隔
+func ParseComposeDependsOn(value string) map[string]DependsOn {
+	dependsOn := make(map[string]DependsOn)
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if parts[0] == "" {
+			continue
+		}
+		dependency := DependsOn{Condition: "service_started"}
+		if len(parts) > 1 && parts[1] != "" {
+			dependency.Condition = parts[1]
+		}
+		if len(parts) > 2 {
+			dependency.Restart = parts[2] == "true"
+		}
+		dependsOn[parts[0]] = dependency
+	}
+	return dependsOn
+}
+
+// ComposeProjectName 在所有容器属于同一个 compose 项目时返回项目名称
+func ComposeProjectName(specs []*ContainerSpec) string {
+	var project string
+	for i, spec := range specs {
+		var name string
+		if spec.Compose != nil {
+			name = spec.Compose.Project
+		}
+		if name == "" || (i > 0 && name != project) {
+			return ""
+		}
+		project = name
+	}
+	return project
+}
+
+// 网络在 compose 文件中的 key 与顶层声明
+// 由同一个 compose 项目创建的网络使用原来的 key，Docker 中存在但不属于该项目的网络与已知的外部网络声明为 external
+func composeNetwork(name string, external bool, project string, resources *DockerResources) (string, ComposeResource) {
+	if resources != nil {
+		if network, ok := resources.Networks[name]; ok {
+			key := network.Labels[ComposeNetworkLabel]
+			if key != "" && project != "" && network.Labels[ComposeProjectLabel] == project {
+				resource := ComposeResource{
+					DriverOpts: network.Options,
+					EnableIPv6: network.EnableIPv6,
+					Internal:   network.Internal,
+					Attachable: network.Attachable,
+				}
+				if network.Driver != "bridge" {
+					resource.Driver = network.Driver
+				}
+				// 子网、网关与地址范围，docker 自动分配的子网同样写出，保证静态地址可用
+				ipam := &ComposeIPAM{Options: network.IPAM.Options}
+				if network.IPAM.Driver != "" && network.IPAM.Driver != "default" {
+					ipam.Driver = network.IPAM.Driver
+				}
+				for _, config := range network.IPAM.Config {
+					ipam.Config = append(ipam.Config, ComposeIPAMConfig{
+						Subnet:       config.Subnet,
+						IPRange:      config.IPRange,
+						Gateway:      config.Gateway,
+						AuxAddresses: config.AuxAddress,
+					})
+				}
+				if ipam.Driver != "" || len(ipam.Config) > 0 || len(ipam.Options) > 0 {
+					resource.IPAM = ipam
+				}
+				if name != project+"_"+key {
+					resource.Name = name
+				}
+				return key, resource
+			}
+			external = true
+		}
+	}
+	return composeUnknownResource(name, external, project)
+}
+
+// 卷在 compose 文件中的 key 与顶层声明，规则与网络相同
+func composeVolume(name string, driver string, external bool, project string, resources *DockerResources) (string, ComposeResource) {
+	if resources != nil {
+		if vol, ok := resources.Volumes[name]; ok {
+			key := vol.Labels[ComposeVolumeLabel]
+			if key != "" && project != "" && vol.Labels[ComposeProjectLabel] == project {
+				resource := ComposeResource{DriverOpts: vol.Options}
+				if vol.Driver != "local" {
+					resource.Driver = vol.Driver
+				}
+				if name != project+"_"+key {
+					resource.Name = name
+				}
+				return key, resource
+			}
+			external = true
+		}
+	}
+	key, resource := composeUnknownResource(name, external, project)
+	// external 的卷不能指定驱动
+	if !resource.External && driver != "" && driver != "local" {
+		resource.Driver = driver
+	}
+	return key, resource
+}
+
+// 没有 Docker 中的信息时，只有已知的外部资源声明为 external，<project>_<key> 形式的名称视为项目创建的资源
+func composeUnknownResource(name string, external bool, project string) (string, ComposeResource) {
+	if external {
+		return name, ComposeResource{Name: name, External: true}
+	}
+	if key, ok := strings.CutPrefix(name, project+"_"); ok && project != "" && key != "" {
+		return key, ComposeResource{}
+	}
+	return name, ComposeResource{Name: name}
+}
+
+// FilterNetworkAliases 去掉 docker 自动生成的网络别名（容器 ID、容器名称与 service 名称）
+func FilterNetworkAliases(config *types.ContainerJSON, aliases []string) []string {
+	var result []string
+	name := strings.TrimPrefix(config.Name, "/")
+	for _, alias := range aliases {
+		if alias == name || alias == config.Config.Labels[ComposeServiceLabel] || strings.HasPrefix(config.ID, alias) {
+			continue
+		}
+		result = append(result, alias)
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package convert
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+)
+
+// environment 使用列表，默认保持容器中的顺序，--sort-env 时按名称排序
+func TestComposeEnvironmentOrder(t *testing.T) {
+	value := func(s string) *string { return &s }
+	newSpec := func() *ContainerSpec {
+		return &ContainerSpec{
+			Name:  "web",
+			Image: "nginx",
+			Env: []EnvSpec{
+				{Name: "ZONE", Value: value("eu")},
+				{Name: "APP_MSG", Value: value("cost $5")},
+				{Name: "TOKEN", Ref: "SECRET"},
+				{Name: "HOME"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sortEnv bool
+		want    []string
+	}{
+		{
+			name: "container order",
+			want: []string{"ZONE=eu", "APP_MSG=cost $$5", "TOKEN=${SECRET}", "HOME=${HOME}"},
+		},
+		{
+			name:    "sorted",
+			sortEnv: true,
+			want:    []string{"APP_MSG=cost $$5", "HOME=${HOME}", "TOKEN=${SECRET}", "ZONE=eu"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs := []*ContainerSpec{newSpec()}
+			Normalize(specs, NormalizeOptions{SortEnv: tt.sortEnv})
+			service, _, _ := ComposeService(specs[0], "", nil)
+			if !slices.Equal(service.Environment, tt.want) {
+				t.Errorf("environment = %q, want %q", service.Environment, tt.want)
+			}
+		})
+	}
+}
+
+// 去掉导出范围外的依赖只影响生成的 service，不能修改传入的 spec
+func TestComposeKeepsSpecDependsOn(t *testing.T) {
+	spec := &ContainerSpec{
+		Name:  "web",
+		Image: "nginx",
+		Compose: &ComposeInfo{
+			Service:   "web",
+			DependsOn: map[string]DependsOn{"db": {Condition: "service_started"}},
+		},
+	}
+
+	compose := Compose([]*ContainerSpec{spec}, ComposeOptions{})
+	if dependsOn := compose.Services["web"].DependsOn; len(dependsOn) != 0 {
+		t.Errorf("depends_on = %v, want db removed", dependsOn)
+	}
+	if _, ok := spec.Compose.DependsOn["db"]; !ok {
+		t.Errorf("spec depends_on was modified: %v", spec.Compose.DependsOn)
+	}
+}
+
+// 引用的容器在导出范围内时改为 service 名称，否则改为 container: 引用与 external_links
+func TestComposeResolvesContainerReferences(t *testing.T) {
+	specs := []*ContainerSpec{
+		{ID: "0123456789abcdef", Name: "web", Image: "nginx", Compose: &ComposeInfo{Service: "frontend"}},
+		{
+			Name:        "app",
+			Image:       "alpine",
+			Ipc:         "container:0123456789ab",
+			VolumesFrom: []string{"web:ro", "data:ro"},
+			Network:     NetworkSpec{Mode: "bridge", Links: []string{"web:w", "db:database"}},
+		},
+	}
+
+	compose := Compose(specs, ComposeOptions{})
+	app := compose.Services["app"]
+	if app.Ipc != "service:frontend" {
+		t.Errorf("ipc = %q", app.Ipc)
+	}
+	if want := []string{"frontend:ro", "container:data:ro"}; !slices.Equal(app.VolumesFrom, want) {
+		t.Errorf("volumes_from = %q, want %q", app.VolumesFrom, want)
+	}
+	if want := []string{"frontend:w"}; !slices.Equal(app.Links, want) {
+		t.Errorf("links = %q, want %q", app.Links, want)
+	}
+	if want := []string{"db:database"}; !slices.Equal(app.ExternalLinks, want) {
+		t.Errorf("external_links = %q, want %q", app.ExternalLinks, want)
+	}
+	if want := []string{"web:ro", "data:ro"}; !slices.Equal(specs[1].VolumesFrom, want) {
+		t.Errorf("spec volumes_from was modified: %q", specs[1].VolumesFrom)
+	}
+}
+
+// compose 创建的网络带上子网等配置，否则 docker compose up 会拒绝容器的静态地址
+func TestComposeNetworkIPAM(t *testing.T) {
+	spec := &ContainerSpec{
+		Name:    "api",
+		Image:   "example/api",
+		Compose: &ComposeInfo{Project: "shop", Service: "api"},
+		Network: NetworkSpec{Mode: "shop_back", Networks: []NetworkAttachment{{Name: "shop_back", IPv4Address: "10.10.0.7"}}},
+	}
+	resources := &DockerResources{Networks: map[string]types.NetworkResource{
+		"shop_back": {
+			Name:       "shop_back",
+			Driver:     "bridge",
+			EnableIPv6: true,
+			Internal:   true,
+			Labels:     map[string]string{ComposeProjectLabel: "shop", ComposeNetworkLabel: "back"},
+			IPAM: network.IPAM{
+				Driver: "default",
+				Config: []network.IPAMConfig{{Subnet: "10.10.0.0/24", Gateway: "10.10.0.1", IPRange: "10.10.0.0/25"}},
+			},
+		},
+	}}
+
+	compose := Compose([]*ContainerSpec{spec}, ComposeOptions{Resources: resources})
+	want := ComposeResource{
+		EnableIPv6: true,
+		Internal:   true,
+		IPAM:       &ComposeIPAM{Config: []ComposeIPAMConfig{{Subnet: "10.10.0.0/24", IPRange: "10.10.0.0/25", Gateway: "10.10.0.1"}}},
+	}
+	if got := compose.Networks["back"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("network = %+v, want %+v", got, want)
+	}
+	if warnings := ComposeNetworkWarnings(compose); len(warnings) > 0 {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+	if _, err := MarshalCompose(compose); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有网络的详细信息时无法写出子网，提示静态地址不可用
+	compose = Compose([]*ContainerSpec{spec}, ComposeOptions{})
+	wantWarnings := []string{"api: static address 10.10.0.7 on network back needs a subnet, add ipam config to the network"}
+	if warnings := ComposeNetworkWarnings(compose); !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
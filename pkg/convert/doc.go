@@ -0,0 +1,9 @@
+// Package convert 将 docker inspect 得到的容器配置转换为 docker run 命令、compose 文件或规范化的 ContainerSpec
+//
+// 包内的函数只返回结果与错误，不会输出内容、读取标准输入或退出进程，可以直接在其他 Go 程序中使用：
+//
+//	info, _ := cli.ContainerInspect(ctx, "web")
+//	spec := convert.NewSpec(&info)
+//	command, err := convert.Run(spec, convert.Format{Shell: convert.ShellPosix})
+//	yamlData, err := convert.ComposeYAML([]*convert.ContainerSpec{spec}, convert.ComposeOptions{})
+package convert
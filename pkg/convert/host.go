@@ -0,0 +1,338 @@
+package convert
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// docker 预定义的网络，不需要创建
+var predefinedNetworks = []string{"bridge", "host", "none", "default"}
+
+// HostOptions 是生成主机重建脚本时使用的参数
+type HostOptions struct {
+	// 网络与卷的详细信息，为 nil 或缺少某个资源时只按名称创建
+	Resources *DockerResources
+	// 镜像名称对应的 digest 引用，例如 nginx:1.25 -> nginx@sha256:...，为 nil 时按标签拉取
+	ImageDigests map[string]string
+	// 每个参数单独一行
+	Multiline bool
+}
+
+// HostScript 生成重建整台主机的 POSIX shell 脚本，同时返回无法完整重建的项
+//
+// 脚本依次创建网络、卷，按 digest 拉取镜像，再按依赖顺序创建容器（container: 网络模式、link、volumes-from）。
+// 已存在的网络、卷、镜像与容器会跳过，脚本可以重复执行。
+func HostScript(configs []*types.ContainerJSON, options HostOptions) (string, []string, error) {
+	specs := make(map[string]*ContainerSpec)
+	running := make(map[string]bool)
+	var names []string
+	for _, config := range configs {
+		spec := NewSpec(config)
+		if _, ok := specs[spec.Name]; ok {
+			continue
+		}
+		specs[spec.Name] = spec
+		running[spec.Name] = config.State != nil && config.State.Running
+		names = append(names, spec.Name)
+	}
+	slices.Sort(names)
+
+	var warnings []string
+	resolve := containerResolver(configs)
+	order, err := hostContainerOrder(names, specs, resolve, &warnings)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lines := []string{
+		"#!/bin/sh",
+		"# Generated by doke export host. Existing networks, volumes, images and containers are skipped.",
+		"set -e",
+	}
+
+	// 网络：驱动、子网、网关与驱动选项
+	var networks []string
+	for _, name := range names {
+		for _, attachment := range specs[name].Network.Networks {
+			if !slices.Contains(predefinedNetworks, attachment.Name) && !slices.Contains(networks, attachment.Name) {
+				networks = append(networks, attachment.Name)
+			}
+		}
+	}
+	slices.Sort(networks)
+	if len(networks) > 0 {
+		lines = append(lines, "", "# networks")
+	}
+	for _, name := range networks {
+		args, ok := hostNetworkArgs(name, options.Resources)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("network %s was not inspected, it is created with default settings", name))
+		}
+		lines = append(lines, hostSkipIfExists([]string{"docker", "network", "inspect", name}, args))
+	}
+
+	// 命名卷：驱动与驱动选项，匿名卷由 docker run 自动创建
+	// 使用 spec 中的挂载，从未启动的容器没有运行时 Mounts，卷只出现在 Binds 与 HostConfig.Mounts 中
+	var volumes []string
+	for _, name := range names {
+		for _, mount := range specs[name].Mounts {
+			if mount.Type == "volume" && mount.Source != "" && !IsAnonymousVolume(mount.Source) && !slices.Contains(volumes, mount.Source) {
+				volumes = append(volumes, mount.Source)
+			}
+		}
+	}
+	slices.Sort(volumes)
+	if len(volumes) > 0 {
+		lines = append(lines, "", "# volumes")
+	}
+	for _, name := range volumes {
+		args, ok := hostVolumeArgs(name, options.Resources)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("volume %s was not inspected, it is created with default settings", name))
+		}
+		lines = append(lines, hostSkipIfExists([]string{"docker", "volume", "inspect", name}, args))
+	}
+
+	// 镜像：按 digest 拉取后打上原来的标签，容器使用的镜像名称不变
+	var images []string
+	for _, name := range order {
+		if image := specs[name].Image; image != "" && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+	slices.Sort(images)
+	if len(images) > 0 {
+		lines = append(lines, "", "# images")
+		if options.ImageDigests == nil {
+			warnings = append(warnings, "image digests were not looked up, images are pulled by tag")
+		}
+	}
+	for _, image := range images {
+		digest := options.ImageDigests[image]
+		switch {
+		case strings.Contains(image, "@") || options.ImageDigests == nil:
+			lines = append(lines, hostSkipIfExists([]string{"docker", "image", "inspect", image}, []string{"docker", "pull", image}))
+		case digest == "":
+			warnings = append(warnings, fmt.Sprintf("image %s has no registry digest, build or load it before running the script", image))
+			lines = append(lines, "# "+image+": no registry digest, build or load it first")
+		default:
+			lines = append(lines,
+				"if ! "+FormatShellCommand(ShellPosix, "docker", "image", "inspect", image)+" >/dev/null 2>&1; then",
+				"  "+FormatShellCommand(ShellPosix, "docker", "pull", digest),
+				"  "+FormatShellCommand(ShellPosix, "docker", "tag", digest, image),
+				"fi")
+		}
+	}
+
+	// 容器：被依赖的容器先创建，未运行的容器只创建不启动
+	if len(order) > 0 {
+		lines = append(lines, "", "# containers")
+	}
+	format := Format{Shell: ShellPosix, Multiline: options.Multiline}
+	for i, name := range order {
+		spec := specs[name]
+		cmd, connects := hostContainerCommand(spec, resolve)
+		run := cmd.Format(format)
+		if !running[name] {
+			run = "docker create" + strings.TrimPrefix(cmd.Without("-d").Format(format), "docker run")
+		}
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines,
+			"# "+name,
+			"if ! "+FormatShellCommand(ShellPosix, "docker", "container", "inspect", name)+" >/dev/null 2>&1; then",
+			"  "+strings.ReplaceAll(run, "\n", "\n  "))
+		for _, connect := range connects {
+			lines = append(lines, "  "+FormatShellCommand(ShellPosix, connect...))
+		}
+		lines = append(lines, "fi")
+	}
+
+	return strings.Join(lines, "\n") + "\n", warnings, nil
+}
+
+// 对象不存在时才执行创建命令
+func hostSkipIfExists(inspect []string, create []string) string {
+	return FormatShellCommand(ShellPosix, inspect...) + " >/dev/null 2>&1 || " + FormatShellCommand(ShellPosix, create...)
+}
+
+// docker network create 的参数，没有网络的详细信息时返回 false
+func hostNetworkArgs(name string, resources *DockerResources) ([]string, bool) {
+	args := []string{"docker", "network", "create"}
+	if resources == nil {
+		return append(args, name), false
+	}
+	network, ok := resources.Networks[name]
+	if !ok {
+		return append(args, name), false
+	}
+	if network.Driver != "" {
+		args = append(args, "--driver", network.Driver)
+	}
+	if network.IPAM.Driver != "" && network.IPAM.Driver != "default" {
+		args = append(args, "--ipam-driver", network.IPAM.Driver)
+	}
+	for _, config := range network.IPAM.Config {
+		if config.Subnet != "" {
+			args = append(args, "--subnet", config.Subnet)
+		}
+		if config.IPRange != "" {
+			args = append(args, "--ip-range", config.IPRange)
+		}
+		if config.Gateway != "" {
+			args = append(args, "--gateway", config.Gateway)
+		}
+		for _, key := range sortedKeys(config.AuxAddress) {
+			args = append(args, "--aux-address", key+"="+config.AuxAddress[key])
+		}
+	}
+	for _, key := range sortedKeys(network.IPAM.Options) {
+		args = append(args, "--ipam-opt", key+"="+network.IPAM.Options[key])
+	}
+	if network.EnableIPv6 {
+		args = append(args, "--ipv6")
+	}
+	if network.Internal {
+		args = append(args, "--internal")
+	}
+	if network.Attachable {
+		args = append(args, "--attachable")
+	}
+	for _, key := range sortedKeys(network.Options) {
+		args = append(args, "--opt", key+"="+network.Options[key])
+	}
+	for _, key := range sortedKeys(network.Labels) {
+		args = append(args, "--label", key+"="+network.Labels[key])
+	}
+	return append(args, name), true
+}
+
+// docker volume create 的参数，没有卷的详细信息时返回 false
+func hostVolumeArgs(name string, resources *DockerResources) ([]string, bool) {
+	args := []string{"docker", "volume", "create"}
+	if resources == nil {
+		return append(args, name), false
+	}
+	vol, ok := resources.Volumes[name]
+	if !ok {
+		return append(args, name), false
+	}
+	if vol.Driver != "" && vol.Driver != "local" {
+		args = append(args, "--driver", vol.Driver)
+	}
+	for _, key := range sortedKeys(vol.Options) {
+		args = append(args, "--opt", key+"="+vol.Options[key])
+	}
+	for _, key := range sortedKeys(vol.Labels) {
+		args = append(args, "--label", key+"="+vol.Labels[key])
+	}
+	return append(args, name), true
+}
+
+// 容器的 docker run 命令与额外网络的 docker network connect 命令
+// 引用其他容器的 ID 替换为容器名称，新主机上的容器 ID 会变化
+func hostContainerCommand(spec *ContainerSpec, resolve func(string) string) (*Command, [][]string) {
+	cmd := RunCommand(spec)
+	for i, flag := range cmd.Flags {
+		switch flag.Name() {
+		case "--network", "--ipc", "--pid":
+			if ref, ok := strings.CutPrefix(flag.Value(), "container:"); ok {
+				cmd.Flags[i].Args = []string{flag.Args[0], "container:" + resolve(ref)}
+			}
+		case "--volumes-from":
+			ref, mode, _ := strings.Cut(flag.Value(), ":")
+			value := resolve(ref)
+			if mode != "" {
+				value += ":" + mode
+			}
+			cmd.Flags[i].Args = []string{flag.Args[0], value}
+		}
+	}
+
+	// 额外网络的连接命令放在 if 块中与容器一起跳过
+	connects := cmd.Connects
+	cmd.Connects = nil
+	return cmd, connects
+}
+
+// 按名称、完整 ID 或 ID 前缀查找容器名称，找不到时原样返回
+func containerResolver(configs []*types.ContainerJSON) func(string) string {
+	return func(ref string) string {
+		for _, config := range configs {
+			name := strings.TrimPrefix(config.Name, "/")
+			if ref == name || ref == config.ID {
+				return name
+			}
+		}
+		for _, config := range configs {
+			if len(ref) >= 12 && strings.HasPrefix(config.ID, ref) {
+				return strings.TrimPrefix(config.Name, "/")
+			}
+		}
+		return ref
+	}
+}
+
+// 容器依赖的其他容器：container: 网络、IPC、PID 模式，link 与 volumes-from
+func hostDependencies(spec *ContainerSpec, resolve func(string) string) []string {
+	var refs []string
+	for _, mode := range []string{spec.Network.Mode, spec.Ipc, spec.Pid} {
+		if ref, ok := strings.CutPrefix(mode, "container:"); ok {
+			refs = append(refs, ref)
+		}
+	}
+	for _, link := range spec.Network.Links {
+		name, _, _ := strings.Cut(link, ":")
+		refs = append(refs, name)
+	}
+	for _, from := range spec.VolumesFrom {
+		name, _, _ := strings.Cut(from, ":")
+		refs = append(refs, name)
+	}
+
+	var dependencies []string
+	for _, ref := range refs {
+		if name := resolve(ref); !slices.Contains(dependencies, name) {
+			dependencies = append(dependencies, name)
+		}
+	}
+	return dependencies
+}
+
+// 深度优先遍历，被依赖的容器排在前面，存在循环依赖时报错
+func hostContainerOrder(names []string, specs map[string]*ContainerSpec, resolve func(string) string, warnings *[]string) ([]string, error) {
+	var order []string
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dependency := range hostDependencies(specs[name], resolve) {
+			if _, ok := specs[dependency]; !ok {
+				*warnings = append(*warnings, fmt.Sprintf("%s depends on container %s, which is not part of the export", name, dependency))
+				continue
+			}
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,55 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// container: 网络的容器复制目标容器的主机名，脚本中不能出现 --hostname，否则 docker run 失败，set -e 会中断脚本
+func TestHostScriptContainerNetwork(t *testing.T) {
+	db := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    "0123456789abcdef0123",
+			Name:  "/db",
+			State: &types.ContainerState{Running: true},
+			HostConfig: &container.HostConfig{
+				NetworkMode: "bridge",
+				// 从未启动过的容器没有运行时 Mounts，卷只出现在 Binds 中
+				Binds: []string{"pgdata:/var/lib/postgresql/data"},
+			},
+		},
+		Config: &container.Config{Image: "postgres:16", Hostname: "database"},
+	}
+	app := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         "fedcba9876543210fedc",
+			Name:       "/app",
+			State:      &types.ContainerState{Running: true},
+			HostConfig: &container.HostConfig{NetworkMode: "container:0123456789abcdef0123"},
+		},
+		Config: &container.Config{Image: "alpine", Hostname: "database"},
+	}
+
+	script, _, err := HostScript([]*types.ContainerJSON{app, db}, HostOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, appBlock, _ := strings.Cut(script, "# app")
+	if strings.Contains(appBlock, "--hostname") {
+		t.Errorf("app sets a hostname:\n%s", script)
+	}
+	for _, want := range []string{
+		"docker volume inspect pgdata >/dev/null 2>&1 || docker volume create pgdata",
+		"--network container:db",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script does not contain %q:\n%s", want, script)
+		}
+	}
+	if !strings.Contains(script, "# db") || strings.Contains(appBlock, "# db") {
+		t.Errorf("db must be created before app:\n%s", script)
+	}
+}
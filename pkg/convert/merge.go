@@ -0,0 +1,253 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeCompose 将生成的 compose 文件合并到已有的 compose 文件中，返回合并后的内容与无法更新的项
+//
+// 只修改生成结果中出现的 service 与 key，注释、key 的顺序、锚点与其他 service 保持不变。
+// service 按名称或 container_name 匹配；顶层 networks 与 volumes 只添加缺少的声明。
+func MergeCompose(existing []byte, generated []byte) ([]byte, []string, error) {
+	var source yaml.Node
+	if err := yaml.Unmarshal(generated, &source); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse generated compose file: %v", err)
+	}
+	var target yaml.Node
+	if err := yaml.Unmarshal(existing, &target); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse compose file: %v", err)
+	}
+	// 空文件直接使用生成的内容
+	if len(target.Content) == 0 {
+		return generated, nil, nil
+	}
+	root := target.Content[0]
+	if root.Kind != yaml.MappingNode || len(source.Content) == 0 {
+		return nil, nil, fmt.Errorf("compose file must be a mapping")
+	}
+
+	var warnings []string
+	generatedRoot := source.Content[0]
+	if services := mappingValue(generatedRoot, "services"); services != nil {
+		existingServices := ensureMapping(root, "services")
+		for i := 0; i < len(services.Content); i += 2 {
+			name, service := services.Content[i].Value, services.Content[i+1]
+			index := findService(existingServices, name, service)
+			if index < 0 {
+				existingServices.Content = append(existingServices.Content, services.Content[i], service)
+				continue
+			}
+			current := existingServices.Content[index+1]
+			if current.Kind != yaml.MappingNode {
+				existingServices.Content[index+1] = service
+				continue
+			}
+			warnings = append(warnings, mergeMapping(current, service, "services."+existingServices.Content[index].Value)...)
+		}
+	}
+	for _, key := range []string{"networks", "volumes"} {
+		resources := mappingValue(generatedRoot, key)
+		if resources == nil {
+			continue
+		}
+		existingResources := ensureMapping(root, key)
+		for i := 0; i < len(resources.Content); i += 2 {
+			if mappingIndex(existingResources, resources.Content[i].Value) < 0 {
+				existingResources.Content = append(existingResources.Content, resources.Content[i], resources.Content[i+1])
+			}
+		}
+	}
+
+	clearMergeTags(&target)
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(detectIndent(existing))
+	if err := encoder.Encode(&target); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	merged := restoreBlankLines(existing, buffer.Bytes())
+	if err := ValidateCompose(merged); err != nil {
+		return nil, nil, fmt.Errorf("merged compose file is invalid: %v", err)
+	}
+	return merged, warnings, nil
+}
+
+// yaml.v3 输出 << 时会带上 !!merge 标签，去掉标签后按普通的合并 key 输出
+func clearMergeTags(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!merge" {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		clearMergeTags(child)
+	}
+}
+
+// yaml.v3 不保留空行，在原来前面有空行的顶层 key 与第二层 key（例如 service，连同它们的注释）之前补回空行
+func restoreBlankLines(original []byte, merged []byte) []byte {
+	indent := detectIndent(original)
+	blank := make(map[string]bool)
+	separated := make(map[int]bool)
+	previous, parent := "", ""
+	for _, line := range strings.Split(string(original), "\n") {
+		if key, level, ok := blankLineKey(line, indent, &parent); ok {
+			isBlank := strings.TrimSpace(previous) == ""
+			blank[key] = isBlank
+			separated[level] = separated[level] || isBlank
+		}
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			previous = line
+		}
+	}
+
+	parent = ""
+	var result []string
+	for i, line := range strings.Split(string(merged), "\n") {
+		if key, level, ok := blankLineKey(line, indent, &parent); i > 0 && ok {
+			// 新增的 key 与原文件同一层级的风格一致
+			if isBlank, known := blank[key]; known && !isBlank || !known && !separated[level] {
+				result = append(result, line)
+				continue
+			}
+			// 空行放在 key 的注释之前
+			start := len(result)
+			for start > 0 && strings.HasPrefix(strings.TrimSpace(result[start-1]), "#") {
+				start--
+			}
+			if start > 0 {
+				result = append(result[:start], append([]string{""}, result[start:]...)...)
+			}
+		}
+		result = append(result, line)
+	}
+	return []byte(strings.Join(result, "\n"))
+}
+
+// 顶层 key 返回 key 与层级 0，第二层 key 返回 parent.key 与层级 1，parent 记录最近的顶层 key
+func blankLineKey(line string, indent int, parent *string) (string, int, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	colon := strings.Index(trimmed, ":")
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '-' || colon < 0 {
+		return "", 0, false
+	}
+	key := trimmed[:colon]
+	switch len(line) - len(trimmed) {
+	case 0:
+		*parent = key
+		return key, 0, true
+	case indent:
+		return *parent + "." + key, 1, true
+	}
+	return "", 0, false
+}
+
+// 逐个 key 更新 service，值相同的 key 保持原样，被锚点引用的值不修改以免影响其他 service
+func mergeMapping(current *yaml.Node, generated *yaml.Node, path string) []string {
+	// 通过 << 合并进来的值也算作已有的值
+	var effective map[string]interface{}
+	_ = current.Decode(&effective)
+
+	var warnings []string
+	for i := 0; i < len(generated.Content); i += 2 {
+		key, value := generated.Content[i], generated.Content[i+1]
+		index := mappingIndex(current, key.Value)
+		if index < 0 {
+			if existing, ok := effective[key.Value]; ok && nodeEquals(value, existing) {
+				continue
+			}
+			current.Content = append(current.Content, key, value)
+			continue
+		}
+		old := current.Content[index+1]
+		var oldValue interface{}
+		if err := old.Decode(&oldValue); err == nil && nodeEquals(value, oldValue) {
+			continue
+		}
+		if old.Anchor != "" {
+			warnings = append(warnings, fmt.Sprintf("%s.%s is shared through anchor &%s and was not updated", path, key.Value, old.Anchor))
+			continue
+		}
+		value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		current.Content[index+1] = value
+	}
+	return warnings
+}
+
+// 节点的值与已有的值是否相同
+func nodeEquals(node *yaml.Node, value interface{}) bool {
+	var decoded interface{}
+	if err := node.Decode(&decoded); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(decoded, value)
+}
+
+// 按 service 名称查找，找不到时按 container_name 查找
+func findService(services *yaml.Node, name string, service *yaml.Node) int {
+	if index := mappingIndex(services, name); index >= 0 {
+		return index
+	}
+	containerName := mappingValue(service, "container_name")
+	if containerName == nil {
+		return -1
+	}
+	for i := 0; i < len(services.Content); i += 2 {
+		if value := mappingValue(services.Content[i+1], "container_name"); value != nil && value.Value == containerName.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+// 返回 key 在 mapping 节点中的位置，不存在时返回 -1
+func mappingIndex(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if index := mappingIndex(node, key); index >= 0 {
+		return node.Content[index+1]
+	}
+	return nil
+}
+
+// 返回 key 对应的 mapping，不存在或为空时创建
+func ensureMapping(node *yaml.Node, key string) *yaml.Node {
+	if index := mappingIndex(node, key); index >= 0 {
+		value := node.Content[index+1]
+		if value.Kind != yaml.MappingNode {
+			value = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content[index+1] = value
+		}
+		return value
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
+
+// 沿用已有文件的缩进，找不到时使用 2 个空格
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if indent := len(line) - len(trimmed); indent > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "- ") {
+			return indent
+		}
+	}
+	return 2
+}
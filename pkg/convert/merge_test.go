@@ -0,0 +1,119 @@
+package convert
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 合并只修改生成结果中的值，注释、空行与 key 的顺序保持原样
+func TestMergeComposePreservesLayout(t *testing.T) {
+	existing := `# shop stack
+name: shop
+
+services:
+  # front end
+  web:
+    image: nginx:1.24 # pinned
+    ports:
+      - "8080:80"
+
+  # cache shared by web
+  cache:
+    image: redis:7
+
+volumes:
+  data: {}
+`
+	generated := `services:
+  web:
+    image: nginx:1.25
+    restart: always
+`
+	merged, warnings, err := MergeCompose([]byte(existing), []byte(generated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+	want := `# shop stack
+name: shop
+
+services:
+  # front end
+  web:
+    image: nginx:1.25 # pinned
+    ports:
+      - "8080:80"
+    restart: always
+
+  # cache shared by web
+  cache:
+    image: redis:7
+
+volumes:
+  data: {}
+`
+	if string(merged) != want {
+		t.Errorf("merged file:\n%s\nwant:\n%s", merged, want)
+	}
+}
+
+// 被锚点共享的值不修改，通过 << 合并进来且相同的值不重复写入
+func TestMergeComposeAnchors(t *testing.T) {
+	existing := `x-defaults: &defaults
+  restart: always
+
+services:
+  web:
+    <<: *defaults
+    image: nginx:1.24
+    logging: &logging
+      driver: json-file
+  api:
+    image: api:1
+    logging: *logging
+`
+	generated := `services:
+  web:
+    image: nginx:1.25
+    restart: always
+    logging:
+      driver: local
+`
+	merged, warnings, err := MergeCompose([]byte(existing), []byte(generated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "services.web.logging is shared through anchor &logging and was not updated"; !slices.Contains(warnings, want) {
+		t.Errorf("missing warning %q in %q", want, warnings)
+	}
+	output := string(merged)
+	for _, want := range []string{"<<: *defaults", "image: nginx:1.25", "logging: &logging", "logging: *logging", "driver: json-file"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("merged file does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "restart: always") != 1 {
+		t.Errorf("restart inherited through << was written again:\n%s", output)
+	}
+}
+
+// 合并结果不符合 compose 规范时返回错误，不写入文件
+func TestMergeComposeInvalidResult(t *testing.T) {
+	existing := `services:
+  web:
+    image: nginx:1.24
+  db:
+    imagee: postgres:16
+`
+	generated := `services:
+  web:
+    image: nginx:1.25
+`
+	_, _, err := MergeCompose([]byte(existing), []byte(generated))
+	if err == nil || !strings.Contains(err.Error(), "merged compose file is invalid") {
+		t.Errorf("err = %v, want a schema validation error", err)
+	}
+}
@@ -0,0 +1,24 @@
+package convert
+
+import (
+	"slices"
+	"strings"
+)
+
+// NormalizeOptions 控制 Normalize 的排序方式
+type NormalizeOptions struct {
+	// SortEnv 为 true 时环境变量按名称排序，否则保持容器中的顺序
+	SortEnv bool
+}
+
+// Normalize 对 docker 返回的无序配置排序，使同一个容器每次生成的输出完全相同
+// 挂载在 NewSpec 中已按容器内路径排序，这里只按选项排序环境变量，会直接修改传入的 ContainerSpec
+func Normalize(specs []*ContainerSpec, options NormalizeOptions) {
+	for _, spec := range specs {
+		if options.SortEnv {
+			slices.SortStableFunc(spec.Env, func(a, b EnvSpec) int {
+				return strings.Compare(a.Name, b.Name)
+			})
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package convert
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsSecret(t *testing.T) {
+	tests := []struct {
+		key    string
+		value  string
+		secret bool
+	}{
+		{key: "DB_PASSWORD", value: "hunter2", secret: true},
+		{key: "POSTGRES_PASSWD", value: "hunter2", secret: true},
+		{key: "API_TOKEN", value: "abc", secret: true},
+		{key: "CLIENT_SECRET", value: "abc", secret: true},
+		{key: "STRIPE_KEY", value: "sk_test", secret: true},
+		{key: "AWS_ACCESS_KEY", value: "AKIA", secret: true},
+		{key: "APIKEY", value: "abc", secret: true},
+		{key: "api-key", value: "abc", secret: true},
+		{key: "DATABASE_URL", value: "postgres://app:s3cret@db:5432/app", secret: true},
+		{key: "SESSION", value: "q8Zr2LmX4vT9pK1sW7nB", secret: true},
+		{key: "KEYBOARD_LAYOUT", value: "us", secret: false},
+		{key: "MONKEY_MODE", value: "on", secret: false},
+		{key: "TURNKEY", value: "yes", secret: false},
+		{key: "PATH", value: "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin", secret: false},
+		{key: "DB_PASSWORD_FILE", value: "/run/secrets/db", secret: false},
+		{key: "DATABASE_URL", value: "postgres://db:5432/app", secret: false},
+		{key: "NGINX_VERSION", value: "1.25.3", secret: false},
+		{key: "GREETING", value: "hello world", secret: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			if got := IsSecret(tt.key, tt.value); got != tt.secret {
+				t.Errorf("IsSecret(%q, %q) = %v, want %v", tt.key, tt.value, got, tt.secret)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	value := func(s string) *string { return &s }
+	tests := []struct {
+		name       string
+		spec       ContainerSpec
+		env        []EnvSpec
+		labels     map[string]string
+		labelRefs  map[string]string
+		logOpts    map[string]string
+		optionRefs map[string]string
+		secrets    []Secret
+	}{
+		{
+			name: "env",
+			spec: ContainerSpec{Name: "web", Env: []EnvSpec{
+				{Name: "DB_PASSWORD", Value: value("hunter2")},
+				{Name: "KEYBOARD_LAYOUT", Value: value("us")},
+				{Name: "HOME"},
+			}},
+			env: []EnvSpec{
+				{Name: "DB_PASSWORD", Ref: "DB_PASSWORD"},
+				{Name: "KEYBOARD_LAYOUT", Value: value("us")},
+				{Name: "HOME"},
+			},
+			secrets: []Secret{{Name: "DB_PASSWORD", Value: "hunter2"}},
+		},
+		{
+			name: "label",
+			spec: ContainerSpec{Name: "web", Labels: map[string]string{
+				"com.example.api-token":             "t0k3n",
+				"org.opencontainers.image.revision": "3f1c2b9e8d7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e",
+			}},
+			labels: map[string]string{
+				"com.example.api-token":             "REDACTED",
+				"org.opencontainers.image.revision": "3f1c2b9e8d7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e",
+			},
+			labelRefs: map[string]string{"com.example.api-token": "WEB_COM_EXAMPLE_API_TOKEN"},
+			secrets:   []Secret{{Name: "WEB_COM_EXAMPLE_API_TOKEN", Value: "t0k3n"}},
+		},
+		{
+			name: "log option",
+			spec: ContainerSpec{Name: "web", Logging: &LoggingSpec{Driver: "splunk", Options: map[string]string{
+				"splunk-token": "00000000-0000-0000-0000-000000000000",
+				"splunk-url":   "https://splunk.example.com:8088",
+			}}},
+			logOpts: map[string]string{
+				"splunk-token": "REDACTED",
+				"splunk-url":   "https://splunk.example.com:8088",
+			},
+			optionRefs: map[string]string{"splunk-token": "WEB_SPLUNK_TOKEN"},
+			secrets:    []Secret{{Name: "WEB_SPLUNK_TOKEN", Value: "00000000-0000-0000-0000-000000000000"}},
+		},
+		{
+			name: "same key in label and log option",
+			spec: ContainerSpec{
+				Name:    "web",
+				Labels:  map[string]string{"token": "a"},
+				Logging: &LoggingSpec{Options: map[string]string{"token": "b"}},
+			},
+			labels:     map[string]string{"token": "REDACTED"},
+			labelRefs:  map[string]string{"token": "WEB_TOKEN"},
+			logOpts:    map[string]string{"token": "REDACTED"},
+			optionRefs: map[string]string{"token": "WEB_TOKEN_2"},
+			secrets:    []Secret{{Name: "WEB_TOKEN", Value: "a"}, {Name: "WEB_TOKEN_2", Value: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			var original map[string]string
+			if spec.Logging != nil {
+				original = spec.Logging.Options
+			}
+			secrets := Redact([]*ContainerSpec{&spec}, RedactOptions{})
+
+			if !slices.Equal(secrets, tt.secrets) {
+				t.Errorf("secrets = %v, want %v", secrets, tt.secrets)
+			}
+			if tt.env != nil && !slices.EqualFunc(spec.Env, tt.env, func(a, b EnvSpec) bool {
+				return a.Name == b.Name && a.Ref == b.Ref && (a.Value == nil) == (b.Value == nil) && (a.Value == nil || *a.Value == *b.Value)
+			}) {
+				t.Errorf("env = %+v, want %+v", spec.Env, tt.env)
+			}
+			if tt.labels != nil && !maps.Equal(spec.Labels, tt.labels) {
+				t.Errorf("labels = %v, want %v", spec.Labels, tt.labels)
+			}
+			if !maps.Equal(spec.LabelRefs, tt.labelRefs) {
+				t.Errorf("label refs = %v, want %v", spec.LabelRefs, tt.labelRefs)
+			}
+			if tt.logOpts != nil {
+				if !maps.Equal(spec.Logging.Options, tt.logOpts) {
+					t.Errorf("log options = %v, want %v", spec.Logging.Options, tt.logOpts)
+				}
+				if !maps.Equal(spec.Logging.OptionRefs, tt.optionRefs) {
+					t.Errorf("log option refs = %v, want %v", spec.Logging.OptionRefs, tt.optionRefs)
+				}
+				// 日志选项与容器配置共用 map，不能修改原来的值
+				if original["token"] == "REDACTED" || original["splunk-token"] == "REDACTED" {
+					t.Errorf("original log options were modified: %v", original)
+				}
+			}
+		})
+	}
+}
+
+// 可以展开变量的格式引用 .env 中的变量，其他格式保留 REDACTED 并提示
+func TestRedactedReferences(t *testing.T) {
+	spec := &ContainerSpec{
+		Name:    "web",
+		Image:   "nginx",
+		Labels:  map[string]string{"api-token": "t0k3n"},
+		Logging: &LoggingSpec{Driver: "splunk", Options: map[string]string{"splunk-token": "s3cr3t"}},
+	}
+	Redact([]*ContainerSpec{spec}, RedactOptions{})
+
+	run, err := Run(spec, Format{Shell: ShellPosix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`--label api-token="${WEB_API_TOKEN}"`, `--log-opt splunk-token="${WEB_SPLUNK_TOKEN}"`} {
+		if !strings.Contains(run, want) {
+			t.Errorf("run command does not contain %s: %s", want, run)
+		}
+	}
+
+	service, _, _ := ComposeService(spec, "", nil)
+	if got := service.Labels["api-token"]; got != "${WEB_API_TOKEN}" {
+		t.Errorf("compose label = %q", got)
+	}
+	if got := service.Logging.Options["splunk-token"]; got != "${WEB_SPLUNK_TOKEN}" {
+		t.Errorf("compose log option = %q", got)
+	}
+	if spec.Logging.Options["splunk-token"] != "REDACTED" {
+		t.Errorf("spec log options were modified: %v", spec.Logging.Options)
+	}
+
+	want := []string{"label api-token, log option splunk-token set to REDACTED, replace with the values from the .env file"}
+	if warnings := RedactedWarnings(spec); !slices.Equal(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
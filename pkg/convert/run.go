@@ -0,0 +1,421 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Run 生成容器的 docker run 命令
+func Run(spec *ContainerSpec, format Format) (string, error) {
+	if err := ValidateShell(format.Shell); err != nil {
+		return "", err
+	}
+	return RunCommand(spec).Format(format), nil
+}
+
+// RunCommand 根据 ContainerSpec 构建 docker run 的参数
+func RunCommand(spec *ContainerSpec) *Command {
+	cmd := &Command{}
+
+	// 添加容器的名称
+	if spec.Name != "" {
+		cmd.Add(GroupGeneral, "--name", spec.Name)
+	}
+	cmd.Add(GroupGeneral, "-d")
+	if spec.Platform != "" {
+		cmd.Add(GroupGeneral, "--platform", spec.Platform)
+	}
+	if spec.Interactive {
+		cmd.Add(GroupGeneral, "-i")
+	}
+	if spec.Tty {
+		cmd.Add(GroupGeneral, "-t")
+	}
+	if spec.AutoRemove {
+		cmd.Add(GroupGeneral, "--rm")
+	}
+	if spec.Hostname != "" {
+		cmd.Add(GroupGeneral, "--hostname", spec.Hostname)
+	}
+	if spec.Domainname != "" {
+		cmd.Add(GroupGeneral, "--domainname", spec.Domainname)
+	}
+	if spec.User != "" {
+		cmd.Add(GroupGeneral, "--user", spec.User)
+	}
+	for _, group := range spec.GroupAdd {
+		cmd.Add(GroupGeneral, "--group-add", group)
+	}
+	if spec.WorkingDir != "" {
+		cmd.Add(GroupGeneral, "--workdir", spec.WorkingDir)
+	}
+
+	// 绑定端口映射
+	for _, port := range spec.Ports {
+		cmd.Add(GroupPorts, "-p", port.String())
+	}
+	if spec.PublishAll {
+		cmd.Add(GroupPorts, "-P")
+	}
+	for _, port := range spec.Expose {
+		cmd.Add(GroupPorts, "--expose", port.String())
+	}
+
+	// 绑定映射目录
+	for _, mount := range spec.Mounts {
+		if mount.Type == "tmpfs" {
+			cmd.Add(GroupVolumes, "--tmpfs", mount.TmpfsString())
+		} else {
+			cmd.Add(GroupVolumes, "-v", mount.String())
+		}
+	}
+	for _, from := range spec.VolumesFrom {
+		cmd.Add(GroupVolumes, "--volumes-from", from)
+	}
+
+	// add env vars
+	for _, env := range spec.Env {
+		switch {
+		case env.Ref != "":
+			// 引用的变量需要由 shell 展开，不能转义
+			cmd.Flags = append(cmd.Flags, Flag{Group: GroupEnv, Args: []string{"-e", fmt.Sprintf("%s=${%s}", env.Name, env.Ref)}, Ref: env.Ref})
+		case env.Value == nil:
+			cmd.Add(GroupEnv, "-e", env.Name)
+		default:
+			cmd.Add(GroupEnv, "-e", fmt.Sprintf("%s=%s", env.Name, *env.Value))
+		}
+	}
+
+	// add labels
+	for _, key := range sortedKeys(spec.Labels) {
+		if ref := spec.LabelRefs[key]; ref != "" {
+			cmd.Flags = append(cmd.Flags, Flag{Group: GroupLabels, Args: []string{"--label", fmt.Sprintf("%s=${%s}", key, ref)}, Ref: ref})
+			continue
+		}
+		cmd.Add(GroupLabels, "--label", fmt.Sprintf("%s=%s", key, spec.Labels[key]))
+	}
+
+	for _, key := range sortedKeys(spec.Annotations) {
+		cmd.Add(GroupLabels, "--annotation", fmt.Sprintf("%s=%s", key, spec.Annotations[key]))
+	}
+
+	// add device
+	for _, device := range spec.Devices {
+		cmd.Add(GroupDevices, "--device", device.String())
+	}
+
+	// 权限与安全选项
+	if spec.Privileged {
+		cmd.Add(GroupSecurity, "--privileged")
+	}
+	for _, capability := range spec.CapAdd {
+		cmd.Add(GroupSecurity, "--cap-add", capability)
+	}
+	for _, capability := range spec.CapDrop {
+		cmd.Add(GroupSecurity, "--cap-drop", capability)
+	}
+	for _, opt := range spec.SecurityOpt {
+		cmd.Add(GroupSecurity, "--security-opt", opt)
+	}
+	if spec.ReadOnly {
+		cmd.Add(GroupSecurity, "--read-only")
+	}
+	if spec.Init {
+		cmd.Add(GroupSecurity, "--init")
+	}
+	for _, ulimit := range spec.Ulimits {
+		cmd.Add(GroupSecurity, "--ulimit", fmt.Sprintf("%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+	for _, key := range sortedKeys(spec.Sysctls) {
+		cmd.Add(GroupSecurity, "--sysctl", fmt.Sprintf("%s=%s", key, spec.Sysctls[key]))
+	}
+
+	// Add CPU limit
+	resources := spec.Resources
+	if resources.CPUs > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--cpus=%s", strconv.FormatFloat(resources.CPUs, 'f', -1, 64)))
+	}
+	if resources.CPUShares > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--cpu-shares=%d", resources.CPUShares))
+	}
+	if resources.CpusetCpus != "" {
+		cmd.Add(GroupResources, fmt.Sprintf("--cpuset-cpus=%s", resources.CpusetCpus))
+	}
+	if resources.CPUPeriod > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--cpu-period=%d", resources.CPUPeriod))
+	}
+	if resources.CPUQuota != 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--cpu-quota=%d", resources.CPUQuota))
+	}
+
+	// Add Memory limit
+	if resources.Memory > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--memory=%d", resources.Memory))
+	}
+	if resources.MemoryReservation > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--memory-reservation=%d", resources.MemoryReservation))
+	}
+	if resources.MemorySwap != 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--memory-swap=%d", resources.MemorySwap))
+	}
+	if resources.ShmSize > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--shm-size=%d", resources.ShmSize))
+	}
+	if resources.PidsLimit > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--pids-limit=%d", resources.PidsLimit))
+	}
+	if resources.OomScoreAdj != 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--oom-score-adj=%d", resources.OomScoreAdj))
+	}
+	if resources.OomKillDisable {
+		cmd.Add(GroupResources, "--oom-kill-disable")
+	}
+	if resources.KernelMemory > 0 {
+		cmd.Add(GroupResources, fmt.Sprintf("--kernel-memory=%d", resources.KernelMemory))
+	}
+	for _, key := range sortedKeys(spec.StorageOpt) {
+		cmd.Add(GroupResources, "--storage-opt", fmt.Sprintf("%s=%s", key, spec.StorageOpt[key]))
+	}
+	if blkio := resources.Blkio; blkio != nil {
+		if blkio.Weight > 0 {
+			cmd.Add(GroupResources, fmt.Sprintf("--blkio-weight=%d", blkio.Weight))
+		}
+		for _, device := range blkio.WeightDevice {
+			cmd.Add(GroupResources, "--blkio-weight-device", fmt.Sprintf("%s:%d", device.Path, device.Weight))
+		}
+		for _, limits := range []struct {
+			flag    string
+			devices []BlkioLimitSpec
+		}{
+			{"--device-read-bps", blkio.DeviceReadBps},
+			{"--device-write-bps", blkio.DeviceWriteBps},
+			{"--device-read-iops", blkio.DeviceReadIOps},
+			{"--device-write-iops", blkio.DeviceWriteIOps},
+		} {
+			for _, device := range limits.devices {
+				cmd.Add(GroupResources, limits.flag, fmt.Sprintf("%s:%d", device.Path, device.Rate))
+			}
+		}
+	}
+	for _, request := range spec.DeviceRequests {
+		if value, ok := GpusValue(request); ok {
+			cmd.Add(GroupDevices, "--gpus", value)
+		}
+	}
+
+	// Add other options from config (e.g., restart policy, network)
+	if spec.Restart != "" {
+		cmd.Add(GroupGeneral, "--restart", spec.Restart)
+	}
+
+	network := spec.Network
+	if network.Mode != "" && network.Mode != "bridge" {
+		cmd.Add(GroupNetwork, "--network", network.Mode)
+	}
+	for _, attachment := range network.Networks {
+		// 第一个网络通过 docker run 的 --network 连接，其余网络在创建后连接
+		if attachment.Name != network.Mode {
+			cmd.Connects = append(cmd.Connects, networkConnect(attachment, spec.Name))
+			continue
+		}
+		for _, alias := range attachment.Aliases {
+			cmd.Add(GroupNetwork, "--network-alias", alias)
+		}
+		if attachment.IPv4Address != "" {
+			cmd.Add(GroupNetwork, "--ip", attachment.IPv4Address)
+		}
+		if attachment.IPv6Address != "" {
+			cmd.Add(GroupNetwork, "--ip6", attachment.IPv6Address)
+		}
+	}
+	if network.MacAddress != "" {
+		cmd.Add(GroupNetwork, "--mac-address", network.MacAddress)
+	}
+	for _, dns := range network.DNS {
+		cmd.Add(GroupNetwork, "--dns", dns)
+	}
+	for _, search := range network.DNSSearch {
+		cmd.Add(GroupNetwork, "--dns-search", search)
+	}
+	for _, opt := range network.DNSOptions {
+		cmd.Add(GroupNetwork, "--dns-option", opt)
+	}
+	for _, host := range network.ExtraHosts {
+		cmd.Add(GroupNetwork, "--add-host", host)
+	}
+	for _, link := range network.Links {
+		cmd.Add(GroupNetwork, "--link", link)
+	}
+
+	// 命名空间
+	if spec.Ipc != "" {
+		cmd.Add(GroupNamespaces, "--ipc", spec.Ipc)
+	}
+	if spec.Pid != "" {
+		cmd.Add(GroupNamespaces, "--pid", spec.Pid)
+	}
+	if spec.Uts != "" {
+		cmd.Add(GroupNamespaces, "--uts", spec.Uts)
+	}
+	if spec.Userns != "" {
+		cmd.Add(GroupNamespaces, "--userns", spec.Userns)
+	}
+	if spec.Runtime != "" {
+		cmd.Add(GroupNamespaces, "--runtime", spec.Runtime)
+	}
+	if spec.CgroupParent != "" {
+		cmd.Add(GroupNamespaces, "--cgroup-parent", spec.CgroupParent)
+	}
+	if spec.Cgroupns != "" {
+		cmd.Add(GroupNamespaces, "--cgroupns", spec.Cgroupns)
+	}
+
+	// 日志配置
+	if logging := spec.Logging; logging != nil {
+		if logging.Driver != "" {
+			cmd.Add(GroupLogging, "--log-driver", logging.Driver)
+		}
+		for _, key := range sortedKeys(logging.Options) {
+			if ref := logging.OptionRefs[key]; ref != "" {
+				cmd.Flags = append(cmd.Flags, Flag{Group: GroupLogging, Args: []string{"--log-opt", fmt.Sprintf("%s=${%s}", key, ref)}, Ref: ref})
+				continue
+			}
+			cmd.Add(GroupLogging, "--log-opt", fmt.Sprintf("%s=%s", key, logging.Options[key]))
+		}
+	}
+
+	// 停止信号与超时
+	if spec.StopSignal != "" {
+		cmd.Add(GroupGeneral, "--stop-signal", spec.StopSignal)
+	}
+	if spec.StopTimeout != nil {
+		cmd.Add(GroupGeneral, fmt.Sprintf("--stop-timeout=%d", *spec.StopTimeout))
+	}
+
+	// 健康检查
+	if health := spec.Healthcheck; health != nil {
+		switch health.Test[0] {
+		case "NONE":
+			cmd.Add(GroupHealth, "--no-healthcheck")
+		case "CMD-SHELL":
+			cmd.Add(GroupHealth, "--health-cmd", strings.Join(health.Test[1:], " "))
+		case "CMD":
+			// --health-cmd 只支持 shell 形式，exec 形式的每个参数需要单独转义
+			var words []string
+			for _, word := range health.Test[1:] {
+				words = append(words, QuotePosix(word))
+			}
+			cmd.Add(GroupHealth, "--health-cmd", strings.Join(words, " "))
+		}
+		if health.Interval != "" {
+			cmd.Add(GroupHealth, fmt.Sprintf("--health-interval=%s", health.Interval))
+		}
+		if health.Timeout != "" {
+			cmd.Add(GroupHealth, fmt.Sprintf("--health-timeout=%s", health.Timeout))
+		}
+		if health.StartPeriod != "" {
+			cmd.Add(GroupHealth, fmt.Sprintf("--health-start-period=%s", health.StartPeriod))
+		}
+		if health.StartInterval != "" {
+			cmd.Add(GroupHealth, fmt.Sprintf("--health-start-interval=%s", health.StartInterval))
+		}
+		if health.Retries > 0 {
+			cmd.Add(GroupHealth, fmt.Sprintf("--health-retries=%d", health.Retries))
+		}
+	}
+
+	// docker run 的 --entrypoint 只接受一个值，其余部分需要放到命令参数之前
+	if len(spec.Entrypoint) > 0 {
+		cmd.Add(GroupGeneral, "--entrypoint", spec.Entrypoint[0])
+		cmd.Args = append(cmd.Args, spec.Entrypoint[1:]...)
+	}
+	cmd.Args = append(cmd.Args, spec.Command...)
+
+	// 设置容器的镜像
+	cmd.Image = spec.Image
+
+	return cmd
+}
+
+// 将容器连接到额外网络的 docker network connect 命令
+func networkConnect(attachment NetworkAttachment, container string) []string {
+	connect := []string{"docker", "network", "connect"}
+	for _, alias := range attachment.Aliases {
+		connect = append(connect, "--alias", alias)
+	}
+	if attachment.IPv4Address != "" {
+		connect = append(connect, "--ip", attachment.IPv4Address)
+	}
+	if attachment.IPv6Address != "" {
+		connect = append(connect, "--ip6", attachment.IPv6Address)
+	}
+	return append(connect, attachment.Name, container)
+}
+
+// RunWarnings 返回 docker run 命令无法表示而被跳过的配置
+func RunWarnings(spec *ContainerSpec) []string {
+	var warnings []string
+	for _, request := range spec.DeviceRequests {
+		if _, ok := GpusValue(request); !ok {
+			warnings = append(warnings, fmt.Sprintf("device request with capabilities %s has no docker run equivalent and is skipped", strings.Join(request.Capabilities, ",")))
+		}
+	}
+	return warnings
+}
+
+// GpusValue 返回设备请求对应的 --gpus 值，格式与 docker 解析时相同，只有包含 gpu 能力的请求可以用 --gpus 表示
+func GpusValue(request DeviceRequestSpec) (string, bool) {
+	if !slices.Contains(request.Capabilities, "gpu") {
+		return "", false
+	}
+	count := strconv.Itoa(request.Count)
+	if request.Count == -1 {
+		count = "all"
+	}
+	capabilities := slices.DeleteFunc(slices.Clone(request.Capabilities), func(c string) bool { return c == "gpu" })
+	// 最常见的 --gpus all 与 --gpus 2
+	if request.Driver == "" && len(request.DeviceIDs) == 0 && len(capabilities) == 0 && len(request.Options) == 0 {
+		return count, true
+	}
+
+	var fields []string
+	if request.Driver != "" {
+		fields = append(fields, "driver="+request.Driver)
+	}
+	if len(request.DeviceIDs) > 0 {
+		fields = append(fields, "device="+strings.Join(request.DeviceIDs, ","))
+	} else if request.Count != 0 {
+		fields = append(fields, "count="+count)
+	}
+	if len(capabilities) > 0 {
+		fields = append(fields, "capabilities="+strings.Join(capabilities, ","))
+	}
+	if len(request.Options) > 0 {
+		// options 的值本身也是 CSV，例如 options="a=1,b=2"
+		var options []string
+		for _, key := range sortedKeys(request.Options) {
+			options = append(options, key+"="+request.Options[key])
+		}
+		value, ok := csvLine(options)
+		if !ok {
+			return "", false
+		}
+		fields = append(fields, "options="+value)
+	}
+	return csvLine(fields)
+}
+
+// docker 按 CSV 解析 --gpus，包含逗号或引号的字段需要加双引号
+func csvLine(fields []string) (string, bool) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.Write(fields); err != nil {
+		return "", false
+	}
+	writer.Flush()
+	return strings.TrimSuffix(buffer.String(), "\n"), true
+}
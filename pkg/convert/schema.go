@@ -0,0 +1,61 @@
+package convert
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// compose 规范的 JSON schema，原样取自 compose-spec/compose-go 的 schema/compose-spec.json
+//
+//go:embed compose-spec.json
+var composeSchemaData []byte
+
+// 只作为 schema 的标识，不会联网下载
+const composeSchemaURL = "https://raw.githubusercontent.com/compose-spec/compose-go/v2.1.3/schema/compose-spec.json"
+
+var composeSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(composeSchemaData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compose schema: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(composeSchemaURL, document); err != nil {
+		return nil, fmt.Errorf("failed to load compose schema: %v", err)
+	}
+	schema, err := compiler.Compile(composeSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile compose schema: %v", err)
+	}
+	return schema, nil
+})
+
+// ValidateCompose 按 compose 规范的 JSON schema 校验 compose 文件，返回所有不符合规范的位置
+func ValidateCompose(data []byte) error {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("failed to parse compose file: %v", err)
+	}
+	// 转换为 JSON 的值类型（数字为 json.Number），与 schema 校验器期望的类型一致
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to parse compose file: %v", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to parse compose file: %v", err)
+	}
+	schema, err := composeSchema()
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(instance); err != nil {
+		return fmt.Errorf("compose file does not match the Compose Specification: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package convert
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 生成命令所使用的 shell
+const (
+	ShellPosix      = "posix"
+	ShellPowerShell = "powershell"
+)
+
+// docker run 参数的分类，多行输出时按以下顺序排列
+const (
+	GroupGeneral = iota
+	GroupPorts
+	GroupVolumes
+	GroupEnv
+	GroupLabels
+	GroupDevices
+	GroupSecurity
+	GroupResources
+	GroupNetwork
+	GroupNamespaces
+	GroupLogging
+	GroupHealth
+)
+
+// Flag 是单个参数及其值，例如 ["-p", "8080:80"]
+type Flag struct {
+	Group int
+	Args  []string
+	// Ref 不为空时最后一个参数以 ${Ref} 结尾，输出时保留变量展开
+	Ref string
+}
+
+// Command 是 docker run 命令的结构化表示
+type Command struct {
+	Flags []Flag
+	Image string
+	Args  []string
+	// docker run 只能连接一个网络，其余网络在容器创建后通过 docker network connect 连接，每项为完整的命令
+	Connects [][]string
+}
+
+// Format 是命令的输出格式
+type Format struct {
+	Shell     string
+	Multiline bool
+}
+
+// Add 添加一个参数
+func (c *Command) Add(group int, args ...string) {
+	c.Flags = append(c.Flags, Flag{Group: group, Args: args})
+}
+
+// Name 返回参数名称，--cpus=1.5 形式的参数返回 --cpus
+func (f Flag) Name() string {
+	name, _, _ := strings.Cut(f.Args[0], "=")
+	return name
+}
+
+// Value 返回参数的值，没有值的开关参数返回空字符串
+func (f Flag) Value() string {
+	if len(f.Args) > 1 {
+		return f.Args[1]
+	}
+	_, value, _ := strings.Cut(f.Args[0], "=")
+	return value
+}
+
+// Without 返回去掉指定参数后的命令
+func (c *Command) Without(names ...string) *Command {
+	result := &Command{Image: c.Image, Args: c.Args, Connects: c.Connects}
+	for _, flag := range c.Flags {
+		if !slices.Contains(names, flag.Name()) {
+			result.Flags = append(result.Flags, flag)
+		}
+	}
+	return result
+}
+
+// SortedFlags 返回按分类排序后的参数，同一分类内保持原有顺序
+func (c *Command) SortedFlags() []Flag {
+	flags := append([]Flag{}, c.Flags...)
+	sort.SliceStable(flags, func(i, j int) bool {
+		return flags[i].Group < flags[j].Group
+	})
+	return flags
+}
+
+// Words 返回 docker run 之后的全部参数，参数顺序与 Format 一致
+func (c *Command) Words() []string {
+	var words []string
+	for _, flag := range c.SortedFlags() {
+		words = append(words, flag.Args...)
+	}
+	if c.Image != "" {
+		words = append(words, c.Image)
+		words = append(words, c.Args...)
+	}
+	return words
+}
+
+// Format 按指定 shell 转义参数，多行输出时每个参数单独一行
+func (c *Command) Format(format Format) string {
+	continuation := " \\"
+	if format.Shell == ShellPowerShell {
+		continuation = " `"
+	}
+
+	lines := []string{"docker run"}
+	for _, flag := range c.SortedFlags() {
+		lines = append(lines, formatFlag(format.Shell, flag))
+	}
+	if c.Image != "" {
+		lines = append(lines, FormatShellCommand(format.Shell, append([]string{c.Image}, c.Args...)...))
+	}
+
+	command := strings.Join(lines, " ")
+	if format.Multiline {
+		command = strings.Join(lines, continuation+"\n  ")
+	}
+	for _, connect := range c.Connects {
+		command += "\n" + FormatShellCommand(format.Shell, connect...)
+	}
+	return command
+}
+
+// 转义单个参数，引用变量的部分改为 shell 的变量展开
+func formatFlag(shell string, flag Flag) string {
+	if flag.Ref == "" {
+		return FormatShellCommand(shell, flag.Args...)
+	}
+	quote := shellQuote(shell)
+	reference := `"${` + flag.Ref + `}"`
+	if shell == ShellPowerShell {
+		reference = `"$env:` + flag.Ref + `"`
+	}
+	last := len(flag.Args) - 1
+	words := make([]string, 0, len(flag.Args))
+	for _, arg := range flag.Args[:last] {
+		words = append(words, quote(arg))
+	}
+	words = append(words, quote(strings.TrimSuffix(flag.Args[last], "${"+flag.Ref+"}"))+reference)
+	return strings.Join(words, " ")
+}
+
+// FormatShellCommand 按指定 shell 转义后用空格连接
+func FormatShellCommand(shell string, args ...string) string {
+	quote := shellQuote(shell)
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// 指定 shell 的转义函数
+func shellQuote(shell string) func(string) string {
+	if shell == ShellPowerShell {
+		return QuotePowerShell
+	}
+	return QuotePosix
+}
+
+// ValidateShell 校验 shell 名称
+func ValidateShell(shell string) error {
+	switch shell {
+	case ShellPosix, ShellPowerShell:
+		return nil
+	}
+	return fmt.Errorf("unsupported shell: %s", shell)
+}
+
+// QuotePosix 按 POSIX shell 转义：包含特殊字符时使用单引号包裹，参数中的单引号需要先结束引号再转义
+func QuotePosix(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\r\n'\"$`\\|&;<>()*?[]#~!{}^%") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// QuotePowerShell 按 PowerShell 转义：单引号字符串中不展开变量，参数中的单引号写两次
+func QuotePowerShell(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	// PowerShell 也按 \v、\f 与不换行空格等 Unicode 空白分隔参数
+	if !strings.ContainsAny(arg, "'\"$`|&;<>(){}[]@#,*?%^~‘’‚‛“”„") && !strings.ContainsFunc(arg, unicode.IsSpace) {
+		return arg
+	}
+	// PowerShell 会把弯引号当作普通单引号处理，也需要成对出现
+	replacer := strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’", "‚", "‚‚", "‛", "‛‛")
+	return "'" + replacer.Replace(arg) + "'"
+}
@@ -0,0 +1,121 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 按 POSIX shell 的规则还原一个单词，只接受 QuotePosix 可能生成的写法：
+// 裸字符、单引号字符串与引号外的 \ 转义；未转义的特殊字符与空白说明转义不完整
+func unquotePosix(word string) (string, bool) {
+	var builder strings.Builder
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; {
+		case c == '\'':
+			end := strings.IndexByte(word[i+1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			builder.WriteString(word[i+1 : i+1+end])
+			i += end + 1
+		case c == '\\':
+			if i+1 >= len(word) {
+				return "", false
+			}
+			i++
+			builder.WriteByte(word[i])
+		case strings.IndexByte(" \t\r\n\"$`|&;<>()*?[]#~!{}", c) >= 0:
+			return "", false
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String(), true
+}
+
+// PowerShell 把弯引号当作单引号
+func isPowerShellQuote(r rune) bool {
+	return strings.ContainsRune("'‘’‚‛", r)
+}
+
+// 按 PowerShell 的规则还原一个单词：单引号字符串中连续两个引号表示一个引号，
+// 裸单词不能包含空白与会被 PowerShell 解释的字符
+func unquotePowerShell(word string) (string, bool) {
+	runes := []rune(word)
+	if len(runes) == 0 || !isPowerShellQuote(runes[0]) {
+		for _, r := range runes {
+			if unicode.IsSpace(r) || strings.ContainsRune("'\"`$|&;<>(){}[]@#,*?%^~‘’‚‛“”„", r) {
+				return "", false
+			}
+		}
+		return word, word != "" && word != "--%"
+	}
+	var builder strings.Builder
+	for i := 1; i < len(runes); i++ {
+		if !isPowerShellQuote(runes[i]) {
+			builder.WriteRune(runes[i])
+			continue
+		}
+		if i == len(runes)-1 {
+			return builder.String(), true
+		}
+		if !isPowerShellQuote(runes[i+1]) {
+			return "", false
+		}
+		builder.WriteRune(runes[i])
+		i++
+	}
+	return "", false
+}
+
+var quoteSeeds = []string{
+	"",
+	"nginx",
+	"hello world",
+	"it's",
+	"''",
+	`C:\Program Files\app`,
+	"$HOME ${USER} $(id) `id`",
+	"a\nb\tc",
+	"\v\f\u00a0\u2003",
+	"--%",
+	"-e=KEY=value",
+	"‘curly’ “double”",
+	"emoji 🐳",
+}
+
+func FuzzQuotePosix(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, arg string) {
+		// 命令行参数中不能包含 NUL
+		if strings.ContainsRune(arg, 0) {
+			t.Skip()
+		}
+		quoted := QuotePosix(arg)
+		got, ok := unquotePosix(quoted)
+		if !ok || got != arg {
+			t.Fatalf("QuotePosix(%q) = %q, which the shell reads as %q (ok=%v)", arg, quoted, got, ok)
+		}
+	})
+}
+
+func FuzzQuotePowerShell(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, arg string) {
+		// PowerShell 的字符串是 UTF-16，无法表示不合法的 UTF-8
+		if strings.ContainsRune(arg, 0) || !utf8.ValidString(arg) {
+			t.Skip()
+		}
+		quoted := QuotePowerShell(arg)
+		got, ok := unquotePowerShell(quoted)
+		if !ok || got != arg {
+			t.Fatalf("QuotePowerShell(%q) = %q, which PowerShell reads as %q (ok=%v)", arg, quoted, got, ok)
+		}
+	})
+}
@@ -0,0 +1,837 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultShmSize 是 docker 默认的 /dev/shm 大小
+const DefaultShmSize = 64 * 1024 * 1024
+
+// SpecVersion 是 SpecJSON 与 SpecYAML 输出的结构版本，字段含义发生不兼容变化时递增
+const SpecVersion = 1
+
+// ContainerSpec 是容器配置的规范化表示
+// docker run 命令与 compose 文件都由它生成，SpecJSON 与 SpecYAML 直接输出该结构
+// 与 Docker API 不同，这里只保留用户可以设置的配置，并去掉 docker 自动生成的默认值
+type ContainerSpec struct {
+	// Docker 中的容器 ID，只用于解析其他容器对它的引用（例如 container:<id> 网络模式），不会输出
+	ID    string `json:"-" yaml:"-"`
+	Name  string `json:"name" yaml:"name"`
+	Image string `json:"image" yaml:"image"`
+	// 只在 docker run 命令或 compose 文件中指定时设置，docker inspect 中的 Platform 只是操作系统
+	Platform   string   `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Entrypoint []string `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+	Command    []string `json:"command,omitempty" yaml:"command,omitempty"`
+	WorkingDir string   `json:"working_dir,omitempty" yaml:"working_dir,omitempty"`
+	User       string   `json:"user,omitempty" yaml:"user,omitempty"`
+	GroupAdd   []string `json:"group_add,omitempty" yaml:"group_add,omitempty"`
+	// 默认主机名（容器 ID 的前 12 位）与 host、container:<name> 网络下的主机名为空
+	Hostname    string `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Domainname  string `json:"domainname,omitempty" yaml:"domainname,omitempty"`
+	Interactive bool   `json:"interactive,omitempty" yaml:"interactive,omitempty"`
+	Tty         bool   `json:"tty,omitempty" yaml:"tty,omitempty"`
+	AutoRemove  bool   `json:"auto_remove,omitempty" yaml:"auto_remove,omitempty"`
+	// 重启策略，例如 always、on-failure:3，不重启时为空
+	Restart string `json:"restart,omitempty" yaml:"restart,omitempty"`
+
+	Ports []PortSpec `json:"ports,omitempty" yaml:"ports,omitempty"`
+	// 只暴露不发布的端口，不包含镜像 EXPOSE 的端口
+	Expose []PortSpec `json:"expose,omitempty" yaml:"expose,omitempty"`
+	// PublishAll 为 true 时所有暴露的端口发布到随机端口（-P）
+	PublishAll  bool        `json:"publish_all,omitempty" yaml:"publish_all,omitempty"`
+	Mounts      []MountSpec `json:"mounts,omitempty" yaml:"mounts,omitempty"`
+	VolumesFrom []string    `json:"volumes_from,omitempty" yaml:"volumes_from,omitempty"`
+	// 环境变量保持容器中的顺序，PATH 由镜像决定不会输出
+	Env []EnvSpec `json:"env,omitempty" yaml:"env,omitempty"`
+	// 用户标签，compose 写入的标签放在 Compose 中
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Redact 替换为 REDACTED 的标签引用的变量名，可以展开变量的格式输出为 ${VAR}
+	LabelRefs map[string]string `json:"label_refs,omitempty" yaml:"label_refs,omitempty"`
+	// 传给容器运行时的注解（--annotation）
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Devices     []DeviceSpec      `json:"devices,omitempty" yaml:"devices,omitempty"`
+	// --gpus 等设备请求
+	DeviceRequests []DeviceRequestSpec `json:"device_requests,omitempty" yaml:"device_requests,omitempty"`
+
+	Privileged  bool              `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	CapAdd      []string          `json:"cap_add,omitempty" yaml:"cap_add,omitempty"`
+	CapDrop     []string          `json:"cap_drop,omitempty" yaml:"cap_drop,omitempty"`
+	SecurityOpt []string          `json:"security_opt,omitempty" yaml:"security_opt,omitempty"`
+	ReadOnly    bool              `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	Init        bool              `json:"init,omitempty" yaml:"init,omitempty"`
+	Ulimits     []UlimitSpec      `json:"ulimits,omitempty" yaml:"ulimits,omitempty"`
+	Sysctls     map[string]string `json:"sysctls,omitempty" yaml:"sysctls,omitempty"`
+
+	Resources ResourceSpec `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Network   NetworkSpec  `json:"network" yaml:"network"`
+
+	Ipc          string `json:"ipc,omitempty" yaml:"ipc,omitempty"`
+	Pid          string `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Uts          string `json:"uts,omitempty" yaml:"uts,omitempty"`
+	Userns       string `json:"userns,omitempty" yaml:"userns,omitempty"`
+	Runtime      string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	CgroupParent string `json:"cgroup_parent,omitempty" yaml:"cgroup_parent,omitempty"`
+	// cgroup 命名空间，默认的 private 为空
+	Cgroupns string `json:"cgroupns,omitempty" yaml:"cgroupns,omitempty"`
+	// 存储驱动的选项，例如 size=10G
+	StorageOpt map[string]string `json:"storage_opt,omitempty" yaml:"storage_opt,omitempty"`
+
+	Logging     *LoggingSpec     `json:"logging,omitempty" yaml:"logging,omitempty"`
+	StopSignal  string           `json:"stop_signal,omitempty" yaml:"stop_signal,omitempty"`
+	StopTimeout *int             `json:"stop_timeout,omitempty" yaml:"stop_timeout,omitempty"`
+	Healthcheck *HealthcheckSpec `json:"healthcheck,omitempty" yaml:"healthcheck,omitempty"`
+	Compose     *ComposeInfo     `json:"compose,omitempty" yaml:"compose,omitempty"`
+}
+
+// PortSpec 是端口映射，每个主机绑定一条，HostPort 为空时由 docker 随机分配
+// 连续的端口合并为范围，此时 HostPort 也是范围，例如 8000-8002
+type PortSpec struct {
+	HostIP        string `json:"host_ip,omitempty" yaml:"host_ip,omitempty"`
+	HostPort      string `json:"host_port,omitempty" yaml:"host_port,omitempty"`
+	ContainerPort int    `json:"container_port" yaml:"container_port"`
+	// 端口范围的结束端口，单个端口时为 0
+	ContainerPortEnd int    `json:"container_port_end,omitempty" yaml:"container_port_end,omitempty"`
+	Protocol         string `json:"protocol" yaml:"protocol"`
+}
+
+// MountSpec 是挂载，Type 为 bind、volume 或 tmpfs
+type MountSpec struct {
+	Type string `json:"type" yaml:"type"`
+	// bind 为主机路径，volume 为卷名称，匿名卷与 tmpfs 为空
+	Source   string `json:"source,omitempty" yaml:"source,omitempty"`
+	Target   string `json:"target" yaml:"target"`
+	ReadOnly bool   `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	Driver   string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	// tmpfs 的挂载选项，例如 rw,size=64m；bind 与 volume 为 ro 以外的 -v 选项，例如 z、nocopy
+	Options string `json:"options,omitempty" yaml:"options,omitempty"`
+	// 卷由 compose 项目之外创建，例如 compose 文件中声明为 external 的卷
+	External bool `json:"external,omitempty" yaml:"external,omitempty"`
+}
+
+// EnvSpec 是环境变量，Value 与 Ref 都为空时从运行 docker 的环境中继承
+type EnvSpec struct {
+	Name  string  `json:"name" yaml:"name"`
+	Value *string `json:"value,omitempty" yaml:"value,omitempty"`
+	// 引用的变量名，只由 Redact 替换掉的密钥与 docker run 命令中的 $VAR 设置
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+}
+
+// DeviceSpec 是设备映射
+type DeviceSpec struct {
+	HostPath      string `json:"host_path" yaml:"host_path"`
+	ContainerPath string `json:"container_path" yaml:"container_path"`
+	// cgroup 权限，默认的 rwm 为空
+	Permissions string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+}
+
+// UlimitSpec 是单个 ulimit 的软限制与硬限制
+type UlimitSpec struct {
+	Name string `json:"name" yaml:"name"`
+	Soft int64  `json:"soft" yaml:"soft"`
+	Hard int64  `json:"hard" yaml:"hard"`
+}
+
+// ResourceSpec 是资源限制，内存相关的值以字节为单位，0 表示不限制
+type ResourceSpec struct {
+	CPUs       float64 `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	CPUShares  int64   `json:"cpu_shares,omitempty" yaml:"cpu_shares,omitempty"`
+	CpusetCpus string  `json:"cpuset_cpus,omitempty" yaml:"cpuset_cpus,omitempty"`
+	// CFS 调度的周期与配额，单位为微秒
+	CPUPeriod         int64 `json:"cpu_period,omitempty" yaml:"cpu_period,omitempty"`
+	CPUQuota          int64 `json:"cpu_quota,omitempty" yaml:"cpu_quota,omitempty"`
+	Memory            int64 `json:"memory,omitempty" yaml:"memory,omitempty"`
+	MemoryReservation int64 `json:"memory_reservation,omitempty" yaml:"memory_reservation,omitempty"`
+	// -1 表示不限制 swap
+	MemorySwap int64 `json:"memory_swap,omitempty" yaml:"memory_swap,omitempty"`
+	// 默认的 64MB 为空
+	ShmSize        int64 `json:"shm_size,omitempty" yaml:"shm_size,omitempty"`
+	PidsLimit      int64 `json:"pids_limit,omitempty" yaml:"pids_limit,omitempty"`
+	OomScoreAdj    int   `json:"oom_score_adj,omitempty" yaml:"oom_score_adj,omitempty"`
+	OomKillDisable bool  `json:"oom_kill_disable,omitempty" yaml:"oom_kill_disable,omitempty"`
+	// 已被 docker 废弃，只有旧版本创建的容器会设置
+	KernelMemory int64      `json:"kernel_memory,omitempty" yaml:"kernel_memory,omitempty"`
+	Blkio        *BlkioSpec `json:"blkio,omitempty" yaml:"blkio,omitempty"`
+}
+
+// BlkioSpec 是块设备 IO 的权重与限速
+type BlkioSpec struct {
+	Weight          uint16            `json:"weight,omitempty" yaml:"weight,omitempty"`
+	WeightDevice    []BlkioWeightSpec `json:"weight_device,omitempty" yaml:"weight_device,omitempty"`
+	DeviceReadBps   []BlkioLimitSpec  `json:"device_read_bps,omitempty" yaml:"device_read_bps,omitempty"`
+	DeviceWriteBps  []BlkioLimitSpec  `json:"device_write_bps,omitempty" yaml:"device_write_bps,omitempty"`
+	DeviceReadIOps  []BlkioLimitSpec  `json:"device_read_iops,omitempty" yaml:"device_read_iops,omitempty"`
+	DeviceWriteIOps []BlkioLimitSpec  `json:"device_write_iops,omitempty" yaml:"device_write_iops,omitempty"`
+}
+
+// BlkioWeightSpec 是单个设备的 IO 权重
+type BlkioWeightSpec struct {
+	Path   string `json:"path" yaml:"path"`
+	Weight uint16 `json:"weight" yaml:"weight"`
+}
+
+// BlkioLimitSpec 是单个设备的 IO 限速，bps 以字节为单位
+type BlkioLimitSpec struct {
+	Path string `json:"path" yaml:"path"`
+	Rate uint64 `json:"rate" yaml:"rate"`
+}
+
+// DeviceRequestSpec 是设备请求，Count 为 -1 表示全部设备
+type DeviceRequestSpec struct {
+	Driver       string            `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Count        int               `json:"count,omitempty" yaml:"count,omitempty"`
+	DeviceIDs    []string          `json:"device_ids,omitempty" yaml:"device_ids,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	Options      map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// NetworkSpec 是网络配置
+type NetworkSpec struct {
+	// host、none、bridge、container:<name> 或第一个自定义网络的名称
+	Mode string `json:"mode" yaml:"mode"`
+	// 连接的自定义网络，按名称排序
+	Networks   []NetworkAttachment `json:"networks,omitempty" yaml:"networks,omitempty"`
+	DNS        []string            `json:"dns,omitempty" yaml:"dns,omitempty"`
+	DNSSearch  []string            `json:"dns_search,omitempty" yaml:"dns_search,omitempty"`
+	DNSOptions []string            `json:"dns_options,omitempty" yaml:"dns_options,omitempty"`
+	ExtraHosts []string            `json:"extra_hosts,omitempty" yaml:"extra_hosts,omitempty"`
+	// 格式为 name:alias
+	Links []string `json:"links,omitempty" yaml:"links,omitempty"`
+	// 创建容器时指定的 MAC 地址，docker 自动分配的地址为空
+	MacAddress string `json:"mac_address,omitempty" yaml:"mac_address,omitempty"`
+}
+
+// NetworkAttachment 是容器在自定义网络中的配置，docker 自动生成的别名已去掉
+type NetworkAttachment struct {
+	// Docker 中的网络 ID，只用于生成导入命令，不会输出
+	ID          string   `json:"-" yaml:"-"`
+	Name        string   `json:"name" yaml:"name"`
+	Aliases     []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	IPv4Address string   `json:"ipv4_address,omitempty" yaml:"ipv4_address,omitempty"`
+	IPv6Address string   `json:"ipv6_address,omitempty" yaml:"ipv6_address,omitempty"`
+	// 网络由 compose 项目之外创建，例如 docker run 使用的网络与 compose 文件中声明为 external 的网络
+	External bool `json:"external,omitempty" yaml:"external,omitempty"`
+}
+
+// LoggingSpec 是日志配置，默认的 json-file 驱动为空
+type LoggingSpec struct {
+	Driver  string            `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+	// Redact 替换为 REDACTED 的日志选项引用的变量名
+	OptionRefs map[string]string `json:"option_refs,omitempty" yaml:"option_refs,omitempty"`
+}
+
+// HealthcheckSpec 是健康检查，时间使用 Go 的时长格式，例如 30s、1m30s
+type HealthcheckSpec struct {
+	// 第一项为 CMD、CMD-SHELL 或 NONE
+	Test        []string `json:"test" yaml:"test"`
+	Interval    string   `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout     string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	StartPeriod string   `json:"start_period,omitempty" yaml:"start_period,omitempty"`
+	// 启动期间的检查间隔
+	StartInterval string `json:"start_interval,omitempty" yaml:"start_interval,omitempty"`
+	Retries       int    `json:"retries,omitempty" yaml:"retries,omitempty"`
+}
+
+// ComposeInfo 是由 compose 创建的容器所属的项目与 service
+type ComposeInfo struct {
+	Project   string               `json:"project,omitempty" yaml:"project,omitempty"`
+	Service   string               `json:"service,omitempty" yaml:"service,omitempty"`
+	DependsOn map[string]DependsOn `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+}
+
+// SpecDocument 是 SpecJSON 与 SpecYAML 输出的文档
+type SpecDocument struct {
+	Version    int              `json:"version" yaml:"version"`
+	Containers []*ContainerSpec `json:"containers" yaml:"containers"`
+}
+
+// String 返回端口映射的简写形式，docker run -p 与 compose ports 通用
+func (p PortSpec) String() string {
+	port := fmt.Sprintf("%d", p.ContainerPort)
+	if p.ContainerPortEnd > p.ContainerPort {
+		port += fmt.Sprintf("-%d", p.ContainerPortEnd)
+	}
+	if p.Protocol != "tcp" {
+		port += "/" + p.Protocol
+	}
+	if p.HostIP == "" && p.HostPort == "" {
+		return port
+	}
+	host := p.HostPort
+	if p.HostIP != "" {
+		ip := p.HostIP
+		if strings.Contains(ip, ":") {
+			ip = "[" + ip + "]"
+		}
+		host = ip + ":" + host
+	}
+	return host + ":" + port
+}
+
+// String 返回卷的简写形式，docker run -v 与 compose volumes 通用
+func (m MountSpec) String() string {
+	volume := m.Target
+	if m.Source != "" {
+		volume = m.Source + ":" + m.Target
+	}
+	var options []string
+	if m.ReadOnly {
+		options = append(options, "ro")
+	}
+	if m.Options != "" {
+		options = append(options, m.Options)
+	}
+	if len(options) > 0 {
+		volume += ":" + strings.Join(options, ",")
+	}
+	return volume
+}
+
+// TmpfsString 返回 tmpfs 的简写形式
+func (m MountSpec) TmpfsString() string {
+	if m.Options != "" {
+		return m.Target + ":" + m.Options
+	}
+	return m.Target
+}
+
+// String 返回设备映射的简写形式
+func (d DeviceSpec) String() string {
+	mapping := d.HostPath + ":" + d.ContainerPath
+	if d.Permissions != "" {
+		mapping += ":" + d.Permissions
+	}
+	return mapping
+}
+
+// ServiceName 返回 compose service 名称：优先使用 compose 的 service 标签，否则使用容器名称
+func (s *ContainerSpec) ServiceName() string {
+	if s.Compose != nil && s.Compose.Service != "" {
+		return s.Compose.Service
+	}
+	return s.Name
+}
+
+// NewSpecs 将多个容器配置转换为 ContainerSpec
+func NewSpecs(configs []*types.ContainerJSON) []*ContainerSpec {
+	specs := make([]*ContainerSpec, 0, len(configs))
+	for _, config := range configs {
+		specs = append(specs, NewSpec(config))
+	}
+	return specs
+}
+
+// NewSpec 根据容器配置生成规范化的 ContainerSpec
+func NewSpec(config *types.ContainerJSON) *ContainerSpec {
+	container := config.Config
+	hostConfig := config.HostConfig
+	spec := &ContainerSpec{
+		ID:           config.ID,
+		Name:         strings.TrimPrefix(config.Name, "/"),
+		Image:        container.Image,
+		Entrypoint:   container.Entrypoint,
+		Command:      container.Cmd,
+		WorkingDir:   container.WorkingDir,
+		User:         container.User,
+		GroupAdd:     hostConfig.GroupAdd,
+		Interactive:  container.OpenStdin,
+		Tty:          container.Tty,
+		AutoRemove:   hostConfig.AutoRemove,
+		VolumesFrom:  hostConfig.VolumesFrom,
+		Privileged:   hostConfig.Privileged,
+		CapAdd:       hostConfig.CapAdd,
+		CapDrop:      hostConfig.CapDrop,
+		SecurityOpt:  hostConfig.SecurityOpt,
+		ReadOnly:     hostConfig.ReadonlyRootfs,
+		Init:         hostConfig.Init != nil && *hostConfig.Init,
+		Sysctls:      hostConfig.Sysctls,
+		Pid:          string(hostConfig.PidMode),
+		Uts:          string(hostConfig.UTSMode),
+		Userns:       string(hostConfig.UsernsMode),
+		CgroupParent: hostConfig.CgroupParent,
+		StorageOpt:   hostConfig.StorageOpt,
+		Annotations:  hostConfig.Annotations,
+		StopSignal:   container.StopSignal,
+		StopTimeout:  container.StopTimeout,
+	}
+
+	// 主机名（默认主机名为容器 ID 的前 12 位，无需输出）
+	// host 网络使用主机的主机名，container:<name> 网络复制目标容器的主机名，docker 不允许再指定
+	if mode := hostConfig.NetworkMode; !mode.IsHost() && !mode.IsContainer() {
+		if container.Hostname != "" && !strings.HasPrefix(config.ID, container.Hostname) {
+			spec.Hostname = container.Hostname
+		}
+		spec.Domainname = container.Domainname
+	}
+
+	if restart := hostConfig.RestartPolicy; restart.Name != "" && restart.Name != "no" {
+		spec.Restart = string(restart.Name)
+		if restart.IsOnFailure() && restart.MaximumRetryCount > 0 {
+			spec.Restart = fmt.Sprintf("%s:%d", restart.Name, restart.MaximumRetryCount)
+		}
+	}
+
+	spec.Ports = specPorts(hostConfig.PortBindings)
+	spec.PublishAll = hostConfig.PublishAllPorts
+	spec.Expose = specExpose(container.ExposedPorts, hostConfig.PortBindings)
+	spec.Mounts = specMounts(config)
+
+	for _, env := range container.Env {
+		key, value, ok := strings.Cut(env, "=")
+		// 默认的环境变量与没有 key 的环境变量直接抛弃
+		if key == "" || key == "PATH" {
+			continue
+		}
+		// 容器中的值都是字面值，即使形如 ${VAR} 也不会作为引用
+		item := EnvSpec{Name: key}
+		if ok {
+			item.Value = &value
+		}
+		spec.Env = append(spec.Env, item)
+	}
+
+	spec.Labels, spec.Compose = SpecLabels(container.Labels)
+
+	for _, device := range hostConfig.Devices {
+		item := DeviceSpec{HostPath: device.PathOnHost, ContainerPath: device.PathInContainer}
+		if device.CgroupPermissions != "rwm" {
+			item.Permissions = device.CgroupPermissions
+		}
+		spec.Devices = append(spec.Devices, item)
+	}
+	for _, request := range hostConfig.DeviceRequests {
+		item := DeviceRequestSpec{Driver: request.Driver, Count: request.Count, DeviceIDs: request.DeviceIDs, Options: request.Options}
+		// docker 的 Capabilities 是多组能力的“或”，--gpus 只会生成一组
+		if len(request.Capabilities) > 0 {
+			item.Capabilities = request.Capabilities[0]
+		}
+		spec.DeviceRequests = append(spec.DeviceRequests, item)
+	}
+	for _, ulimit := range hostConfig.Ulimits {
+		spec.Ulimits = append(spec.Ulimits, UlimitSpec{Name: ulimit.Name, Soft: ulimit.Soft, Hard: ulimit.Hard})
+	}
+
+	spec.Resources = ResourceSpec{
+		CPUs:              float64(hostConfig.NanoCPUs) / 1_000_000_000,
+		CPUShares:         hostConfig.CPUShares,
+		CpusetCpus:        hostConfig.CpusetCpus,
+		CPUPeriod:         hostConfig.CPUPeriod,
+		CPUQuota:          hostConfig.CPUQuota,
+		Memory:            hostConfig.Memory,
+		MemoryReservation: hostConfig.MemoryReservation,
+		OomKillDisable:    hostConfig.OomKillDisable != nil && *hostConfig.OomKillDisable,
+		KernelMemory:      hostConfig.KernelMemory,
+	}
+	if hostConfig.MemorySwap > 0 || hostConfig.MemorySwap == -1 {
+		spec.Resources.MemorySwap = hostConfig.MemorySwap
+	}
+	// 默认 shm 大小为 64MB
+	if hostConfig.ShmSize != DefaultShmSize {
+		spec.Resources.ShmSize = hostConfig.ShmSize
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		spec.Resources.PidsLimit = *hostConfig.PidsLimit
+	}
+	spec.Resources.OomScoreAdj = hostConfig.OomScoreAdj
+	spec.Resources.Blkio = specBlkio(hostConfig.Resources)
+
+	spec.Network = specNetwork(config)
+
+	if mode := string(hostConfig.IpcMode); mode != "private" && mode != "shareable" {
+		spec.Ipc = mode
+	}
+	// cgroup v2 上 docker 默认使用 private
+	if mode := hostConfig.CgroupnsMode; !mode.IsPrivate() {
+		spec.Cgroupns = string(mode)
+	}
+	if hostConfig.Runtime != "runc" {
+		spec.Runtime = hostConfig.Runtime
+	}
+
+	if logConfig := hostConfig.LogConfig; (logConfig.Type != "" && logConfig.Type != "json-file") || len(logConfig.Config) > 0 {
+		spec.Logging = &LoggingSpec{Options: logConfig.Config}
+		if logConfig.Type != "json-file" {
+			spec.Logging.Driver = logConfig.Type
+		}
+	}
+
+	if health := container.Healthcheck; health != nil && len(health.Test) > 0 {
+		spec.Healthcheck = &HealthcheckSpec{Test: health.Test, Retries: health.Retries}
+		if health.Interval > 0 {
+			spec.Healthcheck.Interval = health.Interval.String()
+		}
+		if health.Timeout > 0 {
+			spec.Healthcheck.Timeout = health.Timeout.String()
+		}
+		if health.StartPeriod > 0 {
+			spec.Healthcheck.StartPeriod = health.StartPeriod.String()
+		}
+		if health.StartInterval > 0 {
+			spec.Healthcheck.StartInterval = health.StartInterval.String()
+		}
+	}
+
+	return spec
+}
+
+// SpecLabels 将容器标签拆分为用户标签与 compose 写入的项目信息，没有对应内容时返回 nil
+func SpecLabels(labels map[string]string) (map[string]string, *ComposeInfo) {
+	var info *ComposeInfo
+	if project, service := labels[ComposeProjectLabel], labels[ComposeServiceLabel]; project != "" || service != "" {
+		info = &ComposeInfo{Project: project, Service: service}
+		if dependsOn := labels[ComposeDependsOnLabel]; dependsOn != "" {
+			info.DependsOn = ParseComposeDependsOn(dependsOn)
+		}
+	}
+	if labels = FilterComposeLabels(labels); len(labels) == 0 {
+		labels = nil
+	}
+	return labels, info
+}
+
+// ParsePorts 解析 docker run 的 -p 与 --expose 参数，结果与 NewSpec 相同：已发布的端口不再重复暴露
+func ParsePorts(publish []string, expose []string) ([]PortSpec, []PortSpec, error) {
+	exposed, bindings, err := nat.ParsePortSpecs(publish)
+	if err != nil {
+		return nil, nil, err
+	}
+	exposeOnly, _, err := nat.ParsePortSpecs(expose)
+	if err != nil {
+		return nil, nil, err
+	}
+	for port := range exposeOnly {
+		exposed[port] = struct{}{}
+	}
+	return specPorts(bindings), specExpose(exposed, bindings), nil
+}
+
+// 只暴露不发布的端口
+func specExpose(exposed nat.PortSet, bindings nat.PortMap) []PortSpec {
+	ports := make(nat.PortMap)
+	for port := range exposed {
+		if _, ok := bindings[port]; !ok {
+			ports[port] = []nat.PortBinding{{}}
+		}
+	}
+	return specPorts(ports)
+}
+
+// 块设备 IO 配置，没有设置时返回 nil
+func specBlkio(resources container.Resources) *BlkioSpec {
+	blkio := &BlkioSpec{Weight: resources.BlkioWeight}
+	for _, device := range resources.BlkioWeightDevice {
+		blkio.WeightDevice = append(blkio.WeightDevice, BlkioWeightSpec{Path: device.Path, Weight: device.Weight})
+	}
+	for _, limits := range []struct {
+		devices []*blkiodev.ThrottleDevice
+		target  *[]BlkioLimitSpec
+	}{
+		{resources.BlkioDeviceReadBps, &blkio.DeviceReadBps},
+		{resources.BlkioDeviceWriteBps, &blkio.DeviceWriteBps},
+		{resources.BlkioDeviceReadIOps, &blkio.DeviceReadIOps},
+		{resources.BlkioDeviceWriteIOps, &blkio.DeviceWriteIOps},
+	} {
+		for _, device := range limits.devices {
+			*limits.target = append(*limits.target, BlkioLimitSpec{Path: device.Path, Rate: device.Rate})
+		}
+	}
+	if reflect.ValueOf(*blkio).IsZero() {
+		return nil
+	}
+	return blkio
+}
+
+// 端口映射按容器端口与协议排序，同时监听 IPv4 与 IPv6 的默认绑定合并为一条
+func specPorts(bindings nat.PortMap) []PortSpec {
+	var ports []PortSpec
+	for port, portBindings := range bindings {
+		for _, binding := range portBindings {
+			item := PortSpec{HostIP: binding.HostIP, HostPort: binding.HostPort, ContainerPort: port.Int(), Protocol: port.Proto()}
+			if item.HostIP == "0.0.0.0" || item.HostIP == "::" {
+				item.HostIP = ""
+			}
+			if !slices.Contains(ports, item) {
+				ports = append(ports, item)
+			}
+		}
+	}
+	slices.SortFunc(ports, func(a, b PortSpec) int {
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort - b.ContainerPort
+		}
+		return strings.Compare(a.Protocol+" "+a.HostIP+" "+a.HostPort, b.Protocol+" "+b.HostIP+" "+b.HostPort)
+	})
+	return collapsePorts(ports)
+}
+
+// docker 将 -p 8000-8002:8000-8002 拆成单个端口保存，容器端口与主机端口都连续时合并回范围
+func collapsePorts(ports []PortSpec) []PortSpec {
+	var result []PortSpec
+	for _, port := range ports {
+		merged := false
+		for i := range result {
+			if next, ok := extendPortRange(result[i], port); ok {
+				result[i] = next
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			result = append(result, port)
+		}
+	}
+	return result
+}
+
+// 端口紧接在范围之后时返回合并后的范围
+func extendPortRange(r PortSpec, port PortSpec) (PortSpec, bool) {
+	end := max(r.ContainerPort, r.ContainerPortEnd)
+	if port.ContainerPortEnd != 0 || port.ContainerPort != end+1 || port.Protocol != r.Protocol || port.HostIP != r.HostIP {
+		return r, false
+	}
+	r.ContainerPortEnd = port.ContainerPort
+	if r.HostPort == "" && port.HostPort == "" {
+		return r, true
+	}
+	// 主机端口也需要连续，随机端口不能与指定端口合并
+	start, hostEnd, err := nat.ParsePortRange(r.HostPort)
+	if err != nil || r.ContainerPortEnd-r.ContainerPort != int(hostEnd-start)+1 {
+		return r, false
+	}
+	next, err := strconv.Atoi(port.HostPort)
+	if err != nil || uint64(next) != hostEnd+1 {
+		return r, false
+	}
+	r.HostPort = fmt.Sprintf("%d-%d", start, next)
+	return r, true
+}
+
+// -v 的选项去掉 ro、rw，保留 SELinux 标签（z、Z）、nocopy 与挂载传播，默认的 rprivate 与 defaults 中的选项不输出
+func mountOptions(mode string, propagation string, defaults ...string) string {
+	var options []string
+	for _, option := range strings.Split(mode, ",") {
+		if option != "" && option != "ro" && option != "rw" && !slices.Contains(defaults, option) && !slices.Contains(options, option) {
+			options = append(options, option)
+		}
+	}
+	if propagation != "" && propagation != string(mount.PropagationRPrivate) && !slices.Contains(options, propagation) {
+		options = append(options, propagation)
+	}
+	return strings.Join(options, ",")
+}
+
+// 挂载优先使用容器运行时的 Mounts，容器从未启动时退回到创建时的 Binds 与 Mounts
+// tmpfs 统一放在最后，HostConfig.Tmpfs 中的选项优先
+func specMounts(config *types.ContainerJSON) []MountSpec {
+	var mounts []MountSpec
+	if len(config.Mounts) > 0 {
+		for _, point := range config.Mounts {
+			item := MountSpec{Type: string(point.Type), Target: point.Destination, ReadOnly: !point.RW}
+			switch point.Type {
+			case mount.TypeBind:
+				item.Source = point.Source
+				item.Options = mountOptions(point.Mode, string(point.Propagation))
+			case mount.TypeVolume:
+				if !IsAnonymousVolume(point.Name) {
+					item.Source = point.Name
+				}
+				if point.Driver != "local" {
+					item.Driver = point.Driver
+				}
+				// docker 为卷默认设置 z
+				item.Options = mountOptions(point.Mode, "", "z")
+			case mount.TypeTmpfs:
+				item.ReadOnly = false
+			default:
+				continue
+			}
+			mounts = append(mounts, item)
+		}
+	} else {
+		for _, bind := range config.HostConfig.Binds {
+			parts := strings.Split(bind, ":")
+			if len(parts) < 2 {
+				mounts = append(mounts, MountSpec{Type: string(mount.TypeVolume), Target: parts[0]})
+				continue
+			}
+			item := MountSpec{Type: string(mount.TypeBind), Source: parts[0], Target: parts[1]}
+			if IsVolumeName(parts[0]) {
+				item.Type = string(mount.TypeVolume)
+			}
+			if len(parts) > 2 {
+				item.ReadOnly = slices.Contains(strings.Split(parts[2], ","), "ro")
+				item.Options = mountOptions(parts[2], "")
+			}
+			mounts = append(mounts, item)
+		}
+		for _, m := range config.HostConfig.Mounts {
+			item := MountSpec{Type: string(m.Type), Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly}
+			switch m.Type {
+			case mount.TypeVolume:
+				if m.VolumeOptions != nil && m.VolumeOptions.DriverConfig != nil && m.VolumeOptions.DriverConfig.Name != "local" {
+					item.Driver = m.VolumeOptions.DriverConfig.Name
+				}
+			case mount.TypeTmpfs:
+				item.Source = ""
+			case mount.TypeBind:
+				if m.BindOptions != nil {
+					item.Options = mountOptions("", string(m.BindOptions.Propagation))
+				}
+			default:
+				continue
+			}
+			mounts = append(mounts, item)
+		}
+	}
+
+	// HostConfig.Tmpfs 与运行时 Mounts 中的 tmpfs 是同一个挂载，按路径去重
+	var result []MountSpec
+	var tmpfs []MountSpec
+	for _, item := range mounts {
+		if item.Type == string(mount.TypeTmpfs) {
+			if _, ok := config.HostConfig.Tmpfs[item.Target]; !ok {
+				tmpfs = append(tmpfs, item)
+			}
+			continue
+		}
+		result = append(result, item)
+	}
+	for _, target := range sortedKeys(config.HostConfig.Tmpfs) {
+		tmpfs = append(tmpfs, MountSpec{Type: string(mount.TypeTmpfs), Target: target, Options: config.HostConfig.Tmpfs[target]})
+	}
+	return SortMounts(append(result, tmpfs...))
+}
+
+// SortMounts 按容器内路径排序挂载，tmpfs 统一放在最后
+// docker inspect 返回的挂载顺序不固定，排序后同一个容器每次生成的输出相同
+func SortMounts(mounts []MountSpec) []MountSpec {
+	slices.SortStableFunc(mounts, func(a, b MountSpec) int {
+		if tmpfsA, tmpfsB := a.Type == string(mount.TypeTmpfs), b.Type == string(mount.TypeTmpfs); tmpfsA != tmpfsB {
+			if tmpfsA {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Target, b.Target)
+	})
+	return mounts
+}
+
+// 网络模式与连接的自定义网络
+func specNetwork(config *types.ContainerJSON) NetworkSpec {
+	hostConfig := config.HostConfig
+	network := NetworkSpec{
+		Mode:       string(hostConfig.NetworkMode),
+		DNS:        hostConfig.DNS,
+		DNSSearch:  hostConfig.DNSSearch,
+		DNSOptions: hostConfig.DNSOptions,
+		ExtraHosts: hostConfig.ExtraHosts,
+		// 只有创建时指定的地址保存在 Config 中，网络中的地址包含 docker 自动分配的地址
+		MacAddress: config.Config.MacAddress,
+	}
+	if hostConfig.NetworkMode == "" || hostConfig.NetworkMode.IsDefault() {
+		network.Mode = "bridge"
+	}
+
+	for _, link := range hostConfig.Links {
+		// link 格式为 /other:/this/alias
+		name, alias, _ := strings.Cut(link, ":")
+		network.Links = append(network.Links, fmt.Sprintf("%s:%s", strings.TrimPrefix(name, "/"), path.Base(alias)))
+	}
+
+	switch mode := hostConfig.NetworkMode; {
+	case mode.IsHost(), mode.IsNone(), mode.IsContainer():
+	case config.NetworkSettings != nil && len(config.NetworkSettings.Networks) > 0:
+		for _, name := range sortedKeys(config.NetworkSettings.Networks) {
+			endpoint := config.NetworkSettings.Networks[name]
+			attachment := NetworkAttachment{Name: name}
+			if endpoint != nil {
+				attachment.ID = endpoint.NetworkID
+				attachment.Aliases = FilterNetworkAliases(config, endpoint.Aliases)
+				if endpoint.IPAMConfig != nil {
+					attachment.IPv4Address = endpoint.IPAMConfig.IPv4Address
+					attachment.IPv6Address = endpoint.IPAMConfig.IPv6Address
+				}
+			}
+			network.Networks = append(network.Networks, attachment)
+		}
+		// 默认的 bridge 网络只在通过 docker network connect 连接了其他网络时记录
+		if network.Mode == "bridge" && !slices.ContainsFunc(network.Networks, func(a NetworkAttachment) bool { return a.Name != "bridge" }) {
+			network.Networks = nil
+		}
+	case network.Mode != "bridge":
+		network.Networks = []NetworkAttachment{{Name: string(mode)}}
+	}
+	return network
+}
+
+// Specs 将多个 ContainerSpec 组成文档
+func Specs(specs []*ContainerSpec) *SpecDocument {
+	return &SpecDocument{Version: SpecVersion, Containers: append([]*ContainerSpec{}, specs...)}
+}
+
+// SpecJSON 以 JSON 输出 ContainerSpec 文档
+func SpecJSON(specs []*ContainerSpec) (string, error) {
+	data, err := json.MarshalIndent(Specs(specs), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// SpecYAML 以 YAML 输出 ContainerSpec 文档
+func SpecYAML(specs []*ContainerSpec) (string, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(Specs(specs)); err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %v", err)
+	}
+	return buffer.String(), nil
+}
+
+// IsAnonymousVolume 判断是否为匿名卷（名称为 64 位十六进制字符串）
+func IsAnonymousVolume(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// 卷名只能包含字母、数字、下划线、点和横线，其余来源视为宿主机路径
+var volumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// IsVolumeName 判断 -v 的来源是卷名称还是主机路径
+func IsVolumeName(source string) bool {
+	return volumeName.MatchString(source)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
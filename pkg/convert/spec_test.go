@@ -0,0 +1,241 @@
+package convert
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+)
+
+func portStrings(ports []PortSpec) []string {
+	var result []string
+	for _, port := range ports {
+		result = append(result, port.String())
+	}
+	return result
+}
+
+func TestParsePortsCollapsesRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish []string
+		expose  []string
+		ports   []string
+		exposed []string
+	}{
+		{
+			name:    "same host and container range",
+			publish: []string{"8000-8002:8000-8002"},
+			ports:   []string{"8000-8002:8000-8002"},
+		},
+		{
+			name:    "shifted host range",
+			publish: []string{"9000-9002:7000-7002"},
+			ports:   []string{"9000-9002:7000-7002"},
+		},
+		{
+			name:    "random host ports",
+			publish: []string{"6000-6002/udp"},
+			ports:   []string{"6000-6002/udp"},
+		},
+		{
+			name:    "single ports in sequence",
+			publish: []string{"8080:80", "8081:81"},
+			ports:   []string{"8080-8081:80-81"},
+		},
+		{
+			name:    "host ports not in sequence",
+			publish: []string{"8080:80", "9090:81"},
+			ports:   []string{"8080:80", "9090:81"},
+		},
+		{
+			name:    "random and fixed host ports",
+			publish: []string{"80", "8081:81"},
+			ports:   []string{"80", "8081:81"},
+		},
+		{
+			name:    "different protocols",
+			publish: []string{"53:53/udp", "54:54"},
+			ports:   []string{"53:53/udp", "54:54"},
+		},
+		{
+			name:    "different host ips",
+			publish: []string{"127.0.0.1:8080:80", "8081:81"},
+			ports:   []string{"127.0.0.1:8080:80", "8081:81"},
+		},
+		{
+			name:    "ipv4 and ipv6 default bindings",
+			publish: []string{"0.0.0.0:8080:80", "[::]:8080:80"},
+			ports:   []string{"8080:80"},
+		},
+		{
+			name:    "ipv6 host ip",
+			publish: []string{"[::1]:8443:443"},
+			ports:   []string{"[::1]:8443:443"},
+		},
+		{
+			name:    "exposed range",
+			expose:  []string{"9100-9102", "9200/udp"},
+			exposed: []string{"9100-9102", "9200/udp"},
+		},
+		{
+			name:    "published ports are not exposed again",
+			publish: []string{"8080:80"},
+			expose:  []string{"80-81"},
+			ports:   []string{"8080:80"},
+			exposed: []string{"81"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, exposed, err := ParsePorts(tt.publish, tt.expose)
+			if err != nil {
+				t.Fatalf("ParsePorts: %v", err)
+			}
+			if got := portStrings(ports); !slices.Equal(got, tt.ports) {
+				t.Errorf("ports = %q, want %q", got, tt.ports)
+			}
+			if got := portStrings(exposed); !slices.Equal(got, tt.exposed) {
+				t.Errorf("expose = %q, want %q", got, tt.exposed)
+			}
+		})
+	}
+}
+
+func TestPublishAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		publishAll bool
+		run        []string
+		ports      []string
+		expose     []string
+	}{
+		{
+			name:   "expose only",
+			run:    []string{"-p", "8080:80", "--expose", "9000-9001"},
+			ports:  []string{"8080:80"},
+			expose: []string{"9000-9001"},
+		},
+		{
+			name:       "publish all",
+			publishAll: true,
+			run:        []string{"-p", "8080:80", "-P", "--expose", "9000-9001"},
+			ports:      []string{"8080:80", "9000-9001"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, exposed, err := ParsePorts([]string{"8080:80"}, []string{"9000-9001"})
+			if err != nil {
+				t.Fatalf("ParsePorts: %v", err)
+			}
+			spec := &ContainerSpec{Name: "web", Image: "nginx", Ports: ports, Expose: exposed, PublishAll: tt.publishAll}
+
+			var run []string
+			for _, flag := range RunCommand(spec).Flags {
+				if flag.Group == GroupPorts {
+					run = append(run, flag.Args...)
+				}
+			}
+			if !slices.Equal(run, tt.run) {
+				t.Errorf("run port flags = %q, want %q", run, tt.run)
+			}
+
+			service, _, _ := ComposeService(spec, "", nil)
+			if !slices.Equal(service.Ports, tt.ports) {
+				t.Errorf("compose ports = %q, want %q", service.Ports, tt.ports)
+			}
+			if !slices.Equal(service.Expose, tt.expose) {
+				t.Errorf("compose expose = %q, want %q", service.Expose, tt.expose)
+			}
+		})
+	}
+}
+
+// SELinux 标签与挂载传播保留在 -v 的选项中，卷默认的 z 与 rprivate 不输出
+func TestSpecMountOptions(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{}},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeBind, Source: "/srv/data", Destination: "/data", Mode: "Z", RW: true, Propagation: mount.PropagationRPrivate},
+			{Type: mount.TypeBind, Source: "/mnt", Destination: "/mnt", Mode: "ro,z", Propagation: mount.PropagationRShared},
+			{Type: mount.TypeVolume, Name: "cache", Destination: "/cache", Driver: "local", Mode: "z", RW: true},
+			{Type: mount.TypeVolume, Name: "seed", Destination: "/seed", Driver: "local", Mode: "z,nocopy", RW: true},
+		},
+	}
+	var got []string
+	for _, item := range specMounts(config) {
+		got = append(got, item.String())
+	}
+	want := []string{"cache:/cache", "/srv/data:/data:Z", "/mnt:/mnt:ro,z,rshared", "seed:/seed:nocopy"}
+	if !slices.Equal(got, want) {
+		t.Errorf("mounts = %q, want %q", got, want)
+	}
+}
+
+// 默认 bridge 网络的容器通过 docker network connect 连接的其他网络不能丢失
+func TestSpecNetworkBridgeWithExtraNetworks(t *testing.T) {
+	newConfig := func(networks ...string) *types.ContainerJSON {
+		settings := &types.NetworkSettings{Networks: make(map[string]*network.EndpointSettings)}
+		for _, name := range networks {
+			settings.Networks[name] = &network.EndpointSettings{}
+		}
+		return &types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{Name: "/web", HostConfig: &container.HostConfig{NetworkMode: "bridge"}},
+			Config:            &container.Config{},
+			NetworkSettings:   settings,
+		}
+	}
+
+	if networks := specNetwork(newConfig("bridge")).Networks; networks != nil {
+		t.Errorf("networks = %+v, want none for the default bridge network", networks)
+	}
+
+	spec := &ContainerSpec{Name: "web", Image: "nginx", Network: specNetwork(newConfig("bridge", "backend"))}
+	var names []string
+	for _, attachment := range spec.Network.Networks {
+		names = append(names, attachment.Name)
+	}
+	if want := []string{"backend", "bridge"}; !slices.Equal(names, want) {
+		t.Fatalf("networks = %q, want %q", names, want)
+	}
+	cmd := RunCommand(spec)
+	if slices.ContainsFunc(cmd.Flags, func(flag Flag) bool { return flag.Name() == "--network" }) {
+		t.Errorf("run command should stay on the default bridge network: %+v", cmd.Flags)
+	}
+	if len(cmd.Connects) != 1 || !slices.Contains(cmd.Connects[0], "backend") {
+		t.Errorf("connects = %q, want backend", cmd.Connects)
+	}
+
+	service, _, _ := ComposeService(spec, "", nil)
+	if _, ok := service.Networks["backend"]; !ok || len(service.Networks) != 1 {
+		t.Errorf("compose networks = %v, want backend only", service.Networks)
+	}
+	if warnings := ComposeWarnings(spec); len(warnings) != 1 {
+		t.Errorf("warnings = %q, want the skipped bridge network", warnings)
+	}
+}
+
+// 不是 GPU 的设备请求不能用 --gpus 表示，跳过时需要提示
+func TestRunWarningsDeviceRequests(t *testing.T) {
+	spec := &ContainerSpec{Name: "ml", Image: "pytorch", DeviceRequests: []DeviceRequestSpec{
+		{Count: -1, Capabilities: []string{"gpu"}},
+		{Driver: "cdi", DeviceIDs: []string{"vendor.com/fpga=0"}, Capabilities: []string{"fpga"}},
+	}}
+	var gpus []string
+	for _, flag := range RunCommand(spec).Flags {
+		if flag.Name() == "--gpus" {
+			gpus = append(gpus, flag.Value())
+		}
+	}
+	if want := []string{"all"}; !slices.Equal(gpus, want) {
+		t.Errorf("--gpus = %q, want %q", gpus, want)
+	}
+	want := []string{"device request with capabilities fpga has no docker run equivalent and is skipped"}
+	if warnings := RunWarnings(spec); !slices.Equal(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}